@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/jf-tech/go-corelib/jsons"
+	"github.com/spf13/cobra"
+
+	"github.com/jf-tech/omniparser"
+)
+
+var (
+	outputSchemaCmd = &cobra.Command{
+		Use:   "output-schema",
+		Short: "Generates the JSON Schema of the output records of a schema.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := doOutputSchema(); err != nil {
+				fmt.Println() // to sure cobra cli always write out "Error: ..." on a new line.
+				return err
+			}
+			return nil
+		},
+	}
+	outputSchemaSchema string
+)
+
+func init() {
+	outputSchemaCmd.Flags().StringVarP(&outputSchemaSchema, "schema", "s", "", "schema file (required)")
+	_ = outputSchemaCmd.MarkFlagRequired("schema")
+}
+
+func doOutputSchema() error {
+	schemaReadCloser, err := openFile("schema", outputSchemaSchema)
+	if err != nil {
+		return err
+	}
+	defer schemaReadCloser.Close()
+
+	schema, err := omniparser.NewSchema(filepath.Base(outputSchemaSchema), schemaReadCloser)
+	if err != nil {
+		return err
+	}
+	b, err := schema.OutputJSONSchema()
+	if err != nil {
+		return err
+	}
+	fmt.Println(jsons.BPJ(string(b)))
+	return nil
+}
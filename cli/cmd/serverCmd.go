@@ -130,7 +130,9 @@ func httpPostTransform(w http.ResponseWriter, r *http.Request) {
 		writeBadRequest(w, fmt.Sprintf("bad request: invalid schema. err: %s", err))
 		return
 	}
-	t, err := s.NewTransform(
+	// Bind the transform to the request's context so an aborted request stops the transform.
+	t, err := s.NewTransformWithContext(
+		r.Context(),
 		"test-input", strings.NewReader(req.Input), &transformctx.Ctx{ExternalProperties: req.Properties})
 	if err != nil {
 		writeBadRequest(w, fmt.Sprintf("bad request: unable to new transform. err: %s", err))
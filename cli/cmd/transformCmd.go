@@ -29,8 +29,11 @@ var (
 			return nil
 		},
 	}
-	schema string
-	input  string
+	schema     string
+	input      string
+	workers    int
+	maxErrors  int
+	deadLetter string
 )
 
 func init() {
@@ -39,10 +42,21 @@ func init() {
 
 	transformCmd.Flags().StringVarP(
 		&input, "input", "i", "", "input file (optional; if not specified, stdin/pipe is used)")
+
+	transformCmd.Flags().IntVar(
+		&workers, "workers", 0, "number of records transformed in parallel (optional; if not specified, 1 is used)")
+
+	transformCmd.Flags().IntVar(
+		&maxErrors, "max-errors", 0,
+		"max number of failed records skipped before aborting (optional; if not specified, "+
+			"fails on the first failed record; -1 means unlimited)")
+
+	transformCmd.Flags().StringVar(
+		&deadLetter, "dead-letter", "", "file to write failed records and their errors to, one JSON per line (optional)")
 }
 
 func openFile(label string, filepath string) (io.ReadCloser, error) {
-	if !ios.FileExists(schema) {
+	if !ios.FileExists(filepath) {
 		return nil, fmt.Errorf("%s file '%s' does not exist", label, filepath)
 	}
 	return os.Open(filepath)
@@ -76,7 +90,29 @@ func doTransform() error {
 		return err
 	}
 
-	transform, err := schema.NewTransform(inputName, inputReadCloser, &transformctx.Ctx{})
+	ctx := &transformctx.Ctx{Workers: workers}
+	if maxErrors != 0 {
+		ctx.ErrorPolicy = transformctx.ErrorPolicySkip
+		if maxErrors > 0 {
+			ctx.MaxErrors = maxErrors
+		}
+	}
+	if strs.IsStrNonBlank(deadLetter) {
+		deadLetterFile, err := os.Create(deadLetter)
+		if err != nil {
+			return err
+		}
+		defer deadLetterFile.Close()
+		dlw := omniparser.NewDeadLetterWriter(deadLetterFile)
+		ctx.ErrorSink = dlw.Sink
+		defer func() {
+			if dlw.Err() != nil {
+				fmt.Fprintf(os.Stderr, "failed to write dead letter file '%s': %s\n", deadLetter, dlw.Err().Error())
+			}
+		}()
+	}
+
+	transform, err := schema.NewTransform(inputName, inputReadCloser, ctx)
 	if err != nil {
 		return err
 	}
@@ -35,6 +35,11 @@ var CommonCustomFuncs = map[string]CustomFuncType{
 	"dateTimeLayoutToRFC3339": DateTimeLayoutToRFC3339,
 	"dateTimeToEpoch":         DateTimeToEpoch,
 	"dateTimeToRFC3339":       DateTimeToRFC3339,
+	"decimalAdd":              DecimalAdd,
+	"decimalDiv":              DecimalDiv,
+	"decimalMul":              DecimalMul,
+	"decimalRound":            DecimalRound,
+	"decimalSub":              DecimalSub,
 	"epochToDateTimeRFC3339":  EpochToDateTimeRFC3339,
 	"lower":                   Lower,
 	"now":                     Now,
@@ -0,0 +1,101 @@
+package customfuncs
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jf-tech/omniparser/decimal"
+	"github.com/jf-tech/omniparser/transformctx"
+)
+
+func parseDecimals(values []string) ([]decimal.Decimal, error) {
+	ds := make([]decimal.Decimal, len(values))
+	for i, v := range values {
+		d, err := decimal.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		ds[i] = d
+	}
+	return ds, nil
+}
+
+func parseScale(scale string) (int, error) {
+	s, err := strconv.Atoi(scale)
+	if err != nil || s < 0 {
+		return 0, fmt.Errorf("'%s' is not a valid scale", scale)
+	}
+	if s > decimal.MaxExponent {
+		return 0, fmt.Errorf("'%s' is not a valid scale: must not exceed %d", scale, decimal.MaxExponent)
+	}
+	return s, nil
+}
+
+// DecimalAdd adds a number of decimal strings together exactly, i.e. without any float rounding. If
+// no values specified, 0 is returned.
+func DecimalAdd(_ *transformctx.Ctx, values ...string) (decimal.Decimal, error) {
+	ds, err := parseDecimals(values)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	var sum decimal.Decimal
+	for _, d := range ds {
+		sum = sum.Add(d)
+	}
+	return sum, nil
+}
+
+// DecimalSub subtracts decimal string b from decimal string a exactly.
+func DecimalSub(_ *transformctx.Ctx, a, b string) (decimal.Decimal, error) {
+	ds, err := parseDecimals([]string{a, b})
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return ds[0].Sub(ds[1]), nil
+}
+
+// DecimalMul multiplies a number of decimal strings together exactly. If no values specified, 1 is
+// returned.
+func DecimalMul(_ *transformctx.Ctx, values ...string) (decimal.Decimal, error) {
+	ds, err := parseDecimals(values)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	product := decimal.FromInt64(1)
+	for _, d := range ds {
+		product = product.Mul(d)
+	}
+	return product, nil
+}
+
+// DecimalDiv divides decimal string a by decimal string b, with the quotient rounded half up to the
+// given number of fractional digits.
+func DecimalDiv(_ *transformctx.Ctx, a, b, scale string) (decimal.Decimal, error) {
+	ds, err := parseDecimals([]string{a, b})
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	s, err := parseScale(scale)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return ds[0].Div(ds[1], s, decimal.RoundHalfUp)
+}
+
+// DecimalRound rounds a decimal string to the given number of fractional digits using the given
+// rounding mode, which is one of: "half_up", "half_even", "down", "up", "floor", and "ceiling".
+func DecimalRound(_ *transformctx.Ctx, value, scale, rounding string) (decimal.Decimal, error) {
+	d, err := decimal.Parse(value)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	s, err := parseScale(scale)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	mode := decimal.RoundingMode(rounding)
+	if !mode.IsValid() {
+		return decimal.Decimal{}, fmt.Errorf("unknown rounding mode '%s'", rounding)
+	}
+	return d.Round(s, mode), nil
+}
@@ -0,0 +1,208 @@
+package customfuncs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecimalAdd(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		values   []string
+		err      string
+		expected string
+	}{
+		{
+			name:     "no values",
+			values:   nil,
+			err:      "",
+			expected: "0",
+		},
+		{
+			name:     "exact sum",
+			values:   []string{"0.1", "0.2", "12345678901234.69"},
+			err:      "",
+			expected: "12345678901234.99",
+		},
+		{
+			name:     "invalid value",
+			values:   []string{"0.1", "abc"},
+			err:      "'abc' is not a valid decimal",
+			expected: "",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := DecimalAdd(nil, test.values...)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, d.String())
+		})
+	}
+}
+
+func TestDecimalSub(t *testing.T) {
+	d, err := DecimalSub(nil, "1.00", "0.01")
+	assert.NoError(t, err)
+	assert.Equal(t, "0.99", d.String())
+	_, err = DecimalSub(nil, "1.00", "")
+	assert.Error(t, err)
+	assert.Equal(t, "'' is not a valid decimal", err.Error())
+}
+
+func TestDecimalMul(t *testing.T) {
+	d, err := DecimalMul(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", d.String())
+	d, err = DecimalMul(nil, "19.99", "3", "1.5")
+	assert.NoError(t, err)
+	assert.Equal(t, "89.955", d.String())
+	_, err = DecimalMul(nil, "x")
+	assert.Error(t, err)
+	assert.Equal(t, "'x' is not a valid decimal", err.Error())
+}
+
+func TestDecimalDiv(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		a, b     string
+		scale    string
+		err      string
+		expected string
+	}{
+		{
+			name:     "rounded half up",
+			a:        "10",
+			b:        "3",
+			scale:    "2",
+			err:      "",
+			expected: "3.33",
+		},
+		{
+			name:     "half rounded up",
+			a:        "1",
+			b:        "8",
+			scale:    "2",
+			err:      "",
+			expected: "0.13",
+		},
+		{
+			name:     "invalid a",
+			a:        "a",
+			b:        "3",
+			scale:    "2",
+			err:      "'a' is not a valid decimal",
+			expected: "",
+		},
+		{
+			name:     "invalid scale",
+			a:        "10",
+			b:        "3",
+			scale:    "-1",
+			err:      "'-1' is not a valid scale",
+			expected: "",
+		},
+		{
+			name:     "scale too large",
+			a:        "10",
+			b:        "3",
+			scale:    "309",
+			err:      "'309' is not a valid scale: must not exceed 308",
+			expected: "",
+		},
+		{
+			name:     "division by zero",
+			a:        "10",
+			b:        "0.00",
+			scale:    "2",
+			err:      "division by zero",
+			expected: "",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := DecimalDiv(nil, test.a, test.b, test.scale)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, d.String())
+		})
+	}
+}
+
+func TestDecimalRound(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		value    string
+		scale    string
+		rounding string
+		err      string
+		expected string
+	}{
+		{
+			name:     "half_even",
+			value:    "2.345",
+			scale:    "2",
+			rounding: "half_even",
+			err:      "",
+			expected: "2.34",
+		},
+		{
+			name:     "ceiling",
+			value:    "2.341",
+			scale:    "2",
+			rounding: "ceiling",
+			err:      "",
+			expected: "2.35",
+		},
+		{
+			name:     "invalid value",
+			value:    "2.3.4",
+			scale:    "2",
+			rounding: "up",
+			err:      "'2.3.4' is not a valid decimal",
+			expected: "",
+		},
+		{
+			name:     "invalid scale",
+			value:    "2.345",
+			scale:    "two",
+			rounding: "up",
+			err:      "'two' is not a valid scale",
+			expected: "",
+		},
+		{
+			name:     "scale too large",
+			value:    "2.345",
+			scale:    "999999999",
+			rounding: "up",
+			err:      "'999999999' is not a valid scale: must not exceed 308",
+			expected: "",
+		},
+		{
+			name:     "invalid rounding",
+			value:    "2.345",
+			scale:    "2",
+			rounding: "nearest",
+			err:      "unknown rounding mode 'nearest'",
+			expected: "",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := DecimalRound(nil, test.value, test.scale, test.rounding)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, d.String())
+		})
+	}
+}
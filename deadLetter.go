@@ -0,0 +1,89 @@
+package omniparser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/jf-tech/omniparser/errs"
+	"github.com/jf-tech/omniparser/idr"
+	"github.com/jf-tech/omniparser/transformctx"
+)
+
+// DeadLetterRecord is what DeadLetterWriter writes out, as a line of JSON, for each rejected record.
+type DeadLetterRecord struct {
+	Error     string          `json:"error"`
+	Input     string          `json:"input,omitempty"`
+	Line      int             `json:"line,omitempty"`
+	SegmentNo int             `json:"segment_no,omitempty"`
+	DeclFQDN  string          `json:"decl,omitempty"`
+	Checksum  string          `json:"checksum,omitempty"`
+	Raw       json.RawMessage `json:"raw,omitempty"`
+}
+
+// DeadLetterWriter writes the records rejected by a transform operation, along with their errors, to
+// an io.Writer, one DeadLetterRecord JSON per line. Use its Sink method as transformctx.Ctx.ErrorSink.
+type DeadLetterWriter struct {
+	w     io.Writer
+	mtx   sync.Mutex
+	count int
+	err   error
+}
+
+// NewDeadLetterWriter creates a DeadLetterWriter that writes to w.
+func NewDeadLetterWriter(w io.Writer) *DeadLetterWriter {
+	return &DeadLetterWriter{w: w}
+}
+
+// Sink implements transformctx.ErrorSink. Once a write fails, all the subsequent writes are skipped,
+// and the failure is reported by Err.
+func (d *DeadLetterWriter) Sink(rawRecord transformctx.RawRecord, err error) {
+	record := DeadLetterRecord{Error: err.Error()}
+	var transformErr *errs.TransformError
+	if errors.As(err, &transformErr) {
+		record.Input = transformErr.Input
+		record.Line = transformErr.Line
+		record.SegmentNo = transformErr.SegmentNo
+		record.DeclFQDN = transformErr.DeclFQDN
+	}
+	if rawRecord != nil {
+		record.Checksum = rawRecord.Checksum()
+		record.Raw = marshalRaw(rawRecord.Raw())
+	}
+	b, _ := json.Marshal(record)
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.err != nil {
+		return
+	}
+	if _, d.err = d.w.Write(append(b, '\n')); d.err == nil {
+		d.count++
+	}
+}
+
+func marshalRaw(raw interface{}) json.RawMessage {
+	if n, ok := raw.(*idr.Node); ok {
+		return json.RawMessage(idr.JSONify2(n))
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprintf("%v", raw))
+	}
+	return b
+}
+
+// Count returns the number of records written.
+func (d *DeadLetterWriter) Count() int {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return d.count
+}
+
+// Err returns the first write failure, if any.
+func (d *DeadLetterWriter) Err() error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return d.err
+}
@@ -0,0 +1,84 @@
+package omniparser
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jf-tech/omniparser/errs"
+	"github.com/jf-tech/omniparser/transformctx"
+)
+
+func TestDeadLetterWriter(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"id": { "xpath": "id", "type": "int" }
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	var deadLetters strings.Builder
+	dlw := NewDeadLetterWriter(&deadLetters)
+	tfm, err := s.NewTransform(
+		"test-input",
+		strings.NewReader("<a>\n<b><id>1</id></b>\n<b><id>x</id></b>\n<b><id>3</id></b>\n<b><id>y</id></b>\n</a>"),
+		&transformctx.Ctx{ErrorPolicy: transformctx.ErrorPolicySkip, ErrorSink: dlw.Sink})
+	assert.NoError(t, err)
+	var records []string
+	for {
+		b, err := tfm.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		records = append(records, string(b))
+	}
+	assert.Equal(t, []string{`{"id":1}`, `{"id":3}`}, records)
+	assert.NoError(t, dlw.Err())
+	assert.Equal(t, 2, dlw.Count())
+	lines := strings.Split(strings.TrimSpace(deadLetters.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t,
+		`{"error":"input 'test-input' near line 3: fail to transform. err: unable to convert value 'x' to type 'int' on 'FINAL_OUTPUT.id', err: strconv.ParseInt: parsing \"x\": invalid syntax","input":"test-input","line":3,"decl":"FINAL_OUTPUT.id","checksum":"`,
+		lines[0][:strings.Index(lines[0], `"checksum":"`)+len(`"checksum":"`)])
+	assert.True(t, strings.HasSuffix(lines[0], `"raw":{"id":"x"}}`))
+	assert.True(t, strings.Contains(lines[1], `"line":5`))
+	assert.True(t, strings.HasSuffix(lines[1], `"raw":{"id":"y"}}`))
+}
+
+type testRawRecord struct {
+	raw interface{}
+}
+
+func (r testRawRecord) Raw() interface{} { return r.raw }
+func (r testRawRecord) Checksum() string { return "test-checksum" }
+
+type failingWriter struct{}
+
+func (failingWriter) Write(_ []byte) (int, error) { return 0, errors.New("write failure") }
+
+func TestDeadLetterWriter_NonIDRRawAndWriteFailure(t *testing.T) {
+	var deadLetters strings.Builder
+	dlw := NewDeadLetterWriter(&deadLetters)
+	dlw.Sink(testRawRecord{raw: map[string]int{"a": 1}}, errs.ErrTransformFailed("failure 1"))
+	dlw.Sink(testRawRecord{raw: func() {}}, errs.ErrTransformFailed("failure 2"))
+	dlw.Sink(nil, errs.ErrTransformFailed("failure 3"))
+	assert.Equal(t,
+		`{"error":"failure 1","checksum":"test-checksum","raw":{"a":1}}`+"\n"+
+			`{"error":"failure 2","checksum":"test-checksum","raw":"`,
+		deadLetters.String()[:strings.Index(deadLetters.String(), `"raw":"`)+len(`"raw":"`)])
+	assert.True(t, strings.HasSuffix(deadLetters.String(), `{"error":"failure 3"}`+"\n"))
+	assert.Equal(t, 3, dlw.Count())
+	assert.NoError(t, dlw.Err())
+
+	dlw = NewDeadLetterWriter(failingWriter{})
+	dlw.Sink(nil, errs.ErrTransformFailed("failure"))
+	dlw.Sink(nil, errs.ErrTransformFailed("failure"))
+	assert.Equal(t, 0, dlw.Count())
+	assert.Equal(t, errors.New("write failure"), dlw.Err())
+}
@@ -0,0 +1,268 @@
+// Package decimal provides an arbitrary-precision fixed-point decimal number type, used for values such as
+// monetary amounts where the exact digits must be preserved, which float64 can't guarantee.
+package decimal
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RoundingMode specifies how a Decimal is rounded when its scale is reduced.
+type RoundingMode string
+
+const (
+	// RoundHalfUp rounds towards the nearest neighbor, and away from zero if both neighbors are
+	// equidistant. E.g. 1.25 -> 1.3, -1.25 -> -1.3.
+	RoundHalfUp RoundingMode = "half_up"
+	// RoundHalfEven rounds towards the nearest neighbor, and towards the even neighbor if both neighbors
+	// are equidistant (a.k.a. banker's rounding). E.g. 1.25 -> 1.2, 1.35 -> 1.4.
+	RoundHalfEven RoundingMode = "half_even"
+	// RoundDown rounds towards zero (i.e. truncation). E.g. 1.29 -> 1.2, -1.29 -> -1.2.
+	RoundDown RoundingMode = "down"
+	// RoundUp rounds away from zero. E.g. 1.21 -> 1.3, -1.21 -> -1.3.
+	RoundUp RoundingMode = "up"
+	// RoundFloor rounds towards negative infinity. E.g. 1.29 -> 1.2, -1.21 -> -1.3.
+	RoundFloor RoundingMode = "floor"
+	// RoundCeiling rounds towards positive infinity. E.g. 1.21 -> 1.3, -1.29 -> -1.2.
+	RoundCeiling RoundingMode = "ceiling"
+)
+
+// IsValid tells whether a RoundingMode is one of the supported modes.
+func (m RoundingMode) IsValid() bool {
+	switch m {
+	case RoundHalfUp, RoundHalfEven, RoundDown, RoundUp, RoundFloor, RoundCeiling:
+		return true
+	}
+	return false
+}
+
+// ErrDivisionByZero is returned when a Decimal is divided by zero.
+var ErrDivisionByZero = errors.New("division by zero")
+
+// Decimal is an immutable arbitrary-precision fixed-point decimal number, whose value is
+// unscaled * 10^(-scale). The zero value of Decimal is 0.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+var bigTen = big.NewInt(10)
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+func (d Decimal) unscaledOrZero() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// MaxExponent is the largest magnitude of the exponent Parse accepts, e.g. "1e308" or "1e-308", the same
+// as float64's. It keeps a short input from yielding a Decimal with an enormous number of digits, which
+// would take a lot of time and memory to compute.
+const MaxExponent = 308
+
+// Parse parses a string in the decimal notation, such as "-12345678901234.99", optionally with an
+// exponent, such as "1.5e3", into a Decimal. All the digits are preserved, including trailing zeros
+// after the decimal point. The exponent must be within [-MaxExponent, MaxExponent].
+func Parse(s string) (Decimal, error) {
+	invalid := func() (Decimal, error) {
+		return Decimal{}, fmt.Errorf("'%s' is not a valid decimal", s)
+	}
+	str := s
+	exp := 0
+	if i := strings.IndexAny(str, "eE"); i >= 0 {
+		e, err := strconv.Atoi(str[i+1:])
+		if err != nil {
+			return invalid()
+		}
+		if e > MaxExponent || e < -MaxExponent {
+			return Decimal{}, fmt.Errorf(
+				"'%s' is not a valid decimal: exponent out of range [-%d, %d]", s, MaxExponent, MaxExponent)
+		}
+		exp = e
+		str = str[:i]
+	}
+	sign := ""
+	if len(str) > 0 && (str[0] == '+' || str[0] == '-') {
+		sign, str = str[:1], str[1:]
+	}
+	intPart, fracPart := str, ""
+	if i := strings.IndexByte(str, '.'); i >= 0 {
+		intPart, fracPart = str[:i], str[i+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return invalid()
+	}
+	for _, r := range intPart + fracPart {
+		if r < '0' || r > '9' {
+			return invalid()
+		}
+	}
+	unscaled, _ := new(big.Int).SetString(sign+intPart+fracPart, 10)
+	scale := len(fracPart) - exp
+	if scale < 0 {
+		unscaled.Mul(unscaled, pow10(-scale))
+		scale = 0
+	}
+	return Decimal{unscaled: unscaled, scale: scale}, nil
+}
+
+// FromInt64 creates a Decimal from an int64.
+func FromInt64(i int64) Decimal {
+	return Decimal{unscaled: big.NewInt(i)}
+}
+
+// FromUint64 creates a Decimal from a uint64.
+func FromUint64(u uint64) Decimal {
+	return Decimal{unscaled: new(big.Int).SetUint64(u)}
+}
+
+// FromFloat64 creates a Decimal from a float64, using the shortest decimal representation that
+// round-trips the float64 value, e.g. 0.1 becomes exactly 0.1.
+func FromFloat64(f float64) (Decimal, error) {
+	return Parse(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Sign returns -1, 0 or +1 depending on whether d is negative, zero or positive.
+func (d Decimal) Sign() int {
+	return d.unscaledOrZero().Sign()
+}
+
+// IsZero tells whether d is zero.
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// String returns the string representation of d in the decimal notation, without an exponent.
+func (d Decimal) String() string {
+	unscaled := d.unscaledOrZero()
+	digits := new(big.Int).Abs(unscaled).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+	}
+	if unscaled.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// MarshalJSON implements json.Marshaler. d is marshaled as a JSON number with its exact digits.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Int64 returns the integer part of d (i.e. truncated towards zero) as an int64, or an error if it
+// is out of the int64 range.
+func (d Decimal) Int64() (int64, error) {
+	i := d.Round(0, RoundDown).unscaledOrZero()
+	if !i.IsInt64() {
+		return 0, fmt.Errorf("'%s' is out of the int64 range", d.String())
+	}
+	return i.Int64(), nil
+}
+
+// Float64 returns the nearest float64 value of d.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// rescale returns d's unscaled value as if its scale were the given scale, which must not be less
+// than d's scale.
+func (d Decimal) rescale(scale int) *big.Int {
+	return new(big.Int).Mul(d.unscaledOrZero(), pow10(scale-d.scale))
+}
+
+func maxScale(d1, d2 Decimal) int {
+	if d1.scale > d2.scale {
+		return d1.scale
+	}
+	return d2.scale
+}
+
+// Cmp compares d and d2 and returns -1, 0 or +1 depending on whether d is less than, equal to or
+// greater than d2.
+func (d Decimal) Cmp(d2 Decimal) int {
+	scale := maxScale(d, d2)
+	return d.rescale(scale).Cmp(d2.rescale(scale))
+}
+
+// Add returns d + d2.
+func (d Decimal) Add(d2 Decimal) Decimal {
+	scale := maxScale(d, d2)
+	return Decimal{unscaled: new(big.Int).Add(d.rescale(scale), d2.rescale(scale)), scale: scale}
+}
+
+// Sub returns d - d2.
+func (d Decimal) Sub(d2 Decimal) Decimal {
+	scale := maxScale(d, d2)
+	return Decimal{unscaled: new(big.Int).Sub(d.rescale(scale), d2.rescale(scale)), scale: scale}
+}
+
+// Mul returns d * d2.
+func (d Decimal) Mul(d2 Decimal) Decimal {
+	return Decimal{unscaled: new(big.Int).Mul(d.unscaledOrZero(), d2.unscaledOrZero()), scale: d.scale + d2.scale}
+}
+
+// Div returns d / d2, rounded to the given scale using the given rounding mode.
+func (d Decimal) Div(d2 Decimal, scale int, mode RoundingMode) (Decimal, error) {
+	if d2.IsZero() {
+		return Decimal{}, ErrDivisionByZero
+	}
+	// d / d2 = (d.unscaled * 10^d2.scale) / (d2.unscaled * 10^d.scale), and to get the result's
+	// unscaled value at the given scale, multiply the numerator by 10^scale.
+	num := new(big.Int).Mul(d.unscaledOrZero(), pow10(d2.scale+scale))
+	den := new(big.Int).Mul(d2.unscaledOrZero(), pow10(d.scale))
+	return Decimal{unscaled: roundQuo(num, den, mode), scale: scale}, nil
+}
+
+// Round returns d rounded to the given scale using the given rounding mode. If the given scale is
+// greater than d's scale, d is padded with trailing zeros.
+func (d Decimal) Round(scale int, mode RoundingMode) Decimal {
+	if scale >= d.scale {
+		return Decimal{unscaled: d.rescale(scale), scale: scale}
+	}
+	return Decimal{unscaled: roundQuo(d.unscaledOrZero(), pow10(d.scale-scale), mode), scale: scale}
+}
+
+// roundQuo returns num / den rounded to an integer using the given rounding mode.
+func roundQuo(num, den *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	sign := int64(num.Sign() * den.Sign())
+	// compare the remainder against half of the divisor: 2*|r| vs |den|.
+	half := new(big.Int).Mul(new(big.Int).Abs(r), big.NewInt(2)).Cmp(new(big.Int).Abs(den))
+	awayFromZero := false
+	switch mode {
+	case RoundUp:
+		awayFromZero = true
+	case RoundHalfUp:
+		awayFromZero = half >= 0
+	case RoundHalfEven:
+		awayFromZero = half > 0 || (half == 0 && q.Bit(0) == 1)
+	case RoundFloor:
+		awayFromZero = sign < 0
+	case RoundCeiling:
+		awayFromZero = sign > 0
+	}
+	if awayFromZero {
+		q.Add(q, big.NewInt(sign))
+	}
+	return q
+}
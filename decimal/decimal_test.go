@@ -0,0 +1,142 @@
+package decimal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		expected string
+		err      string
+	}{
+		{input: "0", expected: "0"},
+		{input: "12345678901234.99", expected: "12345678901234.99"},
+		{input: "-12345678901234567890.123456789", expected: "-12345678901234567890.123456789"},
+		{input: "+1.50", expected: "1.50"},
+		{input: ".5", expected: "0.5"},
+		{input: "5.", expected: "5"},
+		{input: "-0.001", expected: "-0.001"},
+		{input: "1.5e3", expected: "1500"},
+		{input: "1.5E-3", expected: "0.0015"},
+		{input: "", err: "'' is not a valid decimal"},
+		{input: "-", err: "'-' is not a valid decimal"},
+		{input: ".", err: "'.' is not a valid decimal"},
+		{input: "1.2.3", err: "'1.2.3' is not a valid decimal"},
+		{input: "abc", err: "'abc' is not a valid decimal"},
+		{input: " 1", err: "' 1' is not a valid decimal"},
+		{input: "1e", err: "'1e' is not a valid decimal"},
+		{input: "1e308", expected: "1" + strings.Repeat("0", 308)},
+		{input: "1e-308", expected: "0." + strings.Repeat("0", 307) + "1"},
+		{input: "1e309", err: "'1e309' is not a valid decimal: exponent out of range [-308, 308]"},
+		{input: "1e-309", err: "'1e-309' is not a valid decimal: exponent out of range [-308, 308]"},
+		{input: "1e10000000", err: "'1e10000000' is not a valid decimal: exponent out of range [-308, 308]"},
+		{input: "1e99999999999999999999", err: "'1e99999999999999999999' is not a valid decimal"},
+	} {
+		t.Run(test.input, func(t *testing.T) {
+			d, err := Parse(test.input)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, d.String())
+		})
+	}
+}
+
+func TestFromNumbers(t *testing.T) {
+	assert.Equal(t, "-123", FromInt64(-123).String())
+	assert.Equal(t, "18446744073709551615", FromUint64(18446744073709551615).String())
+	d, err := FromFloat64(0.1)
+	assert.NoError(t, err)
+	assert.Equal(t, "0.1", d.String())
+	assert.Equal(t, "0", Decimal{}.String())
+	assert.True(t, Decimal{}.IsZero())
+}
+
+func TestConversions(t *testing.T) {
+	d := mustParse("-123.987")
+	i, err := d.Int64()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-123), i)
+	i, err = mustParse("-9223372036854775808.9").Int64()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-9223372036854775808), i)
+	i, err = mustParse("9223372036854775808").Int64()
+	assert.Error(t, err)
+	assert.Equal(t, "'9223372036854775808' is out of the int64 range", err.Error())
+	assert.Equal(t, int64(0), i)
+	assert.Equal(t, -123.987, d.Float64())
+	assert.Equal(t, 3, d.Scale())
+	assert.Equal(t, -1, d.Sign())
+	b, err := json.Marshal(map[string]interface{}{"amount": mustParse("12345678901234.99")})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"amount":12345678901234.99}`, string(b))
+}
+
+func TestArithmetic(t *testing.T) {
+	a, b := mustParse("12345678901234.99"), mustParse("0.01")
+	assert.Equal(t, "12345678901235.00", a.Add(b).String())
+	assert.Equal(t, "12345678901234.98", a.Sub(b).String())
+	assert.Equal(t, "123456789012.3499", a.Mul(b).String())
+	assert.Equal(t, 1, a.Cmp(b))
+	assert.Equal(t, -1, b.Cmp(a))
+	assert.Equal(t, 0, mustParse("1.10").Cmp(mustParse("1.1")))
+
+	q, err := mustParse("10").Div(mustParse("3"), 4, RoundHalfUp)
+	assert.NoError(t, err)
+	assert.Equal(t, "3.3333", q.String())
+	q, err = mustParse("2").Div(mustParse("0.3"), 2, RoundHalfUp)
+	assert.NoError(t, err)
+	assert.Equal(t, "6.67", q.String())
+	_, err = a.Div(Decimal{}, 2, RoundHalfUp)
+	assert.Equal(t, ErrDivisionByZero, err)
+}
+
+func TestRound(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		scale    int
+		mode     RoundingMode
+		expected string
+	}{
+		{input: "1.25", scale: 1, mode: RoundHalfUp, expected: "1.3"},
+		{input: "-1.25", scale: 1, mode: RoundHalfUp, expected: "-1.3"},
+		{input: "1.24", scale: 1, mode: RoundHalfUp, expected: "1.2"},
+		{input: "1.25", scale: 1, mode: RoundHalfEven, expected: "1.2"},
+		{input: "1.35", scale: 1, mode: RoundHalfEven, expected: "1.4"},
+		{input: "-1.35", scale: 1, mode: RoundHalfEven, expected: "-1.4"},
+		{input: "1.251", scale: 1, mode: RoundHalfEven, expected: "1.3"},
+		{input: "1.29", scale: 1, mode: RoundDown, expected: "1.2"},
+		{input: "-1.29", scale: 1, mode: RoundDown, expected: "-1.2"},
+		{input: "1.21", scale: 1, mode: RoundUp, expected: "1.3"},
+		{input: "-1.21", scale: 1, mode: RoundUp, expected: "-1.3"},
+		{input: "1.29", scale: 1, mode: RoundFloor, expected: "1.2"},
+		{input: "-1.21", scale: 1, mode: RoundFloor, expected: "-1.3"},
+		{input: "1.21", scale: 1, mode: RoundCeiling, expected: "1.3"},
+		{input: "-1.29", scale: 1, mode: RoundCeiling, expected: "-1.2"},
+		{input: "1.20", scale: 1, mode: RoundUp, expected: "1.2"},
+		{input: "1.5", scale: 3, mode: RoundHalfUp, expected: "1.500"},
+		{input: "0.005", scale: 2, mode: RoundHalfUp, expected: "0.01"},
+	} {
+		t.Run(test.input+"/"+string(test.mode), func(t *testing.T) {
+			assert.Equal(t, test.expected, mustParse(test.input).Round(test.scale, test.mode).String())
+		})
+	}
+	assert.True(t, RoundHalfEven.IsValid())
+	assert.False(t, RoundingMode("nearest").IsValid())
+}
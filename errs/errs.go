@@ -7,6 +7,12 @@ import (
 // ErrSchemaNotSupported indicates a schema is not supported by a handler.
 var ErrSchemaNotSupported = errors.New("schema not supported")
 
+// ErrDescribeNotSupported indicates a schema's handler doesn't support schema introspection.
+var ErrDescribeNotSupported = errors.New("schema introspection not supported")
+
+// ErrOutputJSONSchemaNotSupported indicates a schema's handler doesn't support output JSON Schema generation.
+var ErrOutputJSONSchemaNotSupported = errors.New("output JSON schema generation not supported")
+
 // ErrTransformFailed indicates a particular record transform has failed. In general
 // this isn't fatal, and processing can continue.
 type ErrTransformFailed string
@@ -14,10 +20,10 @@ type ErrTransformFailed string
 // Error implements the error interface
 func (e ErrTransformFailed) Error() string { return string(e) }
 
-// IsErrTransformFailed tells if an error is of ErrTransformFailed.
+// IsErrTransformFailed tells if an error is of ErrTransformFailed or *TransformError.
 func IsErrTransformFailed(err error) bool {
 	switch err.(type) {
-	case ErrTransformFailed:
+	case ErrTransformFailed, *TransformError:
 		return true
 	default:
 		return false
@@ -0,0 +1,78 @@
+package errs
+
+import "fmt"
+
+// Position describes the (approx.) location in an input stream where an error occurs. Fields
+// not applicable to a particular input file format are left as zero.
+type Position struct {
+	// Input is the name of the input stream (file).
+	Input string
+	// Line is the 1-based line number, used by csv, fixedlength, json and xml formats.
+	Line int
+	// SegmentNo is the 1-based segment number, used by EDI format.
+	SegmentNo int
+	// RuneBegin and RuneEnd are the 1-based rune (char) offsets of the segment, used by EDI format.
+	RuneBegin int
+	RuneEnd   int
+}
+
+// TransformError is the structured form of ErrTransformFailed: it indicates a particular record
+// transform has failed, and carries where in the input stream it failed, which transform decl
+// failed (if known), and the underlying cause. Use errors.As to retrieve it from an error. Just
+// like ErrTransformFailed, in general this isn't fatal, and processing can continue.
+type TransformError struct {
+	Position
+	// DeclFQDN is the fully qualified name of the offending transform decl, e.g.
+	// "FINAL_OUTPUT.items.price". Empty if the failure isn't caused by a particular decl.
+	DeclFQDN string
+	// Err is the underlying cause.
+	Err error
+	// Msg is the full context aware error message, e.g. prefixed with input name and line number.
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *TransformError) Error() string {
+	if e.Msg == "" && e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Msg
+}
+
+// Unwrap returns the underlying cause.
+func (e *TransformError) Unwrap() error { return e.Err }
+
+// ErrTransformAborted indicates a transform operation is aborted due to record transform failures,
+// as decided by the transform's error policy. This is fatal, and processing can't continue.
+type ErrTransformAborted struct {
+	// Failures is the number of record transform failures encountered.
+	Failures int
+	// Err is the last record transform failure, which causes the abortion.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ErrTransformAborted) Error() string {
+	return fmt.Sprintf("transform aborted after %d record transform failure(s), last failure: %s",
+		e.Failures, e.Err.Error())
+}
+
+// Unwrap returns the last record transform failure.
+func (e *ErrTransformAborted) Unwrap() error { return e.Err }
+
+// ValidationError indicates the value of a transform decl violates one of the decl's 'validate' rules.
+// It is the underlying cause of the resulting record transform failure (or warning), thus can be
+// retrieved from it using errors.As.
+type ValidationError struct {
+	// DeclFQDN is the fully qualified name of the transform decl whose value violates the rule.
+	DeclFQDN string
+	// Rule is the name of the violated rule, e.g. "required", "pattern" or "max_length".
+	Rule string
+	// Msg describes the violation.
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("'%s' failed validation rule '%s': %s", e.DeclFQDN, e.Rule, e.Msg)
+}
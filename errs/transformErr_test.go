@@ -0,0 +1,49 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformError(t *testing.T) {
+	cause := errors.New("invalid syntax")
+	var err error = &TransformError{
+		Position: Position{Input: "test-input", Line: 13},
+		DeclFQDN: "FINAL_OUTPUT.price",
+		Err:      cause,
+		Msg:      "input 'test-input' line 13: invalid syntax",
+	}
+	assert.True(t, IsErrTransformFailed(err))
+	assert.Equal(t, "input 'test-input' line 13: invalid syntax", err.Error())
+	assert.True(t, errors.Is(err, cause))
+
+	var te *TransformError
+	assert.True(t, errors.As(fmt.Errorf("wrapped: %w", err), &te))
+	assert.Equal(t, "test-input", te.Input)
+	assert.Equal(t, 13, te.Line)
+	assert.Equal(t, "FINAL_OUTPUT.price", te.DeclFQDN)
+
+	assert.Equal(t, "invalid syntax", (&TransformError{Err: cause}).Error())
+	assert.Equal(t, "", (&TransformError{}).Error())
+	assert.False(t, errors.As(io.EOF, &te))
+}
+
+func TestErrTransformAborted(t *testing.T) {
+	last := ErrTransformFailed("bad record")
+	var err error = &ErrTransformAborted{Failures: 3, Err: last}
+	assert.False(t, IsErrTransformFailed(err))
+	assert.Equal(t, "transform aborted after 3 record transform failure(s), last failure: bad record", err.Error())
+	assert.True(t, errors.Is(err, last))
+}
+
+func TestValidationError(t *testing.T) {
+	var err error = &ValidationError{DeclFQDN: "FINAL_OUTPUT.id", Rule: "required", Msg: "value is missing"}
+	assert.Equal(t, "'FINAL_OUTPUT.id' failed validation rule 'required': value is missing", err.Error())
+	var verr *ValidationError
+	assert.True(t, errors.As(&TransformError{DeclFQDN: "FINAL_OUTPUT.id", Err: err}, &verr))
+	assert.Equal(t, "required", verr.Rule)
+}
@@ -70,7 +70,7 @@ func getNodeJSON(n *idr.Node) string {
 	return j.(string)
 }
 
-func execProgram(program *goja.Program, args map[string]interface{}) (goja.Value, error) {
+func execProgram(ctx *transformctx.Ctx, program *goja.Program, args map[string]interface{}) (goja.Value, error) {
 	var vm *goja.Runtime
 	var poolObj interface{}
 	if disableCaching {
@@ -93,12 +93,31 @@ func execProgram(program *goja.Program, args map[string]interface{}) (goja.Value
 	for arg, val := range args {
 		vm.Set(arg, val)
 	}
+	// If the transform operation can be canceled, watch for the cancellation while the program
+	// is running so a long-running or runaway javascript can be interrupted.
+	if done := ctx.Done(); done != nil {
+		finished, watcherExited := make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(watcherExited)
+			select {
+			case <-done:
+				vm.Interrupt(ctx.Err())
+			case <-finished:
+			}
+		}()
+		defer func() {
+			close(finished)
+			<-watcherExited
+			// in case the interrupt has been raised, reset it in prep for next exec.
+			vm.ClearInterrupt()
+		}()
+	}
 	return vm.RunProgram(program)
 }
 
 // JavaScriptWithContext is a custom_func that runs a javascript with optional arguments and
 // with contextual '_node' JSON, if idr.Node is provided.
-func JavaScriptWithContext(_ *transformctx.Ctx, n *idr.Node, js string, args ...interface{}) (interface{}, error) {
+func JavaScriptWithContext(ctx *transformctx.Ctx, n *idr.Node, js string, args ...interface{}) (interface{}, error) {
 	if len(args)%2 != 0 {
 		return nil, fmt.Errorf("number of args must be even, but got %d", len(args))
 	}
@@ -113,7 +132,7 @@ func JavaScriptWithContext(_ *transformctx.Ctx, n *idr.Node, js string, args ...
 	if n != nil {
 		vmArgs[argNameNode] = getNodeJSON(n)
 	}
-	v, err := execProgram(program, vmArgs)
+	v, err := execProgram(ctx, program, vmArgs)
 	if err != nil {
 		return nil, err
 	}
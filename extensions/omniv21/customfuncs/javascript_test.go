@@ -1,13 +1,17 @@
 package customfuncs
 
 import (
+	"context"
+	"errors"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/jf-tech/omniparser/idr"
+	"github.com/jf-tech/omniparser/transformctx"
 )
 
 const (
@@ -156,6 +160,24 @@ func TestJavaScriptClearVarsAfterRunProgram(t *testing.T) {
 	assert.Equal(t, int64(30), r)
 }
 
+func TestJavaScriptInterruptedByCancel(t *testing.T) {
+	for _, cache := range []bool{noCache, withCache} {
+		prepCachesForTest(cache)
+		c, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		ctx := &transformctx.Ctx{Context: c}
+		r, err := JavaScript(ctx, `while (true) {}`)
+		cancel()
+		assert.Error(t, err)
+		assert.True(t, errors.Is(c.Err(), context.DeadlineExceeded))
+		assert.Contains(t, err.Error(), context.DeadlineExceeded.Error())
+		assert.Nil(t, r)
+		// Make sure the runtime (possibly pooled) isn't left in the interrupted state.
+		r, err = JavaScript(&transformctx.Ctx{Context: context.Background()}, `1+2`)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), r)
+	}
+}
+
 // go test -bench=. -benchmem -benchtime=30s
 // BenchmarkJavaScriptWithNoCache-8             	  225940	    160696 ns/op	  136620 B/op	    1698 allocs/op
 // BenchmarkJavaScriptWithCache-8               	22289469	      1612 ns/op	     140 B/op	       9 allocs/op
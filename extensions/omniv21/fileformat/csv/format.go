@@ -34,6 +34,11 @@ type csvFormatRuntime struct {
 	XPath string
 }
 
+// FileDeclaration implements fileformat.FileDeclarationProvider.
+func (r *csvFormatRuntime) FileDeclaration() interface{} {
+	return r.Decl
+}
+
 func (f *csvFileFormat) ValidateSchema(
 	format string, schemaContent []byte, finalOutputDecl *transform.Decl) (interface{}, error) {
 	if format != fileFormatCSV {
@@ -1,7 +1,6 @@
 package csv
 
 import (
-	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -11,6 +10,7 @@ import (
 	"github.com/jf-tech/go-corelib/ios"
 	"github.com/jf-tech/go-corelib/maths"
 
+	"github.com/jf-tech/omniparser/errs"
 	"github.com/jf-tech/omniparser/idr"
 )
 
@@ -136,7 +136,11 @@ func (r *reader) IsContinuableError(err error) bool {
 }
 
 func (r *reader) FmtErr(format string, args ...interface{}) error {
-	return errors.New(r.fmtErrStr(format, args...))
+	return &errs.TransformError{
+		Position: errs.Position{Input: r.inputName, Line: r.r.LineNum()},
+		Err:      fmt.Errorf(format, args...),
+		Msg:      r.fmtErrStr(format, args...),
+	}
 }
 
 func (r *reader) fmtErrStr(format string, args ...interface{}) string {
@@ -157,7 +157,7 @@ func TestReader(t *testing.T) {
 				assert.True(t, len(test.expected) > 0)
 				if expectedErr, ok := test.expected[0].(error); ok {
 					assert.Error(t, err)
-					assert.Equal(t, expectedErr, err)
+					assert.Equal(t, expectedErr.Error(), err.Error())
 					assert.Nil(t, n)
 					assert.Equal(t, 1, len(test.expected)) // if there is an error, it will be the last one.
 					break
@@ -34,6 +34,11 @@ type ediFormatRuntime struct {
 	XPath string
 }
 
+// FileDeclaration implements fileformat.FileDeclarationProvider.
+func (r *ediFormatRuntime) FileDeclaration() interface{} {
+	return r.Decl
+}
+
 func (f *ediFileFormat) ValidateSchema(
 	format string, schemaContent []byte, finalOutputDecl *transform.Decl) (interface{}, error) {
 	if format != fileFormatEDI {
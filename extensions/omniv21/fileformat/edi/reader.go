@@ -1,7 +1,6 @@
 package edi
 
 import (
-	"errors"
 	"fmt"
 	"io"
 
@@ -10,6 +9,7 @@ import (
 	"github.com/jf-tech/go-corelib/ios"
 	"github.com/jf-tech/go-corelib/strs"
 
+	"github.com/jf-tech/omniparser/errs"
 	"github.com/jf-tech/omniparser/idr"
 )
 
@@ -277,7 +277,14 @@ func (r *ediReader) IsContinuableError(err error) bool {
 }
 
 func (r *ediReader) FmtErr(format string, args ...interface{}) error {
-	return errors.New(r.fmtErrStr(format, args...))
+	segCount, runeBegin, runeEnd := r.r.SegCount(), r.r.RuneBegin(), r.r.RuneEnd()
+	return &errs.TransformError{
+		Position: errs.Position{
+			Input: r.inputName, SegmentNo: segCount, RuneBegin: runeBegin, RuneEnd: runeEnd,
+		},
+		Err: fmt.Errorf(format, args...),
+		Msg: r.fmtErrStr2(segCount, runeBegin, runeEnd, format, args...),
+	}
 }
 
 func (r *ediReader) fmtErrStr(format string, args ...interface{}) string {
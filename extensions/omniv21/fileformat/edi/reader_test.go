@@ -13,6 +13,7 @@ import (
 	"github.com/jf-tech/go-corelib/testlib"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/jf-tech/omniparser/errs"
 	"github.com/jf-tech/omniparser/idr"
 )
 
@@ -924,6 +925,19 @@ func TestRelease(t *testing.T) {
 	assert.Nil(t, reader.target)
 }
 
+func TestFmtErr(t *testing.T) {
+	r := &ediReader{
+		inputName: "test-input",
+		r:         &NonValidatingReader{segCount: 3, runeBegin: 21, runeEnd: 30},
+	}
+	err := r.FmtErr("golang is %s", "fun")
+	assert.Equal(t, "input 'test-input' at segment no.3 (char[21,30]): golang is fun", err.Error())
+	var te *errs.TransformError
+	assert.True(t, errors.As(err, &te))
+	assert.Equal(t, errs.Position{Input: "test-input", SegmentNo: 3, RuneBegin: 21, RuneEnd: 30}, te.Position)
+	assert.Equal(t, "golang is fun", te.Err.Error())
+}
+
 func TestIsContinuableError(t *testing.T) {
 	r := &ediReader{r: &NonValidatingReader{}}
 	assert.True(t, r.IsContinuableError(r.FmtErr("some error")))
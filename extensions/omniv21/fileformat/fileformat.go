@@ -21,6 +21,12 @@ type FileFormat interface {
 		inputName string, input io.Reader, formatRuntime interface{}) (FormatReader, error)
 }
 
+// FileDeclarationProvider is an optional interface a format runtime (as returned by FileFormat.ValidateSchema)
+// can implement to expose its parsed, format specific file declaration for schema introspection.
+type FileDeclarationProvider interface {
+	FileDeclaration() interface{}
+}
+
 // FormatReader is an interface for reading a specific input format in omni schema handler. We'll have
 // a number of format specific readers. The omni schema handler will use these readers for loading input
 // stream content before doing the xpath/node based parsing.
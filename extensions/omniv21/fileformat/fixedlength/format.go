@@ -36,6 +36,11 @@ type fixedLengthFormatRuntime struct {
 	XPath string
 }
 
+// FileDeclaration implements fileformat.FileDeclarationProvider.
+func (r *fixedLengthFormatRuntime) FileDeclaration() interface{} {
+	return r.Decl
+}
+
 func (f *fixedLengthFileFormat) ValidateSchema(
 	format string, schemaContent []byte, finalOutputDecl *transform.Decl) (interface{}, error) {
 	if format != fileFormatFixedLength {
@@ -2,7 +2,6 @@ package fixedlength
 
 import (
 	"bufio"
-	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -11,6 +10,7 @@ import (
 	"github.com/jf-tech/go-corelib/caches"
 	"github.com/jf-tech/go-corelib/ios"
 
+	"github.com/jf-tech/omniparser/errs"
 	"github.com/jf-tech/omniparser/idr"
 )
 
@@ -184,7 +184,11 @@ func (r *reader) IsContinuableError(err error) bool {
 }
 
 func (r *reader) FmtErr(format string, args ...interface{}) error {
-	return errors.New(r.fmtErrStr(format, args...))
+	return &errs.TransformError{
+		Position: errs.Position{Input: r.inputName, Line: r.line},
+		Err:      fmt.Errorf(format, args...),
+		Msg:      r.fmtErrStr(format, args...),
+	}
 }
 
 func (r *reader) fmtErrStr(format string, args ...interface{}) string {
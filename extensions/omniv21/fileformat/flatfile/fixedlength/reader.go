@@ -2,13 +2,13 @@ package fixedlength
 
 import (
 	"bufio"
-	"errors"
 	"fmt"
 	"io"
 
 	"github.com/antchfx/xpath"
 	"github.com/jf-tech/go-corelib/ios"
 
+	"github.com/jf-tech/omniparser/errs"
 	"github.com/jf-tech/omniparser/extensions/omniv21/fileformat/flatfile"
 	"github.com/jf-tech/omniparser/idr"
 )
@@ -223,7 +223,12 @@ func (r *reader) IsContinuableError(err error) bool {
 // FmtErr implements errs.CtxAwareErr embedded in fileformat.FormatReader, formatting an error
 // with line info.
 func (r *reader) FmtErr(format string, args ...interface{}) error {
-	return errors.New(r.fmtErrStr(r.unprocessedLineNum(), format, args...))
+	line := r.unprocessedLineNum()
+	return &errs.TransformError{
+		Position: errs.Position{Input: r.inputName, Line: line},
+		Err:      fmt.Errorf(format, args...),
+		Msg:      r.fmtErrStr(line, format, args...),
+	}
 }
 
 func (r *reader) fmtErrStr(line int, format string, args ...interface{}) string {
@@ -82,6 +82,10 @@ func TestReader_FmtErr(t *testing.T) {
 	err = r.FmtErr("golang is %s", "fun")
 	assert.Error(t, err)
 	assert.Equal(t, `input 'test-input' before/near line 1: golang is fun`, err.Error())
+	var te *errs.TransformError
+	assert.True(t, errors.As(err, &te))
+	assert.Equal(t, errs.Position{Input: "test-input", Line: 1}, te.Position)
+	assert.Equal(t, "golang is fun", te.Err.Error())
 }
 
 func TestReader_IsContinuableError(t *testing.T) {
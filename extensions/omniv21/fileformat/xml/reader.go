@@ -1,10 +1,10 @@
 package xml
 
 import (
-	"errors"
 	"fmt"
 	"io"
 
+	"github.com/jf-tech/omniparser/errs"
 	"github.com/jf-tech/omniparser/idr"
 )
 
@@ -51,7 +51,11 @@ func (r *reader) IsContinuableError(err error) bool {
 }
 
 func (r *reader) FmtErr(format string, args ...interface{}) error {
-	return errors.New(r.fmtErrStr(format, args...))
+	return &errs.TransformError{
+		Position: errs.Position{Input: r.inputName, Line: r.r.AtLine()},
+		Err:      fmt.Errorf(format, args...),
+		Msg:      r.fmtErrStr(format, args...),
+	}
 }
 
 func (r *reader) fmtErrStr(format string, args ...interface{}) string {
@@ -1,8 +1,11 @@
 package omniv21
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"sync"
+	"time"
 
 	"github.com/jf-tech/omniparser/customfuncs"
 	"github.com/jf-tech/omniparser/errs"
@@ -15,12 +18,30 @@ import (
 
 type rawRecord struct {
 	node *idr.Node
+	transformNotes
+}
+
+// transformNotes are what's collected while transforming a target node, besides the result, and attached
+// to the record's raw record.
+type transformNotes struct {
+	traces   []*schemahandler.DeclTrace // only in explain mode.
+	warnings []error
 }
 
 func (rr *rawRecord) Raw() interface{} {
 	return rr.node
 }
 
+// Explain returns the decl evaluation traces of the record, if the transform operation is in explain mode.
+func (rr *rawRecord) Explain() []*schemahandler.DeclTrace {
+	return rr.traces
+}
+
+// Warnings returns the warnings reported while transforming the record.
+func (rr *rawRecord) Warnings() []error {
+	return rr.warnings
+}
+
 // Checksum returns a stable MD5(v3) hash of the rawRecord.
 func (rr *rawRecord) Checksum() string {
 	hash, _ := customfuncs.UUIDv3(nil, idr.JSONify2(rr.node))
@@ -34,41 +55,168 @@ type ingester struct {
 	ctx              *transformctx.Ctx
 	reader           fileformat.FormatReader
 	rawRecord        rawRecord
+	target           *idr.Node   // the current target node read from reader.
+	exploded         []*idr.Node // the exploded nodes of the current target node yet to be transformed.
+	// parallel record transformation related. see parallelIngester.go for details.
+	workers   int
+	readerMtx sync.Mutex
+	jobs      chan *parallelJob
+	lastJob   *parallelJob
+	lastErr   error
+	stop      chan struct{}
 }
 
+// errRecordFiltered indicates a record is filtered out by the schema's `FILTER` decl. It never leaves the
+// ingester: Read silently skips such records.
+var errRecordFiltered = errors.New("record filtered")
+
 // Read ingests a raw record from the input stream, transforms it according the given schema and return
-// the raw record, transformed JSON bytes.
+// the raw record, transformed JSON bytes. Records filtered out by the schema's `FILTER` decl are skipped.
 func (g *ingester) Read() (schemahandler.RawRecord, []byte, error) {
-	if g.rawRecord.node != nil {
-		g.reader.Release(g.rawRecord.node)
-		g.rawRecord.node = nil
+	for {
+		var rawRecord schemahandler.RawRecord
+		var transformed []byte
+		var err error
+		if g.workers > 1 {
+			rawRecord, transformed, err = g.readParallel()
+		} else {
+			rawRecord, transformed, err = g.read()
+		}
+		if err == errRecordFiltered {
+			g.ctx.Stats().AddRecordFiltered()
+			continue
+		}
+		return rawRecord, transformed, err
 	}
-	n, err := g.reader.Read()
-	if n != nil {
-		g.rawRecord.node = n
+}
+
+func (g *ingester) read() (schemahandler.RawRecord, []byte, error) {
+	// A target node can explode into zero or more nodes, each of which is transformed into a record.
+	// Only move on to the next target node once all the exploded nodes of the current one are consumed.
+	for len(g.exploded) == 0 {
+		if g.target != nil {
+			g.reader.Release(g.target)
+			g.target = nil
+			g.rawRecord.node = nil
+			g.rawRecord.transformNotes = transformNotes{}
+		}
+		if err := g.ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		start := time.Now()
+		n, err := g.reader.Read()
+		g.ctx.Stats().AddPhaseDuration(transformctx.PhaseRead, time.Since(start))
+		if n != nil {
+			g.target = n
+			g.rawRecord.node = n
+		}
+		if err != nil {
+			// If the transform operation is canceled, whatever error the reader returned is merely
+			// a side effect of the cancellation. Return the cancellation error instead.
+			if ctxErr := g.ctx.Err(); ctxErr != nil {
+				return nil, nil, ctxErr
+			}
+			// Read() supposed to have already done CtxAwareErr error wrapping. So directly return.
+			return nil, nil, err
+		}
+		g.exploded, err = g.finalOutputDecl.ExplodeNode(n)
+		if err != nil {
+			return &g.rawRecord, nil, transformFailed(g.reader.FmtErr("fail to transform. err: %s", err.Error()), err)
+		}
 	}
+	n := g.exploded[0]
+	g.exploded = g.exploded[1:]
+	g.rawRecord.node = n
+	transformed, notes, err := g.transformNode(n, g.reader.FmtErr)
+	g.rawRecord.transformNotes = notes
 	if err != nil {
-		// Read() supposed to have already done CtxAwareErr error wrapping. So directly return.
-		return nil, nil, err
+		// return the raw record along with the error so it can be passed to transformctx.ErrorSink.
+		return &g.rawRecord, nil, err
+	}
+	return &g.rawRecord, transformed, nil
+}
+
+// transformNode transforms a target node according to the given schema and returns the transformed
+// JSON bytes, or errRecordFiltered if the target node is filtered out, along with the transform notes.
+// fmtErr is used for doing the CtxAwareErr error wrapping on the transform errors.
+func (g *ingester) transformNode(
+	n *idr.Node, fmtErr func(format string, args ...interface{}) error) ([]byte, transformNotes, error) {
+	start := time.Now()
+	result, notes, err := g.parseNode(n)
+	g.ctx.Stats().AddPhaseDuration(transformctx.PhaseTransform, time.Since(start))
+	if err == errRecordFiltered {
+		return nil, notes, err
 	}
-	result, err := transform.NewParseCtx(g.ctx, g.customFuncs, g.customParseFuncs).ParseNode(n, g.finalOutputDecl)
 	if err != nil {
+		if ctxErr := g.ctx.Err(); ctxErr != nil {
+			return nil, transformNotes{}, ctxErr
+		}
 		// ParseNode() error not CtxAwareErr wrapped, so wrap it.
-		// Note errs.ErrorTransformFailed is a continuable error.
-		return nil, nil, errs.ErrTransformFailed(g.fmtErrStr("fail to transform. err: %s", err.Error()))
+		return nil, notes, transformFailed(fmtErr("fail to transform. err: %s", err.Error()), err)
 	}
+	start = time.Now()
+	defer func() { g.ctx.Stats().AddPhaseDuration(transformctx.PhaseMarshal, time.Since(start)) }()
 	transformed, err := json.Marshal(result)
-	return &g.rawRecord, transformed, err
+	return transformed, notes, err
+}
+
+func (g *ingester) parseNode(n *idr.Node) (interface{}, transformNotes, error) {
+	parseCtx := transform.NewParseCtx(g.ctx, g.customFuncs, g.customParseFuncs)
+	notes := func() transformNotes {
+		return transformNotes{traces: parseCtx.Traces(), warnings: parseCtx.Warnings()}
+	}
+	pass, err := parseCtx.FilterNode(n, g.finalOutputDecl)
+	if err != nil {
+		return nil, notes(), err
+	}
+	if !pass {
+		return nil, notes(), errRecordFiltered
+	}
+	err = parseCtx.UpdateVars(n, g.finalOutputDecl)
+	if err != nil {
+		return nil, notes(), err
+	}
+	result, err := parseCtx.ParseNode(n, g.finalOutputDecl)
+	return result, notes(), err
+}
+
+// transformFailed turns a CtxAwareErr wrapped transform error into a continuable error: if the
+// FormatReader reports structured errors, an *errs.TransformError that carries the input position,
+// the offending decl (if any) and the underlying cause; otherwise an errs.ErrTransformFailed.
+func transformFailed(ctxAwareErr, cause error) error {
+	var posErr *errs.TransformError
+	if !errors.As(ctxAwareErr, &posErr) {
+		return errs.ErrTransformFailed(ctxAwareErr.Error())
+	}
+	err := &errs.TransformError{Position: posErr.Position, Err: cause, Msg: ctxAwareErr.Error()}
+	var declErr *errs.TransformError
+	if errors.As(cause, &declErr) {
+		err.DeclFQDN = declErr.DeclFQDN
+	}
+	return err
 }
 
 func (g *ingester) IsContinuableError(err error) bool {
-	return errs.IsErrTransformFailed(err) || g.reader.IsContinuableError(err)
+	if isCtxErr(err) {
+		return false
+	}
+	if errs.IsErrTransformFailed(err) {
+		return true
+	}
+	g.readerMtx.Lock()
+	defer g.readerMtx.Unlock()
+	return g.reader.IsContinuableError(err)
 }
 
+// FmtErr formats an error with the reader's context information. Note in parallel record transformation
+// mode, the context information (such as line number) reflects where the reader is currently at, which
+// can be a few records ahead of the record being transformed.
 func (g *ingester) FmtErr(format string, args ...interface{}) error {
-	return errors.New(g.fmtErrStr(format, args...))
+	g.readerMtx.Lock()
+	defer g.readerMtx.Unlock()
+	return g.reader.FmtErr(format, args...)
 }
 
-func (g *ingester) fmtErrStr(format string, args ...interface{}) string {
-	return g.reader.FmtErr(format, args...).Error()
+func isCtxErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
 }
@@ -1,16 +1,19 @@
 package omniv21
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/jf-tech/omniparser/errs"
 	"github.com/jf-tech/omniparser/extensions/omniv21/transform"
 	"github.com/jf-tech/omniparser/idr"
+	"github.com/jf-tech/omniparser/transformctx"
 )
 
 var errContinuableInTest = errors.New("continuable error")
@@ -72,7 +75,7 @@ func TestIngester_Read_ParseNodeFailure(t *testing.T) {
 	assert.Equal(t,
 		`ctx: fail to transform. err: unable to convert value 'abc' to type 'int' on 'FINAL_OUTPUT', err: strconv.ParseInt: parsing "abc": invalid syntax`,
 		err.Error())
-	assert.Nil(t, raw)
+	assert.True(t, ingesterTestNode == raw.Raw())
 	assert.Nil(t, b)
 	assert.Equal(t, 0, g.reader.(*testReader).releaseCalled)
 }
@@ -102,10 +105,243 @@ func TestIngester_Read_Success(t *testing.T) {
 	assert.Equal(t, 1, g.reader.(*testReader).releaseCalled)
 }
 
+func TestIngester_Read_Explode(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(` {
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "explode": "s", "object": {
+					"id": { "xpath": "../id" },
+					"status": { "xpath": "." }
+				}}
+			}
+		}`), nil, nil)
+	assert.NoError(t, err)
+	target := func(id string, statuses ...string) *idr.Node {
+		n := idr.CreateNode(idr.ElementNode, "t")
+		idNode := idr.CreateNode(idr.ElementNode, "id")
+		idr.AddChild(idNode, idr.CreateNode(idr.TextNode, id))
+		idr.AddChild(n, idNode)
+		for _, status := range statuses {
+			s := idr.CreateNode(idr.ElementNode, "s")
+			idr.AddChild(s, idr.CreateNode(idr.TextNode, status))
+			idr.AddChild(n, s)
+		}
+		return n
+	}
+	g := &ingester{
+		finalOutputDecl: finalOutputDecl,
+		ctx:             &transformctx.Ctx{},
+		reader: &testReader{
+			result: []*idr.Node{target("1", "a", "b"), target("2"), target("3", "c")},
+			err:    []error{nil, nil, nil},
+		},
+	}
+	for _, expected := range []struct {
+		record        string
+		releaseCalled int
+	}{
+		{`{"id":"1","status":"a"}`, 0},
+		{`{"id":"1","status":"b"}`, 0},
+		// target "2" explodes into nothing, thus is skipped.
+		{`{"id":"3","status":"c"}`, 2},
+	} {
+		raw, b, err := g.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, "s", raw.Raw().(*idr.Node).Data)
+		assert.Equal(t, expected.record, string(b))
+		assert.Equal(t, expected.releaseCalled, g.reader.(*testReader).releaseCalled)
+	}
+	raw, b, err := g.Read()
+	assert.Equal(t, io.EOF, err)
+	assert.Nil(t, raw)
+	assert.Nil(t, b)
+	assert.Equal(t, 3, g.reader.(*testReader).releaseCalled)
+}
+
+func TestIngester_Read_Canceled(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(` {
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "const": "123", "type": "int" }
+			}
+		}`), nil, nil)
+	assert.NoError(t, err)
+	c, cancel := context.WithCancel(context.Background())
+	g := &ingester{
+		finalOutputDecl: finalOutputDecl,
+		ctx:             &transformctx.Ctx{Context: c},
+		reader: &testReader{
+			result: []*idr.Node{ingesterTestNode, ingesterTestNode},
+			err:    []error{nil, nil},
+		},
+	}
+	raw, b, err := g.Read()
+	assert.NoError(t, err)
+	assert.NotNil(t, raw)
+	assert.Equal(t, "123", string(b))
+	cancel()
+	raw, b, err = g.Read()
+	assert.Error(t, err)
+	assert.Equal(t, context.Canceled, err)
+	assert.False(t, g.IsContinuableError(err))
+	assert.Nil(t, raw)
+	assert.Nil(t, b)
+	assert.Equal(t, 1, g.reader.(*testReader).releaseCalled)
+}
+
+func TestIngester_Read_ReadFailureDueToCancel(t *testing.T) {
+	c, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-c.Done()
+	g := &ingester{
+		ctx:    &transformctx.Ctx{Context: c},
+		reader: &testReader{result: []*idr.Node{nil}, err: []error{errContinuableInTest}},
+	}
+	raw, b, err := g.Read()
+	assert.Error(t, err)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.False(t, g.IsContinuableError(err))
+	assert.Nil(t, raw)
+	assert.Nil(t, b)
+}
+
+func TestIngester_Read_Parallel(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(` {
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "xpath": ".", "type": "int" }
+			}
+		}`), nil, nil)
+	assert.NoError(t, err)
+	var nodes []*idr.Node
+	var errs []error
+	for _, v := range []string{"1", "2", "", "x", "5"} {
+		if v == "" {
+			nodes = append(nodes, nil)
+			errs = append(errs, errContinuableInTest)
+			continue
+		}
+		n := idr.CreateNode(idr.ElementNode, "test")
+		idr.AddChild(n, idr.CreateNode(idr.TextNode, v))
+		nodes = append(nodes, n)
+		errs = append(errs, nil)
+	}
+	nodes = append(nodes, nil)
+	errs = append(errs, errors.New("fatal"))
+	reader := &testReader{result: nodes, err: errs}
+	g := &ingester{
+		finalOutputDecl: finalOutputDecl,
+		reader:          reader,
+		workers:         3,
+	}
+	raw, b, err := g.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, "1", string(b))
+	assert.Equal(t, "1", raw.Raw().(*idr.Node).InnerText())
+	raw, b, err = g.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, "2", string(b))
+	assert.Equal(t, "2", raw.Raw().(*idr.Node).InnerText())
+	raw, b, err = g.Read()
+	assert.Equal(t, errContinuableInTest, err)
+	assert.True(t, g.IsContinuableError(err))
+	assert.Nil(t, raw)
+	assert.Nil(t, b)
+	raw, b, err = g.Read()
+	assert.Error(t, err)
+	assert.True(t, g.IsContinuableError(err))
+	assert.Equal(t,
+		`ctx: fail to transform. err: unable to convert value 'x' to type 'int' on 'FINAL_OUTPUT', err: strconv.ParseInt: parsing "x": invalid syntax`,
+		err.Error())
+	assert.Equal(t, "x", raw.Raw().(*idr.Node).InnerText())
+	assert.Nil(t, b)
+	raw, b, err = g.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, "5", string(b))
+	assert.Equal(t, "5", raw.Raw().(*idr.Node).InnerText())
+	raw, b, err = g.Read()
+	assert.Error(t, err)
+	assert.Equal(t, "fatal", err.Error())
+	assert.False(t, g.IsContinuableError(err))
+	assert.Nil(t, raw)
+	assert.Nil(t, b)
+	// all the target nodes read are released back to the reader.
+	assert.Equal(t, 4, reader.releaseCalled)
+	// Read after the reading goroutine is done gets the same error.
+	raw, b, err = g.Read()
+	assert.Equal(t, "fatal", err.Error())
+	assert.Nil(t, raw)
+	assert.Nil(t, b)
+}
+
+func TestIngester_Read_ParallelCanceled(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(` {
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "const": "123", "type": "int" }
+			}
+		}`), nil, nil)
+	assert.NoError(t, err)
+	c, cancel := context.WithCancel(context.Background())
+	var nodes []*idr.Node
+	var errs []error
+	for i := 0; i < 100; i++ {
+		nodes = append(nodes, idr.CreateNode(idr.ElementNode, "test"))
+		errs = append(errs, nil)
+	}
+	g := &ingester{
+		finalOutputDecl: finalOutputDecl,
+		ctx:             &transformctx.Ctx{Context: c},
+		reader:          &testReader{result: nodes, err: errs},
+		workers:         2,
+	}
+	_, b, err := g.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, "123", string(b))
+	cancel()
+	_, b, err = g.Read()
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, b)
+}
+
+func TestIngester_Close(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(` {
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "const": "123", "type": "int" }
+			}
+		}`), nil, nil)
+	assert.NoError(t, err)
+	var nodes []*idr.Node
+	var errs []error
+	for i := 0; i < 100; i++ {
+		nodes = append(nodes, idr.CreateNode(idr.ElementNode, "test"))
+		errs = append(errs, nil)
+	}
+	g := &ingester{
+		finalOutputDecl: finalOutputDecl,
+		reader:          &testReader{result: nodes, err: errs},
+		workers:         2,
+	}
+	_, b, err := g.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, "123", string(b))
+	assert.NoError(t, g.Close())
+	// the reading goroutine exits and closes the job queue, instead of reading the rest of the input.
+	for range g.jobs {
+	}
+	assert.True(t, len(g.reader.(*testReader).result) > 0)
+	// Close is idempotent, and a no-op in sequential mode.
+	assert.NoError(t, g.Close())
+	assert.NoError(t, (&ingester{}).Close())
+}
+
 func TestIsContinuableError(t *testing.T) {
 	g := &ingester{reader: &testReader{}}
 	assert.False(t, g.IsContinuableError(errors.New("test failure")))
 	assert.True(t, g.IsContinuableError(errContinuableInTest))
+	assert.False(t, g.IsContinuableError(context.Canceled))
+	assert.False(t, g.IsContinuableError(fmt.Errorf("wrapped: %w", context.DeadlineExceeded)))
 }
 
 func TestFmtErr(t *testing.T) {
@@ -0,0 +1,213 @@
+package omniv21
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jf-tech/omniparser/errs"
+	"github.com/jf-tech/omniparser/idr"
+	"github.com/jf-tech/omniparser/schemahandler"
+	"github.com/jf-tech/omniparser/transformctx"
+)
+
+// Parallel record transformation: a reading goroutine reads target nodes from the FormatReader one
+// by one and queues them up, in input order, as jobs; a pool of worker goroutines transform the jobs
+// concurrently; and ingester.Read hands out the jobs' results in the queue order.
+//
+// All built-in FormatReaders reuse/release the previously returned target node (and mutate the IDR
+// tree the target node is in) upon the next Read call, thus a worker can't safely work on a target
+// node while the reading goroutine moves on. So the reading goroutine makes a copy of the entire IDR
+// tree the target node is in (so that transforms referencing the target's ancestors still work) and
+// releases the original target node back to FormatReader right away. The copied tree is owned by
+// the job and is released when ingester.Read moves on to the next job, i.e. after the worker has
+// finished with it and the caller has had the chance to access it via RawRecord.
+//
+// Note the FormatReader is only ever accessed by the reading goroutine, with the exception of FmtErr
+// and IsContinuableError calls made by custom funcs or omniparser, which are guarded by readerMtx.
+
+// errMsgPlaceholder is used to capture FormatReader's ctx aware error formatting (and the input position
+// if the FormatReader reports structured errors) at the time a target node is read, so that a later
+// transform error from a worker can be formatted as if it were done by the reader at that time.
+const errMsgPlaceholder = "\x00omniv21_err_msg\x00"
+
+type parallelJob struct {
+	root    *idr.Node // the root of the copied IDR tree, owned by the job.
+	node    *idr.Node // the copy of the target node.
+	errTmpl error
+	result  []byte
+	notes   transformNotes
+	err     error
+	done    chan struct{}
+}
+
+func (job *parallelJob) fmtErr(format string, args ...interface{}) error {
+	msg := strings.Replace(job.errTmpl.Error(), errMsgPlaceholder, fmt.Sprintf(format, args...), 1)
+	var tmpl *errs.TransformError
+	if errors.As(job.errTmpl, &tmpl) {
+		return &errs.TransformError{Position: tmpl.Position, Err: fmt.Errorf(format, args...), Msg: msg}
+	}
+	return errors.New(msg)
+}
+
+func (job *parallelJob) release() {
+	if job.root != nil {
+		idr.RemoveAndReleaseTree(job.root)
+		job.root, job.node = nil, nil
+	}
+}
+
+func (g *ingester) readParallel() (schemahandler.RawRecord, []byte, error) {
+	if g.lastJob != nil {
+		g.lastJob.release()
+		g.lastJob = nil
+		g.rawRecord.node = nil
+		g.rawRecord.transformNotes = transformNotes{}
+	}
+	if err := g.ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if g.jobs == nil {
+		g.startParallel()
+	}
+	job, ok := <-g.jobs
+	if !ok {
+		// the reading goroutine has finished, either due to EOF or a fatal error.
+		return nil, nil, g.lastErr
+	}
+	<-job.done
+	if job.node == nil {
+		return nil, nil, job.err
+	}
+	g.lastJob = job
+	g.rawRecord.node = job.node
+	g.rawRecord.transformNotes = job.notes
+	if job.err != nil {
+		// return the raw record along with the error so it can be passed to transformctx.ErrorSink.
+		return &g.rawRecord, nil, job.err
+	}
+	return &g.rawRecord, job.result, nil
+}
+
+// Close stops the reading goroutine, if it is running, so that it, as well as all the worker goroutines,
+// can exit, when the transform operation ceases before the input stream is completely consumed.
+func (g *ingester) Close() error {
+	if g.stop != nil {
+		close(g.stop)
+		g.stop = nil
+	}
+	return nil
+}
+
+func (g *ingester) startParallel() {
+	// jobs is the input ordered queue, whose capacity bounds how far ahead the reading goroutine
+	// can go before ingester.Read catches up.
+	jobs := make(chan *parallelJob, g.workers*2)
+	work := make(chan *parallelJob, g.workers)
+	stop := make(chan struct{})
+	g.jobs, g.stop = jobs, stop
+	for i := 0; i < g.workers; i++ {
+		go func() {
+			for job := range work {
+				if job.err == nil {
+					job.result, job.notes, job.err = g.transformNode(job.node, job.fmtErr)
+				}
+				close(job.done)
+			}
+		}()
+	}
+	go func() {
+		defer close(work)
+		defer close(jobs)
+		for {
+			group := g.explodeJob(g.readJob())
+			for i, job := range group {
+				select {
+				case jobs <- job:
+				case <-g.ctx.Done():
+					releaseUnsent(group, i)
+					g.lastErr = g.ctx.Err()
+					return
+				case <-stop:
+					releaseUnsent(group, i)
+					return
+				}
+				if job.node == nil {
+					if g.IsContinuableError(job.err) {
+						continue
+					}
+					g.lastErr = job.err
+					return
+				}
+				work <- job
+			}
+		}
+	}()
+}
+
+// readJob reads the next target node from the FormatReader and returns it as a job. If reading fails,
+// the returned job carries the error and is already done.
+func (g *ingester) readJob() *parallelJob {
+	job := &parallelJob{done: make(chan struct{})}
+	if err := g.ctx.Err(); err != nil {
+		job.err = err
+		close(job.done)
+		return job
+	}
+	g.readerMtx.Lock()
+	defer g.readerMtx.Unlock()
+	start := time.Now()
+	defer func() { g.ctx.Stats().AddPhaseDuration(transformctx.PhaseRead, time.Since(start)) }()
+	n, err := g.reader.Read()
+	if err != nil {
+		// If the transform operation is canceled, whatever error the reader returned is merely
+		// a side effect of the cancellation. Return the cancellation error instead.
+		if ctxErr := g.ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		job.err = err
+		close(job.done)
+		return job
+	}
+	job.root, job.node = idr.CopyTree(n)
+	job.errTmpl = g.reader.FmtErr("%s", errMsgPlaceholder)
+	g.reader.Release(n)
+	return job
+}
+
+// explodeJob turns the job of a target node into the jobs of the nodes the target node explodes into. All
+// the jobs share the copied IDR tree, which is owned (and thus released) by the last job only, given the
+// jobs are handed out in order. If exploding fails, the job of the target node is returned carrying the
+// error, which the worker passes on as is.
+func (g *ingester) explodeJob(job *parallelJob) []*parallelJob {
+	if job.node == nil {
+		return []*parallelJob{job}
+	}
+	nodes, err := g.finalOutputDecl.ExplodeNode(job.node)
+	if err != nil {
+		job.err = transformFailed(job.fmtErr("fail to transform. err: %s", err.Error()), err)
+		return []*parallelJob{job}
+	}
+	if len(nodes) == 1 && nodes[0] == job.node {
+		return []*parallelJob{job}
+	}
+	if len(nodes) == 0 {
+		job.release()
+		return nil
+	}
+	group := make([]*parallelJob, len(nodes))
+	for i, n := range nodes {
+		group[i] = &parallelJob{node: n, errTmpl: job.errTmpl, done: make(chan struct{})}
+	}
+	group[len(group)-1].root = job.root
+	return group
+}
+
+// releaseUnsent releases the IDR tree shared by a group of jobs when the job at index i can't be handed
+// out, but only if none of the group's jobs has been handed out (and thus possibly being worked on) yet.
+func releaseUnsent(group []*parallelJob, i int) {
+	if i == 0 {
+		group[len(group)-1].release()
+	}
+}
@@ -42,8 +42,8 @@ func CreateSchemaHandler(ctx *schemahandler.CreateCtx) (schemahandler.SchemaHand
 		// err is already context formatted.
 		return nil, err
 	}
-	finalOutputDecl, err := transform.ValidateTransformDeclarations(
-		ctx.Content, ctx.CustomFuncs, customParseFuncs(ctx))
+	finalOutputDecl, err := transform.ValidateTransformDeclarationsWithImports(
+		ctx.Content, ctx.CustomFuncs, customParseFuncs(ctx), ctx.ImportResolver)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"schema '%s' 'transform_declarations' validation failed: %s",
@@ -114,16 +114,40 @@ type schemaHandler struct {
 	finalOutputDecl *transform.Decl
 }
 
+// Describe implements schemahandler.Describer interface.
+func (h *schemaHandler) Describe() *schemahandler.Description {
+	desc := &schemahandler.Description{FinalOutput: h.finalOutputDecl.Describe()}
+	if provider, ok := h.formatRuntime.(fileformat.FileDeclarationProvider); ok {
+		desc.FileDeclaration = provider.FileDeclaration()
+	}
+	return desc
+}
+
+// OutputJSONSchema implements schemahandler.OutputJSONSchemaGenerator interface.
+func (h *schemaHandler) OutputJSONSchema() ([]byte, error) {
+	return h.finalOutputDecl.OutputJSONSchema(h.ctx.Name)
+}
+
 func (h *schemaHandler) NewIngester(ctx *transformctx.Ctx, input io.Reader) (schemahandler.Ingester, error) {
 	reader, err := h.fileFormat.CreateFormatReader(ctx.InputName, input, h.formatRuntime)
 	if err != nil {
 		return nil, err
 	}
+	workers := ctx.Workers
+	if h.finalOutputDecl.HasVars() {
+		if ctx.Vars == nil {
+			ctx.Vars = transformctx.NewVars()
+		}
+		h.finalOutputDecl.InitVars(ctx.Vars)
+		// variables are updated record by record in order, so records can't be transformed in parallel.
+		workers = 1
+	}
 	return &ingester{
 		finalOutputDecl:  h.finalOutputDecl,
 		customFuncs:      h.ctx.CustomFuncs,
 		customParseFuncs: customParseFuncs(h.ctx),
 		ctx:              ctx,
 		reader:           reader,
+		workers:          workers,
 	}, nil
 }
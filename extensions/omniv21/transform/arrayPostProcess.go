@@ -0,0 +1,185 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/jf-tech/go-corelib/times"
+
+	"github.com/jf-tech/omniparser/decimal"
+)
+
+const (
+	// elemItself is the special field name referring to an array element itself, instead of one of its
+	// fields.
+	elemItself = "."
+
+	sortTypeString   = "string"
+	sortTypeNumber   = "number"
+	sortTypeDateTime = "datetime"
+
+	sortOrderAsc  = "asc"
+	sortOrderDesc = "desc"
+)
+
+// fieldValue returns the value of an array element's field, or nil if the element isn't an object or
+// the field doesn't exist.
+func fieldValue(elem interface{}, field string) interface{} {
+	if field == elemItself {
+		return elem
+	}
+	switch obj := elem.(type) {
+	case *orderedObject:
+		value, _ := obj.get(field)
+		return value
+	case map[string]interface{}:
+		return obj[field]
+	}
+	return nil
+}
+
+// postProcessArray removes duplicate elements as specified by 'distinct_by' (the first occurrence of
+// each element is kept), then sorts the elements by 'sort_by' keys (stably, with elements lacking a
+// key placed last), then truncates the elements to 'max_items'.
+func postProcessArray(decl *Decl, array []interface{}) ([]interface{}, error) {
+	if len(decl.DistinctBy) > 0 {
+		distinct, err := distinctArray(decl, array)
+		if err != nil {
+			return nil, err
+		}
+		array = distinct
+	}
+	if len(decl.SortBy) > 0 {
+		err := sortArray(decl, array)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if decl.MaxItems != nil && len(array) > *decl.MaxItems {
+		array = array[:*decl.MaxItems]
+	}
+	return array, nil
+}
+
+func distinctArray(decl *Decl, array []interface{}) ([]interface{}, error) {
+	seen := map[string]bool{}
+	var distinct []interface{}
+	for _, elem := range array {
+		keys := make([]interface{}, len(decl.DistinctBy))
+		for i, field := range decl.DistinctBy {
+			keys[i] = plainValue(fieldValue(elem, field))
+		}
+		// json.Marshal gives a stable encoding of the keys (map keys sorted) to dedupe by.
+		b, err := json.Marshal(keys)
+		if err != nil {
+			return nil, declErr(decl.fqdn, err, "unable to compute 'distinct_by' key on '%s', err: %s",
+				decl.fqdn, err.Error())
+		}
+		if seen[string(b)] {
+			continue
+		}
+		seen[string(b)] = true
+		distinct = append(distinct, elem)
+	}
+	return distinct, nil
+}
+
+// sortKey is a typed sort key value; nil means the element lacks the key.
+type sortKey interface{}
+
+func toSortKey(v interface{}, typ string) (sortKey, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch typ {
+	case sortTypeNumber:
+		d, err := resultTypeConversion(v, resultTypeDecimal)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+	case sortTypeDateTime:
+		s, ok := v.(string)
+		if !ok {
+			return nil, errTypeConversionNotSupported
+		}
+		t, _, err := times.SmartParse(s)
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	default:
+		return fmt.Sprintf("%v", v), nil
+	}
+}
+
+func compareSortKeys(k1, k2 sortKey) int {
+	switch v1 := k1.(type) {
+	case decimal.Decimal:
+		return v1.Cmp(k2.(decimal.Decimal))
+	case time.Time:
+		v2 := k2.(time.Time)
+		switch {
+		case v1.Before(v2):
+			return -1
+		case v1.After(v2):
+			return 1
+		}
+		return 0
+	default:
+		return strings.Compare(k1.(string), k2.(string))
+	}
+}
+
+func sortArray(decl *Decl, array []interface{}) error {
+	// compute all the elements' typed keys upfront so that conversion errors can be reported.
+	keys := make([][]sortKey, len(array))
+	for i, elem := range array {
+		keys[i] = make([]sortKey, len(decl.SortBy))
+		for j, sortKeyDecl := range decl.SortBy {
+			v := fieldValue(elem, strs.StrPtrOrElse(sortKeyDecl.Field, elemItself))
+			k, err := toSortKey(v, strs.StrPtrOrElse(sortKeyDecl.Type, sortTypeString))
+			if err != nil {
+				return declErr(decl.fqdn, err, "unable to convert sort key value '%v' to type '%s' on '%s', err: %s",
+					v, strs.StrPtrOrElse(sortKeyDecl.Type, sortTypeString), decl.fqdn, err.Error())
+			}
+			keys[i][j] = k
+		}
+	}
+	indexes := make([]int, len(array))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		ka, kb := keys[indexes[a]], keys[indexes[b]]
+		for j, sortKeyDecl := range decl.SortBy {
+			switch {
+			case ka[j] == nil && kb[j] == nil:
+				continue
+			case ka[j] == nil:
+				return false
+			case kb[j] == nil:
+				return true
+			}
+			c := compareSortKeys(ka[j], kb[j])
+			if c == 0 {
+				continue
+			}
+			if strs.StrPtrOrElse(sortKeyDecl.Order, sortOrderAsc) == sortOrderDesc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	sorted := make([]interface{}, len(array))
+	for i, index := range indexes {
+		sorted[i] = array[index]
+	}
+	copy(array, sorted)
+	return nil
+}
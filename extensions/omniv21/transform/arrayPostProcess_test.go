@@ -0,0 +1,130 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostProcessArray(t *testing.T) {
+	lines := func() []interface{} {
+		return []interface{}{
+			map[string]interface{}{"line": "10", "ref": "A", "date": "2020-09-22T12:00:00-07:00"},
+			map[string]interface{}{"line": int64(2), "ref": "B", "date": "2020-09-22T18:00:00Z"},
+			map[string]interface{}{"ref": "A"},
+			map[string]interface{}{"line": 3.5, "ref": "C", "date": "2020-09-22T20:00:00Z"},
+		}
+	}
+	for _, test := range []struct {
+		name     string
+		decl     *Decl
+		array    []interface{}
+		err      string
+		expected []interface{}
+	}{
+		{
+			name:     "no post processing",
+			decl:     &Decl{},
+			array:    []interface{}{"b", "a", "b"},
+			err:      "",
+			expected: []interface{}{"b", "a", "b"},
+		},
+		{
+			name:     "distinct by elements themselves",
+			decl:     &Decl{DistinctBy: []string{"."}},
+			array:    []interface{}{"b", "a", "b", int64(1), "1", map[string]interface{}{"x": 1}, map[string]interface{}{"x": 1}},
+			err:      "",
+			expected: []interface{}{"b", "a", int64(1), "1", map[string]interface{}{"x": 1}},
+		},
+		{
+			name:  "distinct by field",
+			decl:  &Decl{DistinctBy: []string{"ref"}},
+			array: lines(),
+			err:   "",
+			expected: []interface{}{
+				lines()[0],
+				lines()[1],
+				lines()[3],
+			},
+		},
+		{
+			name:  "sort by number field, missing keys last",
+			decl:  &Decl{SortBy: []*SortKeyDecl{{Field: strs.StrPtr("line"), Type: strs.StrPtr("number")}}},
+			array: lines(),
+			err:   "",
+			expected: []interface{}{
+				lines()[1],
+				lines()[3],
+				lines()[0],
+				lines()[2],
+			},
+		},
+		{
+			name: "sort by string field desc, then number field",
+			decl: &Decl{SortBy: []*SortKeyDecl{
+				{Field: strs.StrPtr("ref"), Order: strs.StrPtr("desc")},
+				{Field: strs.StrPtr("line"), Type: strs.StrPtr("number")},
+			}},
+			array: lines(),
+			err:   "",
+			expected: []interface{}{
+				lines()[3],
+				lines()[1],
+				lines()[0],
+				lines()[2],
+			},
+		},
+		{
+			name:  "sort by datetime field desc",
+			decl:  &Decl{SortBy: []*SortKeyDecl{{Field: strs.StrPtr("date"), Type: strs.StrPtr("datetime"), Order: strs.StrPtr("desc")}}},
+			array: lines(),
+			err:   "",
+			expected: []interface{}{
+				lines()[3],
+				lines()[0],
+				lines()[1],
+				lines()[2],
+			},
+		},
+		{
+			name:     "sort datetime failure",
+			decl:     &Decl{SortBy: []*SortKeyDecl{{Type: strs.StrPtr("datetime")}}, fqdn: "test_fqdn"},
+			array:    []interface{}{"2020-09-22", int64(1)},
+			err:      "unable to convert sort key value '1' to type 'datetime' on 'test_fqdn', err: type conversion not supported",
+			expected: nil,
+		},
+		{
+			name:     "max items",
+			decl:     &Decl{MaxItems: testMaxItems(2)},
+			array:    []interface{}{"b", "a", "c"},
+			err:      "",
+			expected: []interface{}{"b", "a"},
+		},
+		{
+			name:     "max items more than elements",
+			decl:     &Decl{MaxItems: testMaxItems(5)},
+			array:    []interface{}{"b", "a", "c"},
+			err:      "",
+			expected: []interface{}{"b", "a", "c"},
+		},
+		{
+			name:     "distinct, sort, and then max items",
+			decl:     &Decl{DistinctBy: []string{"."}, SortBy: []*SortKeyDecl{{}}, MaxItems: testMaxItems(2)},
+			array:    []interface{}{"c", "b", "c", "a"},
+			err:      "",
+			expected: []interface{}{"a", "b"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			array, err := postProcessArray(test.decl, test.array)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expected, array)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jf-tech/go-corelib/strs"
+)
+
+// CoercionDecl is the decl for how leniently a string value is converted into an 'int', 'float', 'decimal'
+// or 'boolean' typed output element. It can be set on a decl, or at the top level of a schema as the default
+// for all such decls; a setting on a decl takes precedence over the same setting at the top level.
+type CoercionDecl struct {
+	// ThousandsSeparator is the digit grouping character removed from a number, e.g. "," for "1,234".
+	ThousandsSeparator *string `json:"thousands_separator,omitempty"`
+	// DecimalSeparator is the decimal point character of a number, e.g. "," for "1.234,56". If not
+	// specified, "." is used.
+	DecimalSeparator *string `json:"decimal_separator,omitempty"`
+	// TrailingSign allows a number's sign to trail it, e.g. "12-" for -12.
+	TrailingSign *bool `json:"trailing_sign,omitempty"`
+	// ParenthesizedNegative allows a negative number to be enclosed in parentheses, e.g. "(45.00)" for -45.
+	ParenthesizedNegative *bool `json:"parenthesized_negative,omitempty"`
+	// TrueValues and FalseValues are the values, compared case-insensitively, recognized as true and false
+	// respectively, in addition to the ones recognized by default, such as "true", "1", "false" and "0".
+	TrueValues  []string `json:"true_values,omitempty"`
+	FalseValues []string `json:"false_values,omitempty"`
+}
+
+// Note only deep-copy all the public fields, those internal computed fields are not copied.
+func (d *CoercionDecl) deepCopy() *CoercionDecl {
+	dest := &CoercionDecl{}
+	dest.ThousandsSeparator = strs.CopyStrPtr(d.ThousandsSeparator)
+	dest.DecimalSeparator = strs.CopyStrPtr(d.DecimalSeparator)
+	if d.TrailingSign != nil {
+		trailingSign := *d.TrailingSign
+		dest.TrailingSign = &trailingSign
+	}
+	if d.ParenthesizedNegative != nil {
+		parenthesizedNegative := *d.ParenthesizedNegative
+		dest.ParenthesizedNegative = &parenthesizedNegative
+	}
+	dest.TrueValues = append([]string(nil), d.TrueValues...)
+	dest.FalseValues = append([]string(nil), d.FalseValues...)
+	return dest
+}
+
+// merge returns the coercion settings of d overlaid with the ones specified in override. Either can be nil.
+// Note the result can be d or override itself, so it must not be modified.
+func (d *CoercionDecl) merge(override *CoercionDecl) *CoercionDecl {
+	switch {
+	case override == nil:
+		return d
+	case d == nil:
+		return override
+	}
+	dest := d.deepCopy()
+	if override.ThousandsSeparator != nil {
+		dest.ThousandsSeparator = strs.CopyStrPtr(override.ThousandsSeparator)
+	}
+	if override.DecimalSeparator != nil {
+		dest.DecimalSeparator = strs.CopyStrPtr(override.DecimalSeparator)
+	}
+	if override.TrailingSign != nil {
+		trailingSign := *override.TrailingSign
+		dest.TrailingSign = &trailingSign
+	}
+	if override.ParenthesizedNegative != nil {
+		parenthesizedNegative := *override.ParenthesizedNegative
+		dest.ParenthesizedNegative = &parenthesizedNegative
+	}
+	if override.TrueValues != nil {
+		dest.TrueValues = append([]string(nil), override.TrueValues...)
+	}
+	if override.FalseValues != nil {
+		dest.FalseValues = append([]string(nil), override.FalseValues...)
+	}
+	return dest
+}
+
+func (d *CoercionDecl) validate() error {
+	decimalSeparator := strs.StrPtrOrElse(d.DecimalSeparator, ".")
+	if d.ThousandsSeparator != nil && *d.ThousandsSeparator == decimalSeparator {
+		return fmt.Errorf(
+			"'thousands_separator' and 'decimal_separator' cannot both be '%s'", decimalSeparator)
+	}
+	for _, t := range d.TrueValues {
+		if isOneOfFold(t, d.FalseValues) {
+			return fmt.Errorf("'%s' cannot be in both 'true_values' and 'false_values'", t)
+		}
+	}
+	return nil
+}
+
+// isCoercible tells whether a string value converted into the given result type is subject to coercion.
+func isCoercible(rt *resultType) bool {
+	if rt == nil {
+		return false
+	}
+	switch *rt {
+	case resultTypeInt, resultTypeFloat, resultTypeDecimal, resultTypeBoolean:
+		return true
+	}
+	return false
+}
+
+// validateCoercion validates the decl's 'coercion', and computes the effective coercion settings of the
+// decl, taking the top level 'coercion' of the schema into account.
+func (ctx *validateCtx) validateCoercion(fqdn string, decl *Decl) error {
+	if !isCoercible(decl.ResultType) {
+		if decl.Coercion != nil {
+			return fmt.Errorf(
+				"'%s' cannot set 'coercion' unless 'type' is 'int', 'float', 'decimal' or 'boolean'", fqdn)
+		}
+		return nil
+	}
+	coercion := ctx.Coercion.merge(decl.Coercion)
+	if coercion == nil {
+		return nil
+	}
+	if err := coercion.validate(); err != nil {
+		return fmt.Errorf("'%s' has invalid 'coercion': %s", fqdn, err.Error())
+	}
+	decl.coercion = coercion
+	return nil
+}
+
+// coerce leniently normalizes a string value into the form the (strict) conversion into the given result
+// type accepts, e.g. "(1,234.50)" into "-1234.50", or "Y" into "true". Any other value, or a string value
+// the settings don't apply to, is returned as is, in which case the conversion decides whether it is valid.
+func (d *CoercionDecl) coerce(v interface{}, rt resultType) interface{} {
+	s, ok := v.(string)
+	if d == nil || !ok {
+		return v
+	}
+	switch rt {
+	case resultTypeInt, resultTypeFloat, resultTypeDecimal:
+		return d.coerceNumber(s)
+	case resultTypeBoolean:
+		return d.coerceBoolean(s)
+	}
+	return v
+}
+
+func (d *CoercionDecl) coerceNumber(s string) string {
+	sign := ""
+	switch {
+	case d.ParenthesizedNegative != nil && *d.ParenthesizedNegative &&
+		len(s) > 2 && strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")"):
+		sign, s = "-", strings.TrimSpace(s[1:len(s)-1])
+	case d.TrailingSign != nil && *d.TrailingSign &&
+		len(s) > 1 && (strings.HasSuffix(s, "-") || strings.HasSuffix(s, "+")):
+		sign, s = s[len(s)-1:], strings.TrimSpace(s[:len(s)-1])
+	}
+	if d.ThousandsSeparator != nil {
+		s = strings.ReplaceAll(s, *d.ThousandsSeparator, "")
+	}
+	if d.DecimalSeparator != nil && *d.DecimalSeparator != "." {
+		s = strings.ReplaceAll(s, *d.DecimalSeparator, ".")
+	}
+	return sign + s
+}
+
+func (d *CoercionDecl) coerceBoolean(s string) string {
+	if isOneOfFold(s, d.TrueValues) {
+		return "true"
+	}
+	if isOneOfFold(s, d.FalseValues) {
+		return "false"
+	}
+	return s
+}
+
+func isOneOfFold(s string, values []string) bool {
+	for _, v := range values {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
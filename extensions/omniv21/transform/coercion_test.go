@@ -0,0 +1,176 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCtx_Coercion(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		coercionJSON string // top level 'coercion', if any.
+		declJSON     string
+		err          string
+		expected     interface{}
+	}{
+		{
+			name:     "no coercion",
+			declJSON: `{ "const": "1,234", "type": "int" }`,
+			err:      `unable to convert value '1,234' to type 'int' on 'FINAL_OUTPUT', err: strconv.ParseInt: parsing "1,234": invalid syntax`,
+		},
+		{
+			name:     "leading plus and zeros are always accepted",
+			declJSON: `{ "const": "+00012", "type": "int" }`,
+			expected: int64(12),
+		},
+		{
+			name:     "thousands separator",
+			declJSON: `{ "const": "1,234,567", "type": "int", "coercion": { "thousands_separator": "," } }`,
+			expected: int64(1234567),
+		},
+		{
+			name:     "decimal comma",
+			declJSON: `{ "const": "1.234,5", "type": "float", "coercion": { "thousands_separator": ".", "decimal_separator": "," } }`,
+			expected: 1234.5,
+		},
+		{
+			name:     "trailing negative sign",
+			declJSON: `{ "const": "12-", "type": "int", "coercion": { "trailing_sign": true } }`,
+			expected: int64(-12),
+		},
+		{
+			name:     "trailing positive sign",
+			declJSON: `{ "const": "12.5 +", "type": "float", "coercion": { "trailing_sign": true } }`,
+			expected: 12.5,
+		},
+		{
+			name:     "parenthesized negative",
+			declJSON: `{ "const": "( 1,045.00 )", "type": "decimal", "coercion": { "thousands_separator": ",", "parenthesized_negative": true } }`,
+			expected: testDecimal("-1045.00"),
+		},
+		{
+			name:     "parenthesized negative not allowed",
+			declJSON: `{ "const": "(45.00)", "type": "decimal", "coercion": { "trailing_sign": true } }`,
+			err:      `unable to convert value '(45.00)' to type 'decimal' on 'FINAL_OUTPUT', err: '(45.00)' is not a valid decimal`,
+		},
+		{
+			name:     "boolean vocabularies",
+			declJSON: `{ "const": "n", "type": "boolean", "coercion": { "true_values": [ "Y" ], "false_values": [ "N" ] } }`,
+			expected: false,
+		},
+		{
+			name:     "boolean default vocabulary still accepted",
+			declJSON: `{ "const": "true", "type": "boolean", "coercion": { "true_values": [ "Y" ], "false_values": [ "N" ] } }`,
+			expected: true,
+		},
+		{
+			name:     "boolean unknown value",
+			declJSON: `{ "const": "maybe", "type": "boolean", "coercion": { "true_values": [ "Y" ] } }`,
+			err:      `unable to convert value 'maybe' to type 'boolean' on 'FINAL_OUTPUT', err: strconv.ParseBool: parsing "maybe": invalid syntax`,
+		},
+		{
+			name:         "top level coercion",
+			coercionJSON: `{ "thousands_separator": ",", "trailing_sign": true }`,
+			declJSON:     `{ "const": "1,234-", "type": "int" }`,
+			expected:     int64(-1234),
+		},
+		{
+			name:         "decl coercion overrides top level coercion setting by setting",
+			coercionJSON: `{ "thousands_separator": ",", "trailing_sign": true }`,
+			declJSON:     `{ "const": "1 234-", "type": "int", "coercion": { "thousands_separator": " " } }`,
+			expected:     int64(-1234),
+		},
+		{
+			name:         "decl coercion turns off top level coercion setting",
+			coercionJSON: `{ "trailing_sign": true }`,
+			declJSON:     `{ "const": "12-", "type": "int", "coercion": { "trailing_sign": false } }`,
+			err:          `unable to convert value '12-' to type 'int' on 'FINAL_OUTPUT', err: strconv.ParseInt: parsing "12-": invalid syntax`,
+		},
+		{
+			name:         "top level coercion not applied to string",
+			coercionJSON: `{ "thousands_separator": "," }`,
+			declJSON:     `{ "const": "1,234", "type": "string" }`,
+			expected:     "1,234",
+		},
+		{
+			name:         "default coerced",
+			coercionJSON: `{ "true_values": [ "Y" ] }`,
+			declJSON:     `{ "object": { "a": { "xpath": "non-existing", "type": "boolean", "default": "y" } } }`,
+			expected:     testOrderedObject("a", true),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			coercionJSON := ""
+			if test.coercionJSON != "" {
+				coercionJSON = `"coercion": ` + test.coercionJSON + `,`
+			}
+			finalOutputDecl, err := ValidateTransformDeclarations(
+				[]byte(`{`+coercionJSON+`"transform_declarations": { "FINAL_OUTPUT": `+test.declJSON+` }}`), nil, nil)
+			assert.NoError(t, err)
+			value, err := testParseCtx().ParseNode(testNode(), finalOutputDecl)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Nil(t, value)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, value)
+		})
+	}
+}
+
+func TestValidateCoercion(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		coercionJSON string // top level 'coercion', if any.
+		declJSON     string
+		err          string
+	}{
+		{
+			name:     "not coercible type",
+			declJSON: `{ "object": { "a": { "xpath": "A", "type": "datetime", "coercion": { "trailing_sign": true } } } }`,
+			err:      "'FINAL_OUTPUT.a' cannot set 'coercion' unless 'type' is 'int', 'float', 'decimal' or 'boolean'",
+		},
+		{
+			name:     "no type",
+			declJSON: `{ "xpath": "A", "coercion": { "trailing_sign": true } }`,
+			err:      "'FINAL_OUTPUT' cannot set 'coercion' unless 'type' is 'int', 'float', 'decimal' or 'boolean'",
+		},
+		{
+			name:     "same separators",
+			declJSON: `{ "xpath": "A", "type": "float", "coercion": { "thousands_separator": "." } }`,
+			err:      "'FINAL_OUTPUT' has invalid 'coercion': 'thousands_separator' and 'decimal_separator' cannot both be '.'",
+		},
+		{
+			name:         "same separators after merge",
+			coercionJSON: `{ "thousands_separator": "," }`,
+			declJSON:     `{ "xpath": "A", "type": "float", "coercion": { "decimal_separator": "," } }`,
+			err:          "'FINAL_OUTPUT' has invalid 'coercion': 'thousands_separator' and 'decimal_separator' cannot both be ','",
+		},
+		{
+			name:     "same boolean value",
+			declJSON: `{ "xpath": "A", "type": "boolean", "coercion": { "true_values": [ "Y", "X" ], "false_values": [ "N", "x" ] } }`,
+			err:      "'FINAL_OUTPUT' has invalid 'coercion': 'X' cannot be in both 'true_values' and 'false_values'",
+		},
+		{
+			name:         "invalid top level coercion",
+			coercionJSON: `{ "true_values": [ "Y" ], "false_values": [ "y" ] }`,
+			declJSON:     `{ "xpath": "A" }`,
+			err:          "invalid top level 'coercion': 'Y' cannot be in both 'true_values' and 'false_values'",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			coercionJSON := ""
+			if test.coercionJSON != "" {
+				coercionJSON = `"coercion": ` + test.coercionJSON + `,`
+			}
+			finalOutputDecl, err := ValidateTransformDeclarations(
+				[]byte(`{`+coercionJSON+`"transform_declarations": { "FINAL_OUTPUT": `+test.declJSON+` }}`), nil, nil)
+			assert.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+			assert.Nil(t, finalOutputDecl)
+		})
+	}
+}
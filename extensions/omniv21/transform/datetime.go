@@ -0,0 +1,94 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/jf-tech/go-corelib/times"
+)
+
+const (
+	dateTimeFormatRFC3339     = "rfc3339"
+	dateTimeFormatEpoch       = "epoch"
+	dateTimeFormatEpochMillis = "epoch_millis"
+
+	rfc3339NoTZ = "2006-01-02T15:04:05"
+)
+
+// layoutHasTZ tells whether a Go time layout contains any timezone element, such as 'MST', 'Z07:00'
+// or '-0700'.
+func layoutHasTZ(layout string) bool {
+	return strings.Contains(layout, "MST") || strings.Contains(layout, "Z07") || strings.Contains(layout, "-07")
+}
+
+// isValidLayout tells whether a Go time layout contains any layout element at all.
+func isValidLayout(layout string) bool {
+	return time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC).Format(layout) != layout
+}
+
+// parseDateTime parses a date/time string with the layouts in order, or intelligently if no layouts
+// specified, and returns the parsed time.Time and a flag indicating whether it has tz or not.
+func parseDateTime(s string, layouts []string) (time.Time, bool, error) {
+	if len(layouts) == 0 {
+		return times.SmartParse(s)
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, layoutHasTZ(layout), nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("unable to parse '%s' with any of the layouts", s)
+}
+
+// dateTimeConversion parses a date/time string value and formats it according to the 'datetime' decl.
+// An empty string value results in a nil value.
+func dateTimeConversion(v interface{}, decl *DateTimeDecl) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, errTypeConversionNotSupported
+	}
+	if s == "" {
+		return nil, nil
+	}
+	if decl == nil {
+		decl = &DateTimeDecl{}
+	}
+	t, hasTZ, err := parseDateTime(s, decl.Layouts)
+	if err != nil {
+		return nil, err
+	}
+	// Only use FromTZ if the input value doesn't have tz info baked in.
+	if !hasTZ && decl.FromTZ != nil {
+		t, err = times.OverwriteTZ(t, *decl.FromTZ)
+		if err != nil {
+			return nil, err
+		}
+		hasTZ = true
+	}
+	if decl.ToTZ != nil {
+		if hasTZ {
+			t, err = times.ConvertTZ(t, *decl.ToTZ)
+		} else {
+			t, err = times.OverwriteTZ(t, *decl.ToTZ)
+		}
+		if err != nil {
+			return nil, err
+		}
+		hasTZ = true
+	}
+	switch format := strs.StrPtrOrElse(decl.Format, dateTimeFormatRFC3339); format {
+	case dateTimeFormatRFC3339:
+		if hasTZ {
+			return t.Format(time.RFC3339), nil
+		}
+		return t.Format(rfc3339NoTZ), nil
+	case dateTimeFormatEpoch:
+		return t.Unix(), nil
+	case dateTimeFormatEpochMillis:
+		return t.UnixNano() / int64(time.Millisecond), nil
+	default:
+		return t.Format(format), nil
+	}
+}
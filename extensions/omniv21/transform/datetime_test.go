@@ -0,0 +1,162 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayoutHasTZ(t *testing.T) {
+	assert.False(t, layoutHasTZ("2006-01-02 15:04:05"))
+	assert.True(t, layoutHasTZ("2006-01-02 15:04:05 MST"))
+	assert.True(t, layoutHasTZ(`2006-01-02T15:04:05Z07:00`))
+	assert.True(t, layoutHasTZ("01/02/2006 15:04 -0700"))
+}
+
+func TestIsValidLayout(t *testing.T) {
+	assert.True(t, isValidLayout("01/02/2006"))
+	assert.True(t, isValidLayout("Jan 2"))
+	assert.False(t, isValidLayout("yyyy-mm-dd"))
+}
+
+func TestDateTimeConversion(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		v        interface{}
+		decl     *DateTimeDecl
+		err      string
+		expected interface{}
+	}{
+		{
+			name:     "non string",
+			v:        123,
+			decl:     nil,
+			err:      errTypeConversionNotSupported.Error(),
+			expected: nil,
+		},
+		{
+			name:     "empty string",
+			v:        "",
+			decl:     nil,
+			err:      "",
+			expected: nil,
+		},
+		{
+			name:     "smart parse, no tz",
+			v:        "2020/09/22 12:34:56",
+			decl:     nil,
+			err:      "",
+			expected: "2020-09-22T12:34:56",
+		},
+		{
+			name:     "smart parse, with tz",
+			v:        "2020-09-22T12:34:56-07:00",
+			decl:     &DateTimeDecl{},
+			err:      "",
+			expected: "2020-09-22T12:34:56-07:00",
+		},
+		{
+			name:     "smart parse failure",
+			v:        "invalid",
+			decl:     nil,
+			err:      "unable to parse 'invalid' in any supported date/time format",
+			expected: nil,
+		},
+		{
+			name: "layouts, second one matches",
+			v:    "22.09.2020 12:34",
+			decl: &DateTimeDecl{
+				Layouts: []string{"01/02/2006 15:04", "02.01.2006 15:04"},
+			},
+			err:      "",
+			expected: "2020-09-22T12:34:00",
+		},
+		{
+			name: "layouts, none matches",
+			v:    "2020-09-22",
+			decl: &DateTimeDecl{
+				Layouts: []string{"01/02/2006", "02.01.2006"},
+			},
+			err:      "unable to parse '2020-09-22' with any of the layouts",
+			expected: nil,
+		},
+		{
+			name: "layout with tz, from_tz ignored, to_tz converted",
+			v:    "09/22/2020 12:34 -0700",
+			decl: &DateTimeDecl{
+				Layouts: []string{"01/02/2006 15:04 -0700"},
+				FromTZ:  strs.StrPtr("Asia/Tokyo"),
+				ToTZ:    strs.StrPtr("UTC"),
+			},
+			err:      "",
+			expected: "2020-09-22T19:34:00Z",
+		},
+		{
+			name: "from_tz and to_tz",
+			v:    "2020-09-22 12:34:56",
+			decl: &DateTimeDecl{
+				FromTZ: strs.StrPtr("America/Los_Angeles"),
+				ToTZ:   strs.StrPtr("America/New_York"),
+			},
+			err:      "",
+			expected: "2020-09-22T15:34:56-04:00",
+		},
+		{
+			name: "to_tz only",
+			v:    "2020-09-22 12:34:56",
+			decl: &DateTimeDecl{
+				ToTZ: strs.StrPtr("America/New_York"),
+			},
+			err:      "",
+			expected: "2020-09-22T12:34:56-04:00",
+		},
+		{
+			name: "invalid from_tz",
+			v:    "2020-09-22 12:34:56",
+			decl: &DateTimeDecl{
+				FromTZ: strs.StrPtr("invalid"),
+			},
+			err:      "unknown time zone invalid",
+			expected: nil,
+		},
+		{
+			name: "epoch",
+			v:    "2020-09-22T12:34:56Z",
+			decl: &DateTimeDecl{
+				Format: strs.StrPtr("epoch"),
+			},
+			err:      "",
+			expected: int64(1600778096),
+		},
+		{
+			name: "epoch_millis",
+			v:    "2020-09-22T12:34:56.789Z",
+			decl: &DateTimeDecl{
+				Format: strs.StrPtr("epoch_millis"),
+			},
+			err:      "",
+			expected: int64(1600778096789),
+		},
+		{
+			name: "custom layout",
+			v:    "2020-09-22T12:34:56",
+			decl: &DateTimeDecl{
+				Format: strs.StrPtr("Jan 2, 2006"),
+			},
+			err:      "",
+			expected: "Sep 22, 2020",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := dateTimeConversion(test.v, test.decl)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expected, r)
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package transform
 
 import (
+	"bytes"
 	"encoding/json"
+	"sort"
 
 	"github.com/jf-tech/go-corelib/strs"
 )
@@ -14,12 +16,16 @@ type kind string
 const (
 	kindConst       kind = "const"
 	kindExternal    kind = "external"
+	kindVar         kind = "var"
 	kindField       kind = "field"
 	kindObject      kind = "object"
 	kindArray       kind = "array"
 	kindCustomFunc  kind = "custom_func"
 	kindCustomParse kind = "custom_parse" // Deprecated
 	kindTemplate    kind = "template"
+	kindSwitch      kind = "switch"
+	kindPivot       kind = "pivot"
+	kindUnpivot     kind = "unpivot"
 )
 
 // resultType specifies the types of omni schema's output elements.
@@ -27,10 +33,12 @@ const (
 type resultType string
 
 const (
-	resultTypeInt     resultType = "int"
-	resultTypeFloat   resultType = "float"
-	resultTypeBoolean resultType = "boolean"
-	resultTypeString  resultType = "string"
+	resultTypeInt      resultType = "int"
+	resultTypeFloat    resultType = "float"
+	resultTypeBoolean  resultType = "boolean"
+	resultTypeString   resultType = "string"
+	resultTypeDecimal  resultType = "decimal"
+	resultTypeDateTime resultType = "datetime"
 )
 
 const (
@@ -45,6 +53,9 @@ type CustomFuncDecl struct {
 	Args        []*Decl `json:"args,omitempty"`
 	IgnoreError bool    `json:"ignore_error,omitempty"`
 	fqdn        string  // internal; never unmarshaled from a schema.
+	// internal; the custom func resolved at schema loading time. If nil, the custom func is looked up
+	// by Name at transform time.
+	fn interface{}
 }
 
 // MarshalJSON is the custom JSON marshaler for CustomFuncDecl.
@@ -71,12 +82,128 @@ func (d *CustomFuncDecl) deepCopy() *CustomFuncDecl {
 	return dest
 }
 
+// SwitchCaseDecl is the decl for a case of a "switch".
+type SwitchCaseDecl struct {
+	// When is the predicate of the case. The case is chosen if When yields a truthy value. A case
+	// without When is the default case, which is always chosen.
+	When *Decl `json:"when,omitempty"`
+	// Then is the decl to be used if the case is chosen.
+	Then *Decl `json:"then,omitempty"`
+}
+
+// Note only deep-copy all the public fields, those internal computed fields are not copied.
+func (d *SwitchCaseDecl) deepCopy() *SwitchCaseDecl {
+	dest := &SwitchCaseDecl{}
+	if d.When != nil {
+		dest.When = d.When.deepCopy()
+	}
+	if d.Then != nil {
+		dest.Then = d.Then.deepCopy()
+	}
+	return dest
+}
+
+// PivotDecl is the decl for a "pivot", which builds an object out of the name/value pairs in the input,
+// i.e. an object whose field names are data-driven.
+type PivotDecl struct {
+	// XPath selects the nodes, one for each name/value pair. If not specified, "*" is used.
+	XPath *string `json:"xpath,omitempty"`
+	// Key is evaluated on each of the selected nodes, yielding the field name.
+	Key *Decl `json:"key,omitempty"`
+	// Value is evaluated on each of the selected nodes, yielding the field value.
+	Value *Decl `json:"value,omitempty"`
+}
+
+// Note only deep-copy all the public fields, those internal computed fields are not copied.
+func (d *PivotDecl) deepCopy() *PivotDecl {
+	dest := &PivotDecl{}
+	dest.XPath = strs.CopyStrPtr(d.XPath)
+	if d.Key != nil {
+		dest.Key = d.Key.deepCopy()
+	}
+	if d.Value != nil {
+		dest.Value = d.Value.deepCopy()
+	}
+	return dest
+}
+
+// UnpivotDecl is the decl for an "unpivot", the reverse of "pivot", which builds an array of name/value
+// pair objects out of the nodes in the input, using the nodes' names as the names.
+type UnpivotDecl struct {
+	// XPath selects the nodes, one for each name/value pair. If not specified, "*" is used.
+	XPath *string `json:"xpath,omitempty"`
+	// KeyName is the field name of the pair objects for the node names. If not specified, "key" is used.
+	KeyName *string `json:"key_name,omitempty"`
+	// ValueName is the field name of the pair objects for the values. If not specified, "value" is used.
+	ValueName *string `json:"value_name,omitempty"`
+	// Value is evaluated on each of the selected nodes, yielding the value. If not specified, the node's
+	// text is used.
+	Value *Decl `json:"value,omitempty"`
+}
+
+// Note only deep-copy all the public fields, those internal computed fields are not copied.
+func (d *UnpivotDecl) deepCopy() *UnpivotDecl {
+	dest := &UnpivotDecl{}
+	dest.XPath = strs.CopyStrPtr(d.XPath)
+	dest.KeyName = strs.CopyStrPtr(d.KeyName)
+	dest.ValueName = strs.CopyStrPtr(d.ValueName)
+	if d.Value != nil {
+		dest.Value = d.Value.deepCopy()
+	}
+	return dest
+}
+
+// DateTimeDecl is the decl for how a 'datetime' typed output element is parsed and formatted.
+type DateTimeDecl struct {
+	// Layouts are the Go time layouts tried in order to parse the input value. If none specified, the
+	// input value is parsed intelligently in any of the commonly seen date/time formats.
+	Layouts []string `json:"layouts,omitempty"`
+	// FromTZ is the timezone of the input value, only used if the input value doesn't contain one.
+	FromTZ *string `json:"from_tz,omitempty"`
+	// ToTZ is the timezone the output value is converted into.
+	ToTZ *string `json:"to_tz,omitempty"`
+	// Format is the output format: "rfc3339" (default), "epoch", "epoch_millis", or a Go time layout.
+	Format *string `json:"format,omitempty"`
+}
+
+// Note only deep-copy all the public fields, those internal computed fields are not copied.
+func (d *DateTimeDecl) deepCopy() *DateTimeDecl {
+	dest := &DateTimeDecl{}
+	dest.Layouts = append([]string(nil), d.Layouts...)
+	dest.FromTZ = strs.CopyStrPtr(d.FromTZ)
+	dest.ToTZ = strs.CopyStrPtr(d.ToTZ)
+	dest.Format = strs.CopyStrPtr(d.Format)
+	return dest
+}
+
+// SortKeyDecl is the decl for a sort key of an "array"'s "sort_by".
+type SortKeyDecl struct {
+	// Field is the name of the field of the array's object elements to sort by. If not specified or ".",
+	// the elements themselves are sorted by.
+	Field *string `json:"field,omitempty"`
+	// Type is how the keys are compared: "string" (default), "number", or "datetime".
+	Type *string `json:"type,omitempty"`
+	// Order is the sort order: "asc" (default) or "desc".
+	Order *string `json:"order,omitempty"`
+}
+
+// Note only deep-copy all the public fields, those internal computed fields are not copied.
+func (d *SortKeyDecl) deepCopy() *SortKeyDecl {
+	dest := &SortKeyDecl{}
+	dest.Field = strs.CopyStrPtr(d.Field)
+	dest.Type = strs.CopyStrPtr(d.Type)
+	dest.Order = strs.CopyStrPtr(d.Order)
+	return dest
+}
+
 // Decl is the type for omni schema's `transform_declarations` declarations.
 type Decl struct {
 	// Const indicates the input element is a cost.
 	Const *string `json:"const,omitempty"`
 	// External indicates the input element is from an external property.
 	External *string `json:"external,omitempty"`
+	// Var indicates the input element is from a transform-scoped variable.
+	Var *string `json:"var,omitempty"`
 	// XPath specifies an xpath for an input element.
 	XPath *string `json:"xpath,omitempty"`
 	// XPathDynamic specifies a dynamically constructed xpath for an input element.
@@ -91,19 +218,57 @@ type Decl struct {
 	Object map[string]*Decl `json:"object,omitempty"`
 	// Array specifies the input element is an array.
 	Array []*Decl `json:"array,omitempty"`
+	// Switch specifies the input element is one of the cases, whichever is chosen first.
+	Switch []*SwitchCaseDecl `json:"switch,omitempty"`
+	// Pivot specifies the input element is an object built out of name/value pairs.
+	Pivot *PivotDecl `json:"pivot,omitempty"`
+	// Unpivot specifies the input element is an array of name/value pairs built out of nodes.
+	Unpivot *UnpivotDecl `json:"unpivot,omitempty"`
+	// SortBy specifies the keys an array's elements are sorted by.
+	SortBy []*SortKeyDecl `json:"sort_by,omitempty"`
+	// DistinctBy specifies the fields by which an array's duplicate elements are removed. Field "."
+	// means the elements themselves.
+	DistinctBy []string `json:"distinct_by,omitempty"`
+	// MaxItems specifies the maximum number of elements an array keeps.
+	MaxItems *int `json:"max_items,omitempty"`
+	// Explode specifies the xpath by which a target node explodes into multiple nodes, each of which is
+	// transformed into an output record. Only allowed on FINAL_OUTPUT.
+	Explode *string `json:"explode,omitempty"`
 	// ResultType specifies the desired output type of element.
 	ResultType *resultType `json:"type,omitempty"`
+	// Scale specifies the number of digits after the decimal point of a 'decimal' typed output element.
+	Scale *int `json:"scale,omitempty"`
+	// Rounding specifies how a 'decimal' typed output element is rounded to its Scale.
+	Rounding *string `json:"rounding,omitempty"`
+	// DateTime specifies how a 'datetime' typed output element is parsed and formatted.
+	DateTime *DateTimeDecl `json:"datetime,omitempty"`
+	// Coercion specifies how leniently a string value is converted into an 'int', 'float', 'decimal' or
+	// 'boolean' typed output element.
+	Coercion *CoercionDecl `json:"coercion,omitempty"`
 	// NoTrim specifies space trimming in string value of the output element.
 	NoTrim bool `json:"no_trim,omitempty"`
 	// KeepEmptyOrNull specifies whether to keep an empty/null output or not.
 	KeepEmptyOrNull bool `json:"keep_empty_or_null,omitempty"`
+	// Default specifies the decl whose value is used when the output element resolves to nil or empty.
+	Default *Decl `json:"default,omitempty"`
+	// Validate specifies the rules the output element's value is checked against.
+	Validate *ValidateDecl `json:"validate,omitempty"`
+	// OnError specifies what to do when the output element fails to be computed: "fail" (default),
+	// "null", "default" or "skip".
+	OnError *string `json:"on_error,omitempty"`
+
+	// objectKeys is the declared order of the fields in Object, captured when unmarshaled from a schema.
+	objectKeys []string
 
 	// Internal fields are computed at schema loading time.
 	fqdn     string
 	kind     kind
 	hash     string
+	coercion *CoercionDecl // the effective coercion settings, including the schema's top level ones.
 	children []*Decl
 	parent   *Decl
+	vars     []*VarDecl // only set on the FINAL_OUTPUT decl.
+	filter   *Decl      // only set on the FINAL_OUTPUT decl.
 }
 
 // MarshalJSON is the custom JSON marshaler for Decl.
@@ -119,10 +284,14 @@ func (d Decl) MarshalJSON() ([]byte, error) {
 		// skip hash as it is generated from uuid and would otherwise cause unit test snapshot failures
 		Children []string `json:"children,omitempty"`
 		Parent   string   `json:"parent,omitempty"`
+		// Marshal the declared order of the object fields, so two object decls that differ only in
+		// their field order don't share the same decl hash.
+		ObjectKeys []string `json:"object_keys,omitempty"`
 	}{
-		Alias: Alias(d),
-		FQDN:  emptyToNil(d.fqdn),
-		Kind:  emptyToNil(string(d.kind)),
+		Alias:      Alias(d),
+		ObjectKeys: d.objectKeys,
+		FQDN:       emptyToNil(d.fqdn),
+		Kind:       emptyToNil(string(d.kind)),
 		Children: func() []string {
 			var fqdns []string
 			for _, child := range d.children {
@@ -139,12 +308,88 @@ func (d Decl) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// UnmarshalJSON is the custom JSON unmarshaler for Decl. A JSON string is unmarshaled as a const Decl,
+// which is a shorthand allowed (by the JSON schema) only for 'default'.
+func (d *Decl) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		*d = Decl{Const: &s}
+		return nil
+	}
+	type Alias Decl
+	err := json.Unmarshal(b, (*Alias)(d))
+	if err != nil || d.Object == nil {
+		return err
+	}
+	// Object is a map, which doesn't retain the order of the fields as they are declared in the schema,
+	// so capture the order separately.
+	var raw struct {
+		Object json.RawMessage `json:"object"`
+	}
+	err = json.Unmarshal(b, &raw)
+	if err != nil {
+		return err
+	}
+	d.objectKeys, err = jsonObjectKeys(raw.Object)
+	return err
+}
+
+// jsonObjectKeys returns the keys of a JSON object in their order. If a key appears more than once,
+// only its first occurrence counts.
+func jsonObjectKeys(b []byte) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if _, err := dec.Token(); err != nil { // consumes the opening '{'
+		return nil, err
+	}
+	var keys []string
+	seen := map[string]bool{}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := t.(string)
+		var value json.RawMessage
+		if err = dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// objectFieldNames returns the field names of an object decl in the order they're declared. Fields
+// whose order is unknown (e.g. the decl isn't unmarshaled from a schema) are sorted and placed last.
+func (d *Decl) objectFieldNames() []string {
+	names := make([]string, 0, len(d.Object))
+	ordered := map[string]bool{}
+	for _, name := range d.objectKeys {
+		if _, found := d.Object[name]; found && !ordered[name] {
+			ordered[name] = true
+			names = append(names, name)
+		}
+	}
+	var rest []string
+	for name := range d.Object {
+		if !ordered[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
+}
+
 func (d *Decl) resolveKind() {
 	switch {
 	case d.Const != nil:
 		d.kind = kindConst
 	case d.External != nil:
 		d.kind = kindExternal
+	case d.Var != nil:
+		d.kind = kindVar
 	case d.CustomFunc != nil:
 		d.kind = kindCustomFunc
 	case d.CustomParse != nil:
@@ -155,6 +400,12 @@ func (d *Decl) resolveKind() {
 		d.kind = kindArray
 	case d.Template != nil:
 		d.kind = kindTemplate
+	case d.Switch != nil:
+		d.kind = kindSwitch
+	case d.Pivot != nil:
+		d.kind = kindPivot
+	case d.Unpivot != nil:
+		d.kind = kindUnpivot
 	default:
 		d.kind = kindField
 	}
@@ -170,6 +421,7 @@ func (d *Decl) deepCopy() *Decl {
 	dest := &Decl{}
 	dest.Const = strs.CopyStrPtr(d.Const)
 	dest.External = strs.CopyStrPtr(d.External)
+	dest.Var = strs.CopyStrPtr(d.Var)
 	dest.XPath = strs.CopyStrPtr(d.XPath)
 	if d.XPathDynamic != nil {
 		dest.XPathDynamic = d.XPathDynamic.deepCopy()
@@ -184,15 +436,54 @@ func (d *Decl) deepCopy() *Decl {
 		for childName, childDecl := range d.Object {
 			dest.Object[childName] = childDecl.deepCopy()
 		}
+		// objectKeys isn't computed at schema loading time but is part of what's unmarshaled from a
+		// schema, thus copied.
+		dest.objectKeys = append([]string(nil), d.objectKeys...)
 	}
 	for _, childDecl := range d.Array {
 		dest.Array = append(dest.Array, childDecl.deepCopy())
 	}
+	for _, caseDecl := range d.Switch {
+		dest.Switch = append(dest.Switch, caseDecl.deepCopy())
+	}
+	if d.Pivot != nil {
+		dest.Pivot = d.Pivot.deepCopy()
+	}
+	if d.Unpivot != nil {
+		dest.Unpivot = d.Unpivot.deepCopy()
+	}
+	for _, sortKeyDecl := range d.SortBy {
+		dest.SortBy = append(dest.SortBy, sortKeyDecl.deepCopy())
+	}
+	dest.DistinctBy = append([]string(nil), d.DistinctBy...)
+	if d.MaxItems != nil {
+		maxItems := *d.MaxItems
+		dest.MaxItems = &maxItems
+	}
+	dest.Explode = strs.CopyStrPtr(d.Explode)
 	if d.ResultType != nil {
 		rt := *d.ResultType
 		dest.ResultType = &rt
 	}
+	if d.Scale != nil {
+		scale := *d.Scale
+		dest.Scale = &scale
+	}
+	dest.Rounding = strs.CopyStrPtr(d.Rounding)
+	if d.DateTime != nil {
+		dest.DateTime = d.DateTime.deepCopy()
+	}
+	if d.Coercion != nil {
+		dest.Coercion = d.Coercion.deepCopy()
+	}
 	dest.NoTrim = d.NoTrim
 	dest.KeepEmptyOrNull = d.KeepEmptyOrNull
+	if d.Default != nil {
+		dest.Default = d.Default.deepCopy()
+	}
+	if d.Validate != nil {
+		dest.Validate = d.Validate.deepCopy()
+	}
+	dest.OnError = strs.CopyStrPtr(d.OnError)
 	return dest
 }
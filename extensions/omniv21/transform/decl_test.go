@@ -202,11 +202,15 @@ func verifyDeclDeepCopy(t *testing.T, d1, d2 *Decl) {
 	}
 
 	verifyPtrsInDeepCopy(d1.ResultType, d2.ResultType)
+	verifyPtrsInDeepCopy(d1.Coercion, d2.Coercion)
+	if d1.Coercion != nil {
+		verifyPtrsInDeepCopy(d1.Coercion.TrailingSign, d2.Coercion.TrailingSign)
+	}
 }
 
 func TestDeclDeepCopy(t *testing.T) {
 	declJson := `{ "xpath": "value0", "object": {
-        "field1": { "const": "value1", "type": "boolean" },
+        "field1": { "const": "value1", "type": "boolean", "coercion": { "true_values": [ "Y" ], "trailing_sign": true } },
         "field2": { "external": "value2" },
         "field3": { "xpath": "value3" },
         "field4": { "xpath_dynamic": { "const": "value4" } },
@@ -241,4 +245,32 @@ func TestDeclDeepCopy(t *testing.T) {
 	assert.NoError(t, json.Unmarshal([]byte(declJson), &src))
 	dst := src.deepCopy()
 	verifyDeclDeepCopy(t, &src, dst)
+	assert.Equal(t, src.objectKeys, dst.objectKeys)
+}
+
+func TestDecl_ObjectFieldNames(t *testing.T) {
+	var decl Decl
+	assert.NoError(t, json.Unmarshal([]byte(`{ "object": {
+        "z": { "object": { "b": {}, "a.b": { "object": {} } } },
+        "a": { "const": "{\"x\": 1}" },
+        "m": { "array": [ { "object": { "y": {}, "x": {} } } ] },
+        "a": { "const": "dup" }
+    }}`), &decl))
+	assert.Equal(t, []string{"z", "a", "m"}, decl.objectFieldNames())
+	assert.Equal(t, "dup", *decl.Object["a"].Const)
+	assert.Equal(t, []string{"b", "a.b"}, decl.Object["z"].objectFieldNames())
+	assert.Equal(t, []string{}, decl.Object["z"].Object["a.b"].objectFieldNames())
+	assert.Equal(t, []string{"y", "x"}, decl.Object["m"].Array[0].objectFieldNames())
+
+	// fields whose order is unknown are sorted and placed last.
+	decl.Object["c"] = &Decl{}
+	decl.Object["b"] = &Decl{}
+	delete(decl.Object, "z")
+	assert.Equal(t, []string{"a", "m", "b", "c"}, decl.objectFieldNames())
+
+	assert.Error(t, json.Unmarshal([]byte(`{ "object": { "a": {"const": 1} } }`), &decl))
+	_, err := jsonObjectKeys([]byte(``))
+	assert.Error(t, err)
+	_, err = jsonObjectKeys([]byte(`{ "a": `))
+	assert.Error(t, err)
 }
@@ -0,0 +1,80 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/jf-tech/omniparser/schemahandler"
+)
+
+// Describe returns a read-only view of a validated Decl and its subtree.
+func (d *Decl) Describe() *schemahandler.DeclDescription {
+	return d.describe(finalOutput)
+}
+
+func (d *Decl) describe(name string) *schemahandler.DeclDescription {
+	desc := &schemahandler.DeclDescription{
+		Name:            name,
+		FQDN:            d.fqdn,
+		Kind:            string(d.kind),
+		XPath:           strs.StrPtrOrElse(d.XPath, ""),
+		Const:           strs.StrPtrOrElse(d.Const, ""),
+		External:        strs.StrPtrOrElse(d.External, ""),
+		Var:             strs.StrPtrOrElse(d.Var, ""),
+		CustomParse:     strs.StrPtrOrElse(d.CustomParse, ""),
+		OnError:         strs.StrPtrOrElse(d.OnError, ""),
+		NoTrim:          d.NoTrim,
+		KeepEmptyOrNull: d.KeepEmptyOrNull,
+	}
+	if d.XPathDynamic != nil {
+		desc.XPathDynamic = d.XPathDynamic.describe("xpath_dynamic")
+	}
+	if d.ResultType != nil {
+		desc.ResultType = string(*d.ResultType)
+	}
+	if d.Default != nil {
+		desc.Default = d.Default.describe("default")
+	}
+	if d.CustomFunc != nil {
+		desc.CustomFunc = &schemahandler.CustomFuncDescription{
+			Name:        d.CustomFunc.Name,
+			IgnoreError: d.CustomFunc.IgnoreError,
+		}
+		for i, arg := range d.CustomFunc.Args {
+			desc.CustomFunc.Args = append(desc.CustomFunc.Args, arg.describe(fmt.Sprintf("arg[%d]", i+1)))
+		}
+	}
+	switch d.kind {
+	case kindObject:
+		for _, childName := range d.objectFieldNames() {
+			desc.Children = append(desc.Children, d.Object[childName].describe(childName))
+		}
+	case kindArray:
+		for i, elem := range d.Array {
+			desc.Children = append(desc.Children, elem.describe(fmt.Sprintf("elem[%d]", i+1)))
+		}
+	case kindSwitch:
+		for _, caseDecl := range d.Switch {
+			caseDesc := &schemahandler.SwitchCaseDescription{Then: caseDecl.Then.describe("then")}
+			if caseDecl.When != nil {
+				caseDesc.When = caseDecl.When.describe("when")
+			}
+			desc.Cases = append(desc.Cases, caseDesc)
+		}
+	case kindPivot:
+		desc.Pivot = &schemahandler.PivotDescription{
+			XPath: strs.StrPtrOrElse(d.Pivot.XPath, pivotDefaultXPath),
+			Key:   d.Pivot.Key.describe("key"),
+			Value: d.Pivot.Value.describe("value"),
+		}
+	case kindUnpivot:
+		desc.Unpivot = &schemahandler.UnpivotDescription{
+			XPath:     strs.StrPtrOrElse(d.Unpivot.XPath, pivotDefaultXPath),
+			KeyName:   d.Unpivot.keyName(),
+			ValueName: d.Unpivot.valueName(),
+			Value:     d.Unpivot.Value.describe("value"),
+		}
+	}
+	return desc
+}
@@ -0,0 +1,46 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy"
+	"github.com/jf-tech/go-corelib/jsons"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jf-tech/omniparser/customfuncs"
+	"github.com/jf-tech/omniparser/transformctx"
+)
+
+func TestDecl_Describe(t *testing.T) {
+	finalOutputDecl, err := ValidateTransformDeclarations([]byte(`{
+        "transform_declarations": {
+            "FINAL_OUTPUT": { "xpath": "/a", "object": {
+                "id": { "xpath": "id", "type": "int" },
+                "name": { "xpath_dynamic": { "template": "name_xpath" }, "no_trim": true },
+                "tags": { "array": [
+                    { "const": "tag1" },
+                    { "external": "tag2" },
+                    { "xpath": "tags/tag", "type": "string" }
+                ]},
+                "full_name": { "custom_func": {
+                    "name": "test_func",
+                    "args": [ { "xpath": "first" }, { "const": " ", "no_trim": true }, { "xpath": "last" } ],
+                    "ignore_error": true
+                }, "keep_empty_or_null": true },
+                "address": { "template": "address" },
+                "kind": { "switch": [
+                    { "when": { "xpath": "kind" }, "then": { "xpath": "kind" } },
+                    { "then": { "const": "unknown" } }
+                ]}
+            }},
+            "name_xpath": { "const": "name" },
+            "address": { "xpath": "addr", "object": {
+                "zip": { "xpath": "zip" }
+            }}
+        }
+    }`), customfuncs.CustomFuncs{
+		"test_func": func(*transformctx.Ctx, ...string) (string, error) { return "", nil },
+	}, nil)
+	assert.NoError(t, err)
+	cupaloy.SnapshotT(t, jsons.BPM(finalOutputDecl.Describe()))
+}
@@ -0,0 +1,123 @@
+package transform
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jf-tech/omniparser/idr"
+	"github.com/jf-tech/omniparser/schemahandler"
+)
+
+// Explain mode: when the transform ctx has Explain on, parseCtx records a trace for every decl evaluation
+// (i.e. every ParseNode call), in the evaluation order. The trace of the decl being evaluated is on top of
+// traceStack, so that the parse functions can record what they find along the way (the xpath evaluated,
+// the raw value, etc) without passing the trace around. All the trace recording methods below are no-op
+// if explain mode is off.
+
+// Traces returns the traces of all the decl evaluations done by the parseCtx so far, or nil if explain
+// mode is off.
+func (p *parseCtx) Traces() []*schemahandler.DeclTrace {
+	return p.traces
+}
+
+func (p *parseCtx) curTrace() *schemahandler.DeclTrace {
+	if len(p.traceStack) == 0 {
+		return nil
+	}
+	return p.traceStack[len(p.traceStack)-1]
+}
+
+func (p *parseCtx) beginTrace(decl *Decl) {
+	if !p.explain {
+		return
+	}
+	t := &schemahandler.DeclTrace{DeclFQDN: decl.fqdn}
+	p.traces = append(p.traces, t)
+	p.traceStack = append(p.traceStack, t)
+}
+
+// endTrace records the result of the evaluation of the decl on top of traceStack, and pops it.
+func (p *parseCtx) endTrace(decl *Decl, value interface{}, err error) {
+	t := p.curTrace()
+	if t == nil {
+		return
+	}
+	p.traceStack = p.traceStack[:len(p.traceStack)-1]
+	if err != nil {
+		t.Err = err.Error()
+		return
+	}
+	t.Value = value
+	if (value != nil && !isEmpty(value)) || decl.KeepEmptyOrNull {
+		// the value is output, even if, say, the xpath matched no node, thanks to 'default'.
+		t.Omitted = ""
+		return
+	}
+	switch raw := reflect.ValueOf(t.RawValue); {
+	case t.Omitted != "":
+	case t.IgnoredError != "":
+		t.Omitted = "custom_func error ignored"
+	case raw.Kind() == reflect.String && raw.Len() > 0:
+		t.Omitted = "value is empty after trimming"
+	case value == nil && t.RawValue == nil:
+		t.Omitted = "value is null"
+	default:
+		t.Omitted = "value is empty"
+	}
+}
+
+func (p *parseCtx) traceCacheHit(decl *Decl, value interface{}) {
+	if p.explain {
+		p.traces = append(p.traces, &schemahandler.DeclTrace{DeclFQDN: decl.fqdn, Value: value, CacheHit: true})
+	}
+}
+
+func (p *parseCtx) traceXPath(xpath string, dynamic bool, nodes ...*idr.Node) {
+	if t := p.curTrace(); t != nil {
+		recordXPath(t, xpath, dynamic, nodes)
+	}
+}
+
+// traceArrayElemXPath records the xpath query done at array level for an array element decl, see
+// parseArray(), as a trace of its own, followed by the traces of the element decl evaluations on each
+// of the matched nodes.
+func (p *parseCtx) traceArrayElemXPath(elemDecl *Decl, xpath string, dynamic bool, nodes []*idr.Node, err error) {
+	if !p.explain {
+		return
+	}
+	t := &schemahandler.DeclTrace{DeclFQDN: elemDecl.fqdn}
+	if err != nil {
+		t.Omitted = fmt.Sprintf("xpath_dynamic failed: %s", err.Error())
+	} else {
+		recordXPath(t, xpath, dynamic, nodes)
+	}
+	p.traces = append(p.traces, t)
+}
+
+func recordXPath(t *schemahandler.DeclTrace, xpath string, dynamic bool, nodes []*idr.Node) {
+	t.XPath, t.XPathDynamic = xpath, dynamic
+	for _, n := range nodes {
+		t.MatchedNodes = append(t.MatchedNodes, idr.JSONify2(n))
+	}
+	if len(nodes) == 0 {
+		t.Omitted = fmt.Sprintf("xpath '%s' matched no node", xpath)
+	}
+}
+
+func (p *parseCtx) traceRawValue(v interface{}) {
+	if t := p.curTrace(); t != nil {
+		t.RawValue = v
+	}
+}
+
+func (p *parseCtx) traceIgnoredError(err error) {
+	if t := p.curTrace(); t != nil {
+		t.IgnoredError = err.Error()
+	}
+}
+
+func (p *parseCtx) traceOmitted(format string, args ...interface{}) {
+	if t := p.curTrace(); t != nil {
+		t.Omitted = fmt.Sprintf(format, args...)
+	}
+}
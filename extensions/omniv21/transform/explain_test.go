@@ -0,0 +1,82 @@
+package transform
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy"
+	"github.com/jf-tech/go-corelib/jsons"
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jf-tech/omniparser/customfuncs"
+	"github.com/jf-tech/omniparser/transformctx"
+)
+
+func TestParseCtx_Explain(t *testing.T) {
+	funcs := customfuncs.Merge(
+		customfuncs.CustomFuncs{
+			"test_fail": func(_ *transformctx.Ctx) (string, error) { return "", errors.New("test_fail failed") },
+		},
+		customfuncs.CommonCustomFuncs)
+	finalOutputDecl, err := ValidateTransformDeclarations([]byte(`{
+        "transform_declarations": {
+            "FINAL_OUTPUT": { "object": {
+                "const_trimmed": { "const": " x " },
+                "field": { "xpath": "B" },
+                "field_cached": { "xpath": "B" },
+                "field_no_match": { "xpath": "X" },
+                "field_dynamic": { "xpath_dynamic": { "const": "C" } },
+                "field_trimmed_to_empty": { "const": "  " },
+                "field_default": { "xpath": "X", "default": "d" },
+                "func_error_ignored": { "custom_func": { "name": "test_fail", "ignore_error": true } },
+                "field_on_error": { "xpath": "B", "type": "int", "on_error": "null" },
+                "array": { "array": [ { "xpath": "C" }, { "xpath": "X" } ] },
+                "switch": { "switch": [ { "when": { "xpath": "X" }, "then": { "const": "then" } } ] }
+            }}
+        }
+    }`), funcs, nil)
+	assert.NoError(t, err)
+	p := NewParseCtx(&transformctx.Ctx{Explain: true}, funcs, nil)
+	value, err := p.ParseNode(testNode(), finalOutputDecl)
+	assert.NoError(t, err)
+	assert.Equal(t, testOrderedObject(
+		"const_trimmed", "x",
+		"field", "b",
+		"field_cached", "b",
+		"field_dynamic", "c",
+		"field_default", "d",
+		"array", []interface{}{"c"},
+	), value)
+	cupaloy.SnapshotT(t, jsons.BPM(p.Traces()))
+}
+
+func TestParseCtx_Explain_Off(t *testing.T) {
+	p := NewParseCtx(&transformctx.Ctx{}, nil, nil)
+	_, err := p.ParseNode(testNode(), &Decl{kind: kindConst, Const: strs.StrPtr("x")})
+	assert.NoError(t, err)
+	assert.Nil(t, p.Traces())
+	p = NewParseCtx(nil, nil, nil)
+	_, err = p.ParseNode(testNode(), &Decl{kind: kindConst, Const: strs.StrPtr("x")})
+	assert.NoError(t, err)
+	assert.Nil(t, p.Traces())
+}
+
+func TestParseCtx_Explain_Error(t *testing.T) {
+	finalOutputDecl, err := ValidateTransformDeclarations([]byte(`{
+        "transform_declarations": {
+            "FINAL_OUTPUT": { "object": { "n": { "const": "abc", "type": "int" } } }
+        }
+    }`), nil, nil)
+	assert.NoError(t, err)
+	p := NewParseCtx(&transformctx.Ctx{Explain: true}, nil, nil)
+	_, err = p.ParseNode(testNode(), finalOutputDecl)
+	assert.Error(t, err)
+	traces := p.Traces()
+	assert.Equal(t, 2, len(traces))
+	assert.Equal(t, "FINAL_OUTPUT", traces[0].DeclFQDN)
+	assert.Equal(t, err.Error(), traces[0].Err)
+	assert.Equal(t, "FINAL_OUTPUT.n", traces[1].DeclFQDN)
+	assert.Equal(t, "abc", traces[1].RawValue)
+	assert.Equal(t, err.Error(), traces[1].Err)
+}
@@ -0,0 +1,37 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/jf-tech/go-corelib/caches"
+
+	"github.com/jf-tech/omniparser/idr"
+)
+
+func (ctx *validateCtx) validateExplode(fqdn string, decl *Decl) error {
+	if decl.Explode == nil {
+		return nil
+	}
+	if fqdn != finalOutput {
+		return fmt.Errorf("'%s' cannot set 'explode' unless it is '%s'", fqdn, finalOutput)
+	}
+	if _, err := caches.GetXPathExpr(*decl.Explode); err != nil {
+		return fmt.Errorf("'%s' has invalid 'explode' value '%s': %s", fqdn, *decl.Explode, err.Error())
+	}
+	return nil
+}
+
+// ExplodeNode returns the nodes, each of which is to be transformed into an output record, that the target
+// node n explodes into, as specified by the 'explode' xpath of the FINAL_OUTPUT decl d. If d has no
+// 'explode', n itself is returned. The exploded nodes stay in the IDR tree, so the transforms can still
+// access the target node and its ancestors, e.g. with xpath "..".
+func (d *Decl) ExplodeNode(n *idr.Node) ([]*idr.Node, error) {
+	if d == nil || d.Explode == nil {
+		return []*idr.Node{n}, nil
+	}
+	nodes, err := idr.MatchAll(n, *d.Explode)
+	if err != nil {
+		return nil, declErr(d.fqdn, err, "xpath query '%s' on '%s' failed: %s", *d.Explode, d.fqdn, err.Error())
+	}
+	return nodes, nil
+}
@@ -0,0 +1,76 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jf-tech/omniparser/idr"
+)
+
+func TestExplodeNode(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		declJSON string
+		err      string
+		expected []string
+	}{
+		{
+			name:     "no explode",
+			declJSON: `{ "transform_declarations": { "FINAL_OUTPUT": { "object": {} } } }`,
+			expected: []string{"A"},
+		},
+		{
+			name:     "explode",
+			declJSON: `{ "transform_declarations": { "FINAL_OUTPUT": { "explode": "*", "object": {} } } }`,
+			expected: []string{"B", "C"},
+		},
+		{
+			name:     "explode into nothing",
+			declJSON: `{ "transform_declarations": { "FINAL_OUTPUT": { "explode": "D", "object": {} } } }`,
+			expected: nil,
+		},
+		{
+			name: "explode via template",
+			declJSON: `{ "transform_declarations": {
+                "FINAL_OUTPUT": { "template": "t" },
+                "t": { "explode": "C", "object": {} }
+            }}`,
+			expected: []string{"C"},
+		},
+		{
+			name: "explode on non FINAL_OUTPUT",
+			declJSON: `{ "transform_declarations": {
+                "FINAL_OUTPUT": { "object": { "a": { "explode": "B", "object": {} } } }
+            }}`,
+			err: "'FINAL_OUTPUT.a' cannot set 'explode' unless it is 'FINAL_OUTPUT'",
+		},
+		{
+			name:     "invalid explode",
+			declJSON: `{ "transform_declarations": { "FINAL_OUTPUT": { "explode": "<", "object": {} } } }`,
+			err:      "'FINAL_OUTPUT' has invalid 'explode' value '<': expression must evaluate to a node-set",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			finalOutputDecl, err := ValidateTransformDeclarations([]byte(test.declJSON), nil, nil)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Nil(t, finalOutputDecl)
+				return
+			}
+			assert.NoError(t, err)
+			nodes, err := finalOutputDecl.ExplodeNode(testNode())
+			assert.NoError(t, err)
+			var names []string
+			for _, n := range nodes {
+				names = append(names, n.Data)
+			}
+			assert.Equal(t, test.expected, names)
+		})
+	}
+	n := testNode()
+	nodes, err := (*Decl)(nil).ExplodeNode(n)
+	assert.NoError(t, err)
+	assert.Equal(t, []*idr.Node{n}, nodes)
+}
@@ -0,0 +1,199 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/jf-tech/omniparser/decimal"
+	"github.com/jf-tech/omniparser/errs"
+)
+
+const (
+	validateOnFailError = "error"
+	validateOnFailWarn  = "warn"
+)
+
+// ValidateDecl is the decl for the 'validate' rules of an output element, checked against its final value.
+type ValidateDecl struct {
+	// Required requires the value to be neither null nor empty. All the other rules are only checked
+	// against non-null values.
+	Required bool `json:"required,omitempty"`
+	// Pattern is the regular expression the value (in its string form) must match.
+	Pattern *string `json:"pattern,omitempty"`
+	// Enum is the list of allowed values (in their string form).
+	Enum []string `json:"enum,omitempty"`
+	// MinLength and MaxLength are the bounds of the number of characters of a string value, or the
+	// number of elements of an array value.
+	MinLength *int `json:"min_length,omitempty"`
+	MaxLength *int `json:"max_length,omitempty"`
+	// Min and Max are the bounds of a numeric value.
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+	// OnFail decides what happens if a rule is violated: "error" (default) fails the record, while
+	// "warn" only reports a warning and keeps the value.
+	OnFail *string `json:"on_fail,omitempty"`
+
+	// Internal fields are computed at schema loading time.
+	pattern *regexp.Regexp
+}
+
+// Note only deep-copy all the public fields, those internal computed fields are not copied.
+func (d *ValidateDecl) deepCopy() *ValidateDecl {
+	dest := &ValidateDecl{}
+	dest.Required = d.Required
+	dest.Pattern = strs.CopyStrPtr(d.Pattern)
+	dest.Enum = append([]string(nil), d.Enum...)
+	if d.MinLength != nil {
+		minLength := *d.MinLength
+		dest.MinLength = &minLength
+	}
+	if d.MaxLength != nil {
+		maxLength := *d.MaxLength
+		dest.MaxLength = &maxLength
+	}
+	if d.Min != nil {
+		min := *d.Min
+		dest.Min = &min
+	}
+	if d.Max != nil {
+		max := *d.Max
+		dest.Max = &max
+	}
+	dest.OnFail = strs.CopyStrPtr(d.OnFail)
+	return dest
+}
+
+func (ctx *validateCtx) validateValidate(fqdn string, decl *Decl) error {
+	v := decl.Validate
+	if v == nil {
+		return nil
+	}
+	if v.Pattern != nil {
+		pattern, err := regexp.Compile(*v.Pattern)
+		if err != nil {
+			return fmt.Errorf("'%s' has invalid 'validate.pattern' value '%s': %s", fqdn, *v.Pattern, err.Error())
+		}
+		v.pattern = pattern
+	}
+	if v.MinLength != nil && v.MaxLength != nil && *v.MinLength > *v.MaxLength {
+		return fmt.Errorf("'%s' has 'validate.min_length' greater than 'validate.max_length'", fqdn)
+	}
+	if v.Min != nil && v.Max != nil && *v.Min > *v.Max {
+		return fmt.Errorf("'%s' has 'validate.min' greater than 'validate.max'", fqdn)
+	}
+	return nil
+}
+
+// checkValidate checks the final value of a decl against the decl's 'validate' rules. A violation fails
+// the record with an error, or, if 'on_fail' is "warn", is reported as a warning.
+func (p *parseCtx) checkValidate(decl *Decl, value interface{}) error {
+	verr := checkValidateRules(decl, value)
+	if verr == nil {
+		return nil
+	}
+	err := declErr(decl.fqdn, verr, "%s", verr.Error())
+	if strs.StrPtrOrElse(decl.Validate.OnFail, validateOnFailError) == validateOnFailWarn {
+		p.warn(err)
+		return nil
+	}
+	return err
+}
+
+func checkValidateRules(decl *Decl, value interface{}) *errs.ValidationError {
+	v := decl.Validate
+	fail := func(rule, format string, args ...interface{}) *errs.ValidationError {
+		return &errs.ValidationError{DeclFQDN: decl.fqdn, Rule: rule, Msg: fmt.Sprintf(format, args...)}
+	}
+	if value == nil || isEmpty(value) {
+		if v.Required {
+			return fail("required", "value is missing")
+		}
+		return nil
+	}
+	s := valueString(value)
+	if v.pattern != nil && !v.pattern.MatchString(s) {
+		return fail("pattern", "value '%s' doesn't match '%s'", s, *v.Pattern)
+	}
+	if len(v.Enum) > 0 && !isOneOf(s, v.Enum) {
+		return fail("enum", "value '%s' is not one of %s", s, strings.Join(
+			strs.NoErrMapSlice(v.Enum, func(e string) string { return "'" + e + "'" }), ", "))
+	}
+	if v.MinLength != nil || v.MaxLength != nil {
+		length := valueLength(value)
+		if v.MinLength != nil && length < *v.MinLength {
+			return fail("min_length", "value '%s' has length %d, less than %d", s, length, *v.MinLength)
+		}
+		if v.MaxLength != nil && length > *v.MaxLength {
+			return fail("max_length", "value '%s' has length %d, greater than %d", s, length, *v.MaxLength)
+		}
+	}
+	if v.Min != nil || v.Max != nil {
+		d, err := resultTypeConversion(value, resultTypeDecimal)
+		if err != nil {
+			rule := "min"
+			if v.Min == nil {
+				rule = "max"
+			}
+			return fail(rule, "value '%s' is not a number", s)
+		}
+		if v.Min != nil && d.(decimal.Decimal).Cmp(mustDecimalFromFloat64(*v.Min)) < 0 {
+			return fail("min", "value '%s' is less than %v", s, *v.Min)
+		}
+		if v.Max != nil && d.(decimal.Decimal).Cmp(mustDecimalFromFloat64(*v.Max)) > 0 {
+			return fail("max", "value '%s' is greater than %v", s, *v.Max)
+		}
+	}
+	return nil
+}
+
+// valueString returns the string form of a value, as it appears in the output (sans JSON quotes), e.g.
+// "12.50" for a decimal, or `{"a":"x"}` for an object.
+func valueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case decimal.Decimal:
+		return v.String()
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		if b, err := json.Marshal(value); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func isOneOf(s string, values []string) bool {
+	for _, v := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// valueLength returns the number of elements of an array (or object) value, or the number of characters
+// of the string form of any other value.
+func valueLength(value interface{}) int {
+	if obj, ok := value.(*orderedObject); ok {
+		return obj.len()
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice, reflect.Map:
+		return reflect.ValueOf(value).Len()
+	}
+	return utf8.RuneCountInString(valueString(value))
+}
+
+func mustDecimalFromFloat64(f float64) decimal.Decimal {
+	// f comes from JSON parsing, thus always finite and convertible.
+	d, _ := decimal.FromFloat64(f)
+	return d
+}
@@ -0,0 +1,191 @@
+package transform
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jf-tech/omniparser/errs"
+)
+
+func TestParseCtx_Validate(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		declJSON      string
+		expectedValue interface{}
+		expectedErr   string
+		expectedRule  string
+	}{
+		{
+			name:          "all rules passed",
+			declJSON:      `{ "xpath": "B", "validate": { "required": true, "pattern": "^[a-z]$", "enum": [ "a", "b" ], "min_length": 1, "max_length": 1 } }`,
+			expectedValue: "b",
+		},
+		{
+			name:          "missing value not required",
+			declJSON:      `{ "xpath": "X", "validate": { "pattern": "^[a-z]$", "min": 1 } }`,
+			expectedValue: nil,
+		},
+		{
+			name:         "required",
+			declJSON:     `{ "xpath": "X", "validate": { "required": true } }`,
+			expectedErr:  "'FINAL_OUTPUT.test' failed validation rule 'required': value is missing",
+			expectedRule: "required",
+		},
+		{
+			name:          "required satisfied by default",
+			declJSON:      `{ "xpath": "X", "default": "x", "validate": { "required": true } }`,
+			expectedValue: "x",
+		},
+		{
+			name:         "pattern",
+			declJSON:     `{ "xpath": "B", "validate": { "pattern": "^[0-9]+$" } }`,
+			expectedErr:  "'FINAL_OUTPUT.test' failed validation rule 'pattern': value 'b' doesn't match '^[0-9]+$'",
+			expectedRule: "pattern",
+		},
+		{
+			name:         "enum",
+			declJSON:     `{ "xpath": "B", "validate": { "enum": [ "x", "y" ] } }`,
+			expectedErr:  "'FINAL_OUTPUT.test' failed validation rule 'enum': value 'b' is not one of 'x', 'y'",
+			expectedRule: "enum",
+		},
+		{
+			name:         "min_length",
+			declJSON:     `{ "xpath": "B", "validate": { "min_length": 2 } }`,
+			expectedErr:  "'FINAL_OUTPUT.test' failed validation rule 'min_length': value 'b' has length 1, less than 2",
+			expectedRule: "min_length",
+		},
+		{
+			name:         "max_length on array",
+			declJSON:     `{ "array": [ { "xpath": "B" }, { "xpath": "C" } ], "validate": { "max_length": 1 } }`,
+			expectedErr:  `'FINAL_OUTPUT.test' failed validation rule 'max_length': value '["b","c"]' has length 2, greater than 1`,
+			expectedRule: "max_length",
+		},
+		{
+			name:         "pattern on object",
+			declJSON:     `{ "object": { "b": { "xpath": "B" } }, "validate": { "pattern": "^\\{\"b\":\"c\"\\}$" } }`,
+			expectedErr:  `'FINAL_OUTPUT.test' failed validation rule 'pattern': value '{"b":"b"}' doesn't match '^\{"b":"c"\}$'`,
+			expectedRule: "pattern",
+		},
+		{
+			name:          "enum on object",
+			declJSON:      `{ "object": { "b": { "xpath": "B" } }, "validate": { "enum": [ "{\"b\":\"b\"}" ] } }`,
+			expectedValue: testOrderedObject("b", "b"),
+		},
+		{
+			name:          "enum on decimal",
+			declJSON:      `{ "const": "1.50", "type": "decimal", "validate": { "enum": [ "1.50" ] } }`,
+			expectedValue: testDecimal("1.50"),
+		},
+		{
+			name:          "numeric range passed",
+			declJSON:      `{ "const": "10.50", "type": "decimal", "validate": { "min": 10.5, "max": 10.5 } }`,
+			expectedValue: testDecimal("10.50"),
+		},
+		{
+			name:         "min",
+			declJSON:     `{ "const": "-1", "type": "int", "validate": { "min": 0 } }`,
+			expectedErr:  "'FINAL_OUTPUT.test' failed validation rule 'min': value '-1' is less than 0",
+			expectedRule: "min",
+		},
+		{
+			name:         "max",
+			declJSON:     `{ "const": "100.01", "type": "float", "validate": { "max": 100 } }`,
+			expectedErr:  "'FINAL_OUTPUT.test' failed validation rule 'max': value '100.01' is greater than 100",
+			expectedRule: "max",
+		},
+		{
+			name:         "not a number",
+			declJSON:     `{ "xpath": "B", "validate": { "max": 100 } }`,
+			expectedErr:  "'FINAL_OUTPUT.test' failed validation rule 'max': value 'b' is not a number",
+			expectedRule: "max",
+		},
+		{
+			name:          "warn only",
+			declJSON:      `{ "xpath": "B", "validate": { "enum": [ "x" ], "on_fail": "warn" } }`,
+			expectedValue: "b",
+		},
+		{
+			name:          "template site validate",
+			declJSON:      `{ "template": "t", "validate": { "enum": [ "b" ] } }`,
+			expectedValue: "b",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			finalOutputDecl, err := ValidateTransformDeclarations(
+				[]byte(`{"transform_declarations": {
+					"FINAL_OUTPUT": { "object": { "test": `+test.declJSON+` }},
+					"t": { "xpath": "B", "validate": { "enum": [ "x" ] } }
+				}}`),
+				testParseCtx().customFuncs, nil)
+			assert.NoError(t, err)
+			value, err := testParseCtx().ParseNode(testNode(), finalOutputDecl.Object["test"])
+			switch test.expectedErr {
+			case "":
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedValue, value)
+			default:
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+				var verr *errs.ValidationError
+				assert.True(t, errors.As(err, &verr))
+				assert.Equal(t, test.expectedRule, verr.Rule)
+				assert.Equal(t, "FINAL_OUTPUT.test", verr.DeclFQDN)
+				var terr *errs.TransformError
+				assert.True(t, errors.As(err, &terr))
+				assert.Equal(t, "FINAL_OUTPUT.test", terr.DeclFQDN)
+				assert.Nil(t, value)
+			}
+		})
+	}
+}
+
+func TestParseCtx_Validate_Warn(t *testing.T) {
+	finalOutputDecl, err := ValidateTransformDeclarations(
+		[]byte(`{"transform_declarations": { "FINAL_OUTPUT": { "object": {
+			"b": { "xpath": "B", "validate": { "pattern": "^x$", "on_fail": "warn" } },
+			"c": { "xpath": "C", "validate": { "pattern": "^c$", "on_fail": "warn" } }
+		}}}}`),
+		testParseCtx().customFuncs, nil)
+	assert.NoError(t, err)
+	p := testParseCtx()
+	var warnings []string
+	p.transformCtx.WarningSink = func(warning error) { warnings = append(warnings, warning.Error()) }
+	value, err := p.ParseNode(testNode(), finalOutputDecl)
+	assert.NoError(t, err)
+	assert.Equal(t, testOrderedObject("b", "b", "c", "c"), value)
+	assert.Equal(t, []string{"'FINAL_OUTPUT.b' failed validation rule 'pattern': value 'b' doesn't match '^x$'"}, warnings)
+}
+
+func TestValidateValidate(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		declJSON string
+		err      string
+	}{
+		{
+			name:     "invalid pattern",
+			declJSON: `{ "xpath": "B", "validate": { "pattern": "[" } }`,
+			err:      "'FINAL_OUTPUT' has invalid 'validate.pattern' value '[': error parsing regexp: missing closing ]: `[`",
+		},
+		{
+			name:     "min_length greater than max_length",
+			declJSON: `{ "xpath": "B", "validate": { "min_length": 2, "max_length": 1 } }`,
+			err:      "'FINAL_OUTPUT' has 'validate.min_length' greater than 'validate.max_length'",
+		},
+		{
+			name:     "min greater than max",
+			declJSON: `{ "xpath": "B", "validate": { "min": 2, "max": 1.5 } }`,
+			err:      "'FINAL_OUTPUT' has 'validate.min' greater than 'validate.max'",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			finalOutputDecl, err := ValidateTransformDeclarations(
+				[]byte(`{"transform_declarations": { "FINAL_OUTPUT": `+test.declJSON+` }}`), nil, nil)
+			assert.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+			assert.Nil(t, finalOutputDecl)
+		})
+	}
+}
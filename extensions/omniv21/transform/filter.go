@@ -0,0 +1,39 @@
+package transform
+
+import (
+	"github.com/jf-tech/omniparser/idr"
+)
+
+const (
+	// filter is the special name of an optional Decl that decides whether a target record is transformed
+	// or silently skipped.
+	filter = "FILTER"
+)
+
+// validateFilter validates the schema's optional `FILTER` decl.
+func (ctx *validateCtx) validateFilter() (*Decl, error) {
+	filterDecl, found := ctx.Decls[filter]
+	if !found {
+		return nil, nil
+	}
+	filterDecl, err := ctx.validateDecl(filter, filterDecl, []string{filter})
+	if err != nil {
+		return nil, err
+	}
+	linkParent(filterDecl)
+	return filterDecl, nil
+}
+
+// FilterNode tells whether the target node n passes the `FILTER` decl of the schema, whose FINAL_OUTPUT
+// decl is finalOutputDecl, i.e. the `FILTER` decl yields a truthy value on n (see isPredicateTruthy). If the
+// schema has no `FILTER` decl, all target nodes pass.
+func (p *parseCtx) FilterNode(n *idr.Node, finalOutputDecl *Decl) (bool, error) {
+	if finalOutputDecl == nil || finalOutputDecl.filter == nil {
+		return true, nil
+	}
+	v, err := p.ParseNode(n, finalOutputDecl.filter)
+	if err != nil {
+		return false, err
+	}
+	return isPredicateTruthy(finalOutputDecl.filter, v), nil
+}
@@ -0,0 +1,126 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jf-tech/omniparser/idr"
+)
+
+func TestFilterNode(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		node     *idr.Node // if nil, testNode() is used.
+		declJSON string
+		err      string
+		pass     bool
+		parseErr string
+	}{
+		{
+			name:     "no filter",
+			declJSON: `{ "transform_declarations": { "FINAL_OUTPUT": { "const": "x" } } }`,
+			pass:     true,
+		},
+		{
+			name: "xpath predicate matched",
+			declJSON: `{ "transform_declarations": {
+                "FILTER": { "xpath": ".[B = 'b']" },
+                "FINAL_OUTPUT": { "const": "x" }
+            }}`,
+			pass: true,
+		},
+		{
+			name: "xpath predicate not matched",
+			declJSON: `{ "transform_declarations": {
+                "FILTER": { "xpath": "B[. != 'b']" },
+                "FINAL_OUTPUT": { "const": "x" }
+            }}`,
+			pass: false,
+		},
+		{
+			name: "xpath predicate matched on node text '0'",
+			node: testNodeWithChild("status", "0"),
+			declJSON: `{ "transform_declarations": {
+                "FILTER": { "xpath": "status[. = '0']" },
+                "FINAL_OUTPUT": { "const": "x" }
+            }}`,
+			pass: true,
+		},
+		{
+			name: "template xpath predicate matched on node text 'F'",
+			node: testNodeWithChild("flag", "F"),
+			declJSON: `{ "transform_declarations": {
+                "FILTER": { "template": "is_f" },
+                "FINAL_OUTPUT": { "const": "x" },
+                "is_f": { "xpath": "flag[. = 'F']" }
+            }}`,
+			pass: true,
+		},
+		{
+			name: "const predicate '0'",
+			declJSON: `{ "transform_declarations": {
+                "FILTER": { "const": "0" },
+                "FINAL_OUTPUT": { "const": "x" }
+            }}`,
+			pass: false,
+		},
+		{
+			name: "custom_func",
+			declJSON: `{ "transform_declarations": {
+                "FILTER": { "custom_func": { "name": "decimalSub", "args": [ { "const": "1" }, { "const": "1" } ] } },
+                "FINAL_OUTPUT": { "const": "x" }
+            }}`,
+			pass: false,
+		},
+		{
+			name: "template",
+			declJSON: `{ "transform_declarations": {
+                "FILTER": { "template": "is_c" },
+                "FINAL_OUTPUT": { "const": "x" },
+                "is_c": { "xpath": "C[. = 'c']" }
+            }}`,
+			pass: true,
+		},
+		{
+			name: "invalid filter",
+			declJSON: `{ "transform_declarations": {
+                "FILTER": { "template": "non_existing" },
+                "FINAL_OUTPUT": { "const": "x" }
+            }}`,
+			err: "'FILTER' contains non-existing template reference 'non_existing'",
+		},
+		{
+			name: "filter failure",
+			declJSON: `{ "transform_declarations": {
+                "FILTER": { "xpath": "B", "type": "int" },
+                "FINAL_OUTPUT": { "const": "x" }
+            }}`,
+			parseErr: `unable to convert value 'b' to type 'int' on 'FILTER', err: strconv.ParseInt: parsing "b": invalid syntax`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			finalOutputDecl, err := ValidateTransformDeclarations([]byte(test.declJSON), testParseCtx().customFuncs, nil)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Nil(t, finalOutputDecl)
+				return
+			}
+			assert.NoError(t, err)
+			n := test.node
+			if n == nil {
+				n = testNode()
+			}
+			pass, err := testParseCtx().FilterNode(n, finalOutputDecl)
+			if test.parseErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.parseErr, err.Error())
+				assert.False(t, pass)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.pass, pass)
+		})
+	}
+}
@@ -1,15 +1,19 @@
 package transform
 
 import (
-	"fmt"
 	"reflect"
 
+	"github.com/jf-tech/omniparser/decimal"
 	"github.com/jf-tech/omniparser/idr"
 )
 
 func (p *parseCtx) invokeCustomFunc(n *idr.Node, customFuncDecl *CustomFuncDecl) (interface{}, error) {
-	// In validation, we've validated the custom func exists.
-	fn, _ := p.customFuncs[customFuncDecl.Name]
+	// In validation, we've validated the custom func exists, and resolved it, in case it is a schema
+	// specific one, such as `lookup`.
+	fn := customFuncDecl.fn
+	if fn == nil {
+		fn = p.customFuncs[customFuncDecl.Name]
+	}
 	fnType := reflect.TypeOf(fn)
 	argValues, err := p.prepArgValues(n, customFuncDecl, fnType)
 	if err != nil {
@@ -21,10 +25,12 @@ func (p *parseCtx) invokeCustomFunc(n *idr.Node, customFuncDecl *CustomFuncDecl)
 	if result[1].Interface() == nil {
 		return result[0].Interface(), nil
 	}
+	err = result[1].Interface().(error)
 	if customFuncDecl.IgnoreError {
+		p.traceIgnoredError(err)
 		return nil, nil
 	}
-	return nil, fmt.Errorf("'%s' failed: %s", customFuncDecl.fqdn, result[1].Interface().(error).Error())
+	return nil, declErr(customFuncDecl.fqdn, err, "'%s' failed: %s", customFuncDecl.fqdn, err.Error())
 }
 
 func (p *parseCtx) prepArgValues(
@@ -48,20 +54,30 @@ func (p *parseCtx) prepArgValues(
 		if val == nil {
 			argVals = append(argVals, reflect.Zero(getFuncArgType(fnType, fnArgIndex)))
 		} else {
-			argVals = append(argVals, reflect.ValueOf(val))
+			argVals = append(argVals, argValue(val, getFuncArgType(fnType, fnArgIndex)))
 		}
 		fnArgIndex++
 	}
 	return argVals, nil
 }
 
+// argValue returns the reflect.Value of a custom_func argument. A decimal argument is passed in as its
+// string representation if the custom_func expects a string, given most custom_funcs are string based. An
+// object argument is passed in as a plain map.
+func argValue(val interface{}, argType reflect.Type) reflect.Value {
+	if d, ok := val.(decimal.Decimal); ok && argType.Kind() == reflect.String {
+		return reflect.ValueOf(d.String())
+	}
+	return reflect.ValueOf(plainValue(val))
+}
+
 func getFuncArgType(fnType reflect.Type, argIndex int) reflect.Type {
-	isVariadic := fnType.IsVariadic()
 	if argIndex >= fnType.NumIn() {
 		argIndex = fnType.NumIn() - 1
 	}
 	typ := fnType.In(argIndex)
-	if isVariadic {
+	// only the last param of a variadic func is a slice whose elem type is the actual arg type.
+	if fnType.IsVariadic() && argIndex == fnType.NumIn()-1 {
 		typ = typ.Elem()
 	}
 	return typ
@@ -1,11 +1,13 @@
 package transform
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/jf-tech/go-corelib/strs"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/jf-tech/omniparser/errs"
 	"github.com/jf-tech/omniparser/idr"
 )
 
@@ -98,12 +100,74 @@ func TestInvokeCustomFunc(t *testing.T) {
 			err:      ``,
 			expected: "a//b",
 		},
+		{
+			name: "decimal args",
+			n:    testNode(),
+			decl: &CustomFuncDecl{
+				Name: "concat",
+				Args: []*Decl{
+					{
+						Const:      strs.StrPtr("1.50"),
+						ResultType: testResultType(resultTypeDecimal),
+						kind:       kindConst,
+						fqdn:       "test-arg1-fqdn",
+					},
+					{
+						CustomFunc: &CustomFuncDecl{
+							Name: "decimalAdd",
+							Args: []*Decl{
+								{
+									Const:      strs.StrPtr("0.1"),
+									ResultType: testResultType(resultTypeDecimal),
+									kind:       kindConst,
+								},
+								{
+									Const: strs.StrPtr("0.20"),
+									kind:  kindConst,
+								},
+							},
+							fqdn: "test-arg2-decimal-add-fqdn",
+						},
+						kind: kindCustomFunc,
+						fqdn: "test-arg2-fqdn",
+					},
+				},
+				fqdn: "test-fqdn",
+			},
+			err:      ``,
+			expected: "1.500.30",
+		},
+		{
+			name: "object args",
+			n:    testNode(),
+			decl: &CustomFuncDecl{
+				Name: "javascript",
+				Args: []*Decl{
+					// an object argument is passed in as a plain map, which javascript sees as an object.
+					{Const: strs.StrPtr("obj.c + obj.b"), kind: kindConst},
+					{Const: strs.StrPtr("obj"), kind: kindConst},
+					{
+						kind: kindObject,
+						children: []*Decl{
+							{XPath: strs.StrPtr("B"), kind: kindField, fqdn: "b"},
+							{XPath: strs.StrPtr("C"), kind: kindField, fqdn: "c"},
+						},
+					},
+				},
+				fqdn: "test-fqdn",
+			},
+			err:      ``,
+			expected: "cb",
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			r, err := testParseCtx().invokeCustomFunc(test.n, test.decl)
 			if test.err != "" {
 				assert.Error(t, err)
 				assert.Equal(t, test.err, err.Error())
+				var declErr *errs.TransformError
+				assert.True(t, errors.As(err, &declErr))
+				assert.Contains(t, test.err, "'"+declErr.DeclFQDN+"'")
 				assert.Nil(t, r)
 			} else {
 				assert.NoError(t, err)
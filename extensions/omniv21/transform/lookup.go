@@ -0,0 +1,167 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/jf-tech/omniparser/customfuncs"
+	"github.com/jf-tech/omniparser/transformctx"
+)
+
+const (
+	lookupCustomFuncName = "lookup"
+
+	lookupOnMissEmpty = "empty"
+	lookupOnMissKey   = "key"
+	lookupOnMissError = "error"
+)
+
+// LookupTableDecl is the decl for a lookup table in the schema's `lookup_tables` section.
+type LookupTableDecl struct {
+	// Map is the inline key/value entries of the lookup table.
+	Map map[string]string `json:"map,omitempty"`
+	// File is the reference to a ".csv" (records of key and value) or ".json" (an object of keys and
+	// values) file containing the entries of the lookup table, loaded through the import resolver.
+	File *string `json:"file,omitempty"`
+	// Default is the value returned when a key is not found in the lookup table.
+	Default *string `json:"default,omitempty"`
+	// OnMiss decides what is returned when a key is not found in the lookup table and there is no
+	// default value: "empty" (default), "key" (the key itself), or "error".
+	OnMiss *string `json:"on_miss,omitempty"`
+}
+
+type lookupTable struct {
+	entries map[string]string
+	decl    *LookupTableDecl
+}
+
+type lookupTables map[string]*lookupTable
+
+// loadLookupTables loads all the lookup tables declared in the schema (and its imports) and, if there
+// are any, makes the schema specific `lookup` custom func available.
+func (ctx *validateCtx) loadLookupTables() error {
+	if len(ctx.LookupTables) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(ctx.LookupTables))
+	for name := range ctx.LookupTables {
+		names = append(names, name)
+	}
+	// load the tables in a stable order so that errors are deterministic.
+	sort.Strings(names)
+	tables := lookupTables{}
+	for _, name := range names {
+		table, err := ctx.loadLookupTable(name, ctx.LookupTables[name])
+		if err != nil {
+			return err
+		}
+		tables[name] = table
+	}
+	ctx.lookupTables = tables
+	ctx.customFuncs = customfuncs.Merge(
+		ctx.customFuncs, customfuncs.CustomFuncs{lookupCustomFuncName: tables.lookup})
+	return nil
+}
+
+func (ctx *validateCtx) loadLookupTable(name string, decl *LookupTableDecl) (*lookupTable, error) {
+	if decl.Default != nil && decl.OnMiss != nil {
+		return nil, fmt.Errorf("lookup table '%s' cannot set both 'default' and 'on_miss'", name)
+	}
+	if decl.File == nil {
+		return &lookupTable{entries: decl.Map, decl: decl}, nil
+	}
+	loadErr := func(err error) error {
+		return fmt.Errorf("unable to load lookup table '%s' from file '%s': %s", name, *decl.File, err.Error())
+	}
+	if ctx.importResolver == nil {
+		return nil, loadErr(fmt.Errorf("no import resolver provided"))
+	}
+	content, err := ctx.importResolver.Resolve(*decl.File)
+	if err != nil {
+		return nil, loadErr(err)
+	}
+	var entries map[string]string
+	switch ext := strings.ToLower(path.Ext(*decl.File)); ext {
+	case ".csv":
+		entries, err = loadLookupTableCSV(content)
+	case ".json":
+		err = json.Unmarshal(content, &entries)
+	default:
+		err = fmt.Errorf("unsupported file format '%s'", ext)
+	}
+	if err != nil {
+		return nil, loadErr(err)
+	}
+	return &lookupTable{entries: entries, decl: decl}, nil
+}
+
+func loadLookupTableCSV(content []byte) (map[string]string, error) {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.FieldsPerRecord = 2
+	entries := map[string]string{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, found := entries[record[0]]; found {
+			return nil, fmt.Errorf("duplicate key '%s'", record[0])
+		}
+		entries[record[0]] = record[1]
+	}
+}
+
+// lookup is the schema specific `lookup` custom func. It translates a key using a lookup table. If the
+// key is not found, then the optional defaultValue is returned, if specified; otherwise, the lookup
+// table's 'default' or 'on_miss' decides what's returned.
+func (t lookupTables) lookup(_ *transformctx.Ctx, table, key string, defaultValue ...string) (string, error) {
+	if len(defaultValue) > 1 {
+		return "", fmt.Errorf("cannot specify default value argument more than once")
+	}
+	lt, found := t[table]
+	if !found {
+		return "", fmt.Errorf("lookup table '%s' does not exist", table)
+	}
+	if v, found := lt.entries[key]; found {
+		return v, nil
+	}
+	if len(defaultValue) > 0 {
+		return defaultValue[0], nil
+	}
+	if lt.decl.Default != nil {
+		return *lt.decl.Default, nil
+	}
+	switch strs.StrPtrOrElse(lt.decl.OnMiss, lookupOnMissEmpty) {
+	case lookupOnMissKey:
+		return key, nil
+	case lookupOnMissError:
+		return "", fmt.Errorf("key '%s' not found in lookup table '%s'", key, table)
+	default:
+		return "", nil
+	}
+}
+
+// validateLookupArgs validates the args of a call to the schema specific `lookup` custom func.
+func (ctx *validateCtx) validateLookupArgs(fqdn string, decl *CustomFuncDecl) error {
+	if len(decl.Args) < 2 || len(decl.Args) > 3 {
+		return fmt.Errorf("'%s' must have 2 or 3 args (table, key, and optional default value), instead got %d",
+			fqdn, len(decl.Args))
+	}
+	if table := decl.Args[0].Const; table != nil {
+		if _, found := ctx.lookupTables[*table]; !found {
+			return fmt.Errorf("'%s' refers to non-existing lookup table '%s'", fqdn, *table)
+		}
+	}
+	return nil
+}
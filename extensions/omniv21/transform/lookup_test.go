@@ -0,0 +1,233 @@
+package transform
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jf-tech/omniparser/schemahandler"
+)
+
+func TestLookupTables(t *testing.T) {
+	files := map[string]string{
+		"carriers.csv":     "b,Bee Line\nc,\"Sea, Inc.\"\n",
+		"carriers.json":    `{ "b": "Bee Line (json)" }`,
+		"dup.csv":          "b,1\nb,2\n",
+		"bad.csv":          "b,1,extra\n",
+		"bad.json":         `[ "b" ]`,
+		"carriers.txt":     "b=Bee Line",
+		"lib_tables":       `{ "lookup_tables": { "lib": { "map": { "b": "lib.b" } }, "local": { "map": { "b": "lib.local" } } }, "transform_declarations": {} }`,
+		"lib_tables_again": `{ "lookup_tables": { "lib": { "map": { "b": "lib_again.b" } } }, "transform_declarations": {} }`,
+		"lib_tables_near":  `{ "imports": [ "lib_tables", "lib_tables_again" ], "lookup_tables": { "lib": { "map": { "b": "lib_near.b" } } }, "transform_declarations": {} }`,
+	}
+	resolver := schemahandler.ImportResolverFunc(func(ref string) ([]byte, error) {
+		if content, found := files[ref]; found {
+			return []byte(content), nil
+		}
+		return nil, errors.New("not found")
+	})
+	for _, test := range []struct {
+		name     string
+		declJSON string
+		resolver schemahandler.ImportResolver
+		err      string
+		expected interface{}
+	}{
+		{
+			name: "success",
+			declJSON: `{
+                "imports": [ "lib_tables" ],
+                "lookup_tables": {
+                    "inline": { "map": { "b": "inline.b" } },
+                    "inline_default": { "map": {}, "default": "n/a" },
+                    "inline_key": { "map": {}, "on_miss": "key" },
+                    "csv": { "file": "carriers.csv" },
+                    "json": { "file": "carriers.json" },
+                    "local": { "map": { "b": "local.b" } }
+                },
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "inline": { "custom_func": { "name": "lookup", "args": [ { "const": "inline" }, { "xpath": "B" } ] } },
+                        "inline_miss": { "custom_func": { "name": "lookup", "args": [ { "const": "inline" }, { "xpath": "C" } ] }, "keep_empty_or_null": true },
+                        "inline_miss_arg_default": { "custom_func": { "name": "lookup", "args": [ { "const": "inline" }, { "xpath": "C" }, { "const": "arg default" } ] } },
+                        "inline_default": { "custom_func": { "name": "lookup", "args": [ { "const": "inline_default" }, { "xpath": "C" } ] } },
+                        "inline_key": { "custom_func": { "name": "lookup", "args": [ { "const": "inline_key" }, { "xpath": "C" } ] } },
+                        "csv_b": { "custom_func": { "name": "lookup", "args": [ { "const": "csv" }, { "xpath": "B" } ] } },
+                        "csv_c": { "custom_func": { "name": "lookup", "args": [ { "const": "csv" }, { "xpath": "C" } ] } },
+                        "json": { "custom_func": { "name": "lookup", "args": [ { "const": "json" }, { "xpath": "B" } ] } },
+                        "imported": { "custom_func": { "name": "lookup", "args": [ { "const": "lib" }, { "xpath": "B" } ] } },
+                        "local_over_imported": { "custom_func": { "name": "lookup", "args": [ { "const": "local" }, { "xpath": "B" } ] } }
+                    }}
+                }
+            }`,
+			resolver: resolver,
+			err:      "",
+			expected: testOrderedObject(
+				"inline", "inline.b",
+				"inline_miss", "",
+				"inline_miss_arg_default", "arg default",
+				"inline_default", "n/a",
+				"inline_key", "c",
+				"csv_b", "Bee Line",
+				"csv_c", "Sea, Inc.",
+				"json", "Bee Line (json)",
+				"imported", "lib.b",
+				"local_over_imported", "local.b",
+			),
+		},
+		{
+			name: "both default and on_miss",
+			declJSON: `{
+                "lookup_tables": { "t": { "map": {}, "default": "x", "on_miss": "key" } },
+                "transform_declarations": { "FINAL_OUTPUT": { "const": "x" } }
+            }`,
+			resolver: resolver,
+			err:      "lookup table 't' cannot set both 'default' and 'on_miss'",
+		},
+		{
+			name: "file without resolver",
+			declJSON: `{
+                "lookup_tables": { "t": { "file": "carriers.csv" } },
+                "transform_declarations": { "FINAL_OUTPUT": { "const": "x" } }
+            }`,
+			resolver: nil,
+			err:      "unable to load lookup table 't' from file 'carriers.csv': no import resolver provided",
+		},
+		{
+			name: "file not found",
+			declJSON: `{
+                "lookup_tables": { "t": { "file": "missing.csv" } },
+                "transform_declarations": { "FINAL_OUTPUT": { "const": "x" } }
+            }`,
+			resolver: resolver,
+			err:      "unable to load lookup table 't' from file 'missing.csv': not found",
+		},
+		{
+			name: "csv file with duplicate keys",
+			declJSON: `{
+                "lookup_tables": { "t": { "file": "dup.csv" } },
+                "transform_declarations": { "FINAL_OUTPUT": { "const": "x" } }
+            }`,
+			resolver: resolver,
+			err:      "unable to load lookup table 't' from file 'dup.csv': duplicate key 'b'",
+		},
+		{
+			name: "csv file with wrong number of fields",
+			declJSON: `{
+                "lookup_tables": { "t": { "file": "bad.csv" } },
+                "transform_declarations": { "FINAL_OUTPUT": { "const": "x" } }
+            }`,
+			resolver: resolver,
+			err:      "unable to load lookup table 't' from file 'bad.csv': record on line 1: wrong number of fields",
+		},
+		{
+			name: "invalid json file",
+			declJSON: `{
+                "lookup_tables": { "t": { "file": "bad.json" } },
+                "transform_declarations": { "FINAL_OUTPUT": { "const": "x" } }
+            }`,
+			resolver: resolver,
+			err:      "unable to load lookup table 't' from file 'bad.json': json: cannot unmarshal array into Go value of type map[string]string",
+		},
+		{
+			name: "unsupported file format",
+			declJSON: `{
+                "lookup_tables": { "t": { "file": "carriers.txt" } },
+                "transform_declarations": { "FINAL_OUTPUT": { "const": "x" } }
+            }`,
+			resolver: resolver,
+			err:      "unable to load lookup table 't' from file 'carriers.txt': unsupported file format '.txt'",
+		},
+		{
+			name: "same lookup table imported from different documents",
+			declJSON: `{
+                "imports": [ "lib_tables", "lib_tables_again" ],
+                "transform_declarations": { "FINAL_OUTPUT": { "const": "x" } }
+            }`,
+			resolver: resolver,
+			err:      "lookup table 'lib' is imported from both 'lib_tables' and 'lib_tables_again'",
+		},
+		{
+			name: "nearer imported lookup table takes precedence",
+			declJSON: `{
+                "imports": [ "lib_tables_near" ],
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "custom_func": { "name": "lookup", "args": [ { "const": "lib" }, { "xpath": "B" } ] } }
+                }
+            }`,
+			resolver: resolver,
+			err:      "",
+			expected: "lib_near.b",
+		},
+		{
+			name: "lookup without lookup tables",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "custom_func": { "name": "lookup", "args": [ { "const": "t" }, { "xpath": "B" } ] } }
+                }
+            }`,
+			resolver: resolver,
+			err:      "unknown custom_func 'lookup' on 'FINAL_OUTPUT'",
+		},
+		{
+			name: "lookup with wrong number of args",
+			declJSON: `{
+                "lookup_tables": { "t": { "map": {} } },
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "custom_func": { "name": "lookup", "args": [ { "const": "t" } ] } }
+                }
+            }`,
+			resolver: resolver,
+			err:      "'FINAL_OUTPUT.custom_func(lookup)' must have 2 or 3 args (table, key, and optional default value), instead got 1",
+		},
+		{
+			name: "lookup with non-existing table",
+			declJSON: `{
+                "lookup_tables": { "t": { "map": {} } },
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "custom_func": { "name": "lookup", "args": [ { "const": "t2" }, { "xpath": "B" } ] } }
+                }
+            }`,
+			resolver: resolver,
+			err:      "'FINAL_OUTPUT.custom_func(lookup)' refers to non-existing lookup table 't2'",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			finalOutputDecl, err := ValidateTransformDeclarationsWithImports(
+				[]byte(test.declJSON), testParseCtx().customFuncs, nil, test.resolver)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Nil(t, finalOutputDecl)
+				return
+			}
+			assert.NoError(t, err)
+			value, err := testParseCtx().ParseNode(testNode(), finalOutputDecl)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, value)
+		})
+	}
+}
+
+func TestLookupTables_Lookup(t *testing.T) {
+	tables := lookupTables{
+		"t": {entries: map[string]string{"a": "A"}, decl: &LookupTableDecl{OnMiss: strs.StrPtr("error")}},
+	}
+	v, err := tables.lookup(nil, "t", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "A", v)
+	v, err = tables.lookup(nil, "t", "b", "default")
+	assert.NoError(t, err)
+	assert.Equal(t, "default", v)
+	_, err = tables.lookup(nil, "t", "b")
+	assert.Error(t, err)
+	assert.Equal(t, "key 'b' not found in lookup table 't'", err.Error())
+	_, err = tables.lookup(nil, "t2", "a")
+	assert.Error(t, err)
+	assert.Equal(t, "lookup table 't2' does not exist", err.Error())
+	_, err = tables.lookup(nil, "t", "a", "default1", "default2")
+	assert.Error(t, err)
+	assert.Equal(t, "cannot specify default value argument more than once", err.Error())
+}
@@ -0,0 +1,79 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/jf-tech/omniparser/idr"
+)
+
+const (
+	// onErrorFail fails the record, which is the default.
+	onErrorFail = "fail"
+	// onErrorNull suppresses the error and the decl yields null.
+	onErrorNull = "null"
+	// onErrorDefault suppresses the error and the decl yields its 'default' value.
+	onErrorDefault = "default"
+	// onErrorSkip suppresses the error and the decl yields nothing, i.e. it is omitted from the object
+	// or the array it is in.
+	onErrorSkip = "skip"
+)
+
+func (ctx *validateCtx) validateOnError(fqdn string, decl *Decl) error {
+	if decl.OnError == nil {
+		return nil
+	}
+	// FINAL_OUTPUT failures are record transform failures, which are dealt with by the transform's
+	// error policy instead.
+	if fqdn == finalOutput {
+		return fmt.Errorf("'%s' cannot set 'on_error'", fqdn)
+	}
+	switch *decl.OnError {
+	case onErrorDefault:
+		if decl.Default == nil {
+			return fmt.Errorf("'%s' cannot set 'on_error' to '%s' without 'default'", fqdn, onErrorDefault)
+		}
+	case onErrorSkip:
+		if decl.KeepEmptyOrNull {
+			return fmt.Errorf(
+				"'%s' cannot set 'on_error' to '%s' along with 'keep_empty_or_null'", fqdn, onErrorSkip)
+		}
+	}
+	return nil
+}
+
+// onError deals with the error of a decl evaluation according to the decl's 'on_error': unless it is
+// "fail", the error is suppressed and reported as a warning, and the decl yields its 'default' value
+// (for "default") or null instead. Note a value of null from a decl with "skip" is always omitted, given
+// "skip" can't be used along with 'keep_empty_or_null'. Cancellation of the transform operation is never
+// suppressed.
+func (p *parseCtx) onError(n *idr.Node, decl *Decl, err error) (interface{}, error) {
+	onError := *decl.OnError
+	if onError == onErrorFail || p.transformCtx.Err() != nil {
+		return nil, err
+	}
+	p.warn(declErr(decl.fqdn, err,
+		"error on '%s' suppressed by 'on_error' '%s': %s", decl.fqdn, onError, err.Error()))
+	p.traceSuppressedError(onError, err)
+	if onError == onErrorDefault {
+		return p.parseDefault(n, decl)
+	}
+	return nil, nil
+}
+
+// warn reports a warning via the transform ctx, and keeps it so that it can be attached to the record.
+func (p *parseCtx) warn(warning error) {
+	p.warnings = append(p.warnings, warning)
+	p.transformCtx.Warn(warning)
+}
+
+// Warnings returns all the warnings reported by the parseCtx so far.
+func (p *parseCtx) Warnings() []error {
+	return p.warnings
+}
+
+func (p *parseCtx) traceSuppressedError(onError string, err error) {
+	if t := p.curTrace(); t != nil {
+		t.IgnoredError = err.Error()
+		t.Omitted = fmt.Sprintf("error suppressed by 'on_error' '%s'", onError)
+	}
+}
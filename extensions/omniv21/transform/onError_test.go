@@ -0,0 +1,153 @@
+package transform
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jf-tech/omniparser/errs"
+)
+
+func TestParseCtx_OnError(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		declJSON string
+		err      string
+		expected interface{}
+		warnings []string
+	}{
+		{
+			name:     "fail",
+			declJSON: `{ "xpath": "attr[@name='size']/@value", "type": "boolean", "on_error": "fail" }`,
+			err:      `unable to convert value '10' to type 'boolean' on 'FINAL_OUTPUT.test', err: strconv.ParseBool: parsing "10": invalid syntax`,
+		},
+		{
+			name:     "null on type conversion failure",
+			declJSON: `{ "xpath": "attr[@name='size']/@value", "type": "boolean", "on_error": "null" }`,
+			expected: testOrderedObject("id", "1"),
+			warnings: []string{
+				`error on 'FINAL_OUTPUT.test' suppressed by 'on_error' 'null': unable to convert value '10' to type 'boolean' on 'FINAL_OUTPUT.test', err: strconv.ParseBool: parsing "10": invalid syntax`,
+			},
+		},
+		{
+			name:     "null kept",
+			declJSON: `{ "xpath": "attr", "on_error": "null", "keep_empty_or_null": true }`,
+			expected: testOrderedObject("id", "1", "test", nil),
+			warnings: []string{
+				`error on 'FINAL_OUTPUT.test' suppressed by 'on_error' 'null': xpath query 'attr' on 'FINAL_OUTPUT.test' yielded more than one result`,
+			},
+		},
+		{
+			name:     "default",
+			declJSON: `{ "xpath": "attr[@name='size']/@value", "type": "int", "validate": { "max": 5 }, "on_error": "default", "default": "5" }`,
+			expected: testOrderedObject("id", "1", "test", int64(5)),
+			warnings: []string{
+				`error on 'FINAL_OUTPUT.test' suppressed by 'on_error' 'default': 'FINAL_OUTPUT.test' failed validation rule 'max': value '10' is greater than 5`,
+			},
+		},
+		{
+			name:     "default fails",
+			declJSON: `{ "xpath": "attr[@name='size']/@value", "type": "boolean", "on_error": "default", "default": "maybe" }`,
+			err:      `unable to convert value 'maybe' to type 'boolean' on 'FINAL_OUTPUT.test', err: strconv.ParseBool: parsing "maybe": invalid syntax`,
+		},
+		{
+			name:     "skip array element",
+			declJSON: `{ "array": [ { "xpath": "dims/*", "type": "int", "on_error": "skip" } ] }`,
+			expected: testOrderedObject("id", "1", "test", []interface{}{int64(3), int64(4)}),
+			warnings: []string{
+				`error on 'FINAL_OUTPUT.test.elem[1]' suppressed by 'on_error' 'skip': unable to convert value '' to type 'int' on 'FINAL_OUTPUT.test.elem[1]', err: strconv.ParseInt: parsing "": invalid syntax`,
+			},
+		},
+		{
+			name: "child failure suppressed by parent",
+			declJSON: `{ "object": {
+                "w": { "xpath": "dims/w", "type": "int" },
+                "d": { "xpath": "dims/d", "type": "int" }
+            }, "on_error": "skip" }`,
+			expected: testOrderedObject("id", "1"),
+			warnings: []string{
+				`error on 'FINAL_OUTPUT.test' suppressed by 'on_error' 'skip': unable to convert value '' to type 'int' on 'FINAL_OUTPUT.test.d', err: strconv.ParseInt: parsing "": invalid syntax`,
+			},
+		},
+		{
+			name:     "on_error at template site",
+			declJSON: `{ "template": "t", "on_error": "null" }`,
+			expected: testOrderedObject("id", "1"),
+			warnings: []string{
+				`error on 'FINAL_OUTPUT.test' suppressed by 'on_error' 'null': unable to convert value 'red' to type 'int' on 'FINAL_OUTPUT.test', err: strconv.ParseInt: parsing "red": invalid syntax`,
+			},
+		},
+		{
+			name:     "on_error in template",
+			declJSON: `{ "template": "t_on_error" }`,
+			expected: testOrderedObject("id", "1", "test", int64(0)),
+			warnings: []string{
+				`error on 'FINAL_OUTPUT.test' suppressed by 'on_error' 'default': unable to convert value 'red' to type 'int' on 'FINAL_OUTPUT.test', err: strconv.ParseInt: parsing "red": invalid syntax`,
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			finalOutputDecl, err := ValidateTransformDeclarations(
+				[]byte(`{"transform_declarations": {
+					"FINAL_OUTPUT": { "object": { "id": { "const": "1" }, "test": `+test.declJSON+` }},
+					"t": { "xpath": "attr[1]/@value", "type": "int" },
+					"t_on_error": { "xpath": "attr[1]/@value", "type": "int", "on_error": "default", "default": "0" }
+				}}`),
+				testParseCtx().customFuncs, nil)
+			assert.NoError(t, err)
+			p := testParseCtx()
+			var warnings []string
+			p.transformCtx.WarningSink = func(warning error) { warnings = append(warnings, warning.Error()) }
+			value, err := p.ParseNode(testPivotNode(t), finalOutputDecl)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Nil(t, value)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, value)
+			assert.Equal(t, test.warnings, warnings)
+			assert.Equal(t, len(test.warnings), len(p.Warnings()))
+			for _, warning := range p.Warnings() {
+				var declErr *errs.TransformError
+				assert.True(t, errors.As(warning, &declErr))
+				// the cause of the suppressed error is retained.
+				assert.True(t, errors.As(declErr.Err, &declErr))
+			}
+		})
+	}
+}
+
+func TestValidateOnError(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		declJSON string
+		err      string
+	}{
+		{
+			name:     "on FINAL_OUTPUT",
+			declJSON: `{ "xpath": "B", "on_error": "null" }`,
+			err:      "'FINAL_OUTPUT' cannot set 'on_error'",
+		},
+		{
+			name:     "default without default",
+			declJSON: `{ "object": { "a": { "xpath": "B", "on_error": "default" } } }`,
+			err:      "'FINAL_OUTPUT.a' cannot set 'on_error' to 'default' without 'default'",
+		},
+		{
+			name:     "skip with keep_empty_or_null",
+			declJSON: `{ "array": [ { "xpath": "B", "on_error": "skip", "keep_empty_or_null": true } ] }`,
+			err:      "'FINAL_OUTPUT.elem[1]' cannot set 'on_error' to 'skip' along with 'keep_empty_or_null'",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			finalOutputDecl, err := ValidateTransformDeclarations(
+				[]byte(`{"transform_declarations": { "FINAL_OUTPUT": `+test.declJSON+` }}`), nil, nil)
+			assert.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+			assert.Nil(t, finalOutputDecl)
+		})
+	}
+}
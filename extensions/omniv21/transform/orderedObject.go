@@ -0,0 +1,83 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// orderedObject is the value of an object-yielding decl (such as "object" or "pivot"). Unlike a plain
+// map, whose keys json.Marshal sorts alphabetically, it remembers the order in which its fields are
+// set, so the output record's fields follow the order they're declared in the schema.
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedObject() *orderedObject {
+	return &orderedObject{values: map[string]interface{}{}}
+}
+
+// set sets the value of a field. A new field is placed after all the existing fields; an existing
+// field keeps its place.
+func (o *orderedObject) set(key string, value interface{}) {
+	if _, found := o.values[key]; !found {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *orderedObject) get(key string) (interface{}, bool) {
+	value, found := o.values[key]
+	return value, found
+}
+
+func (o *orderedObject) len() int {
+	return len(o.keys)
+}
+
+// MarshalJSON is the custom JSON marshaler for orderedObject, which marshals the fields in their order.
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		b, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+		buf.WriteByte(':')
+		b, err = json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// plainValue returns v with all the orderedObject's in it, however deeply nested in objects and arrays,
+// turned into plain map[string]interface{}'s, for code that expects the plain form, such as custom_funcs.
+func plainValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *orderedObject:
+		m := make(map[string]interface{}, len(v.values))
+		for key, value := range v.values {
+			m[key] = plainValue(value)
+		}
+		return m
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		a := make([]interface{}, len(v))
+		for i, elem := range v {
+			a[i] = plainValue(elem)
+		}
+		return a
+	}
+	return v
+}
@@ -0,0 +1,57 @@
+package transform
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testOrderedObject builds an orderedObject out of alternating keys and values.
+func testOrderedObject(keyValues ...interface{}) *orderedObject {
+	obj := newOrderedObject()
+	for i := 0; i < len(keyValues); i += 2 {
+		obj.set(keyValues[i].(string), keyValues[i+1])
+	}
+	return obj
+}
+
+func TestOrderedObject(t *testing.T) {
+	obj := testOrderedObject("z", "1", "a", int64(2), "m", nil)
+	assert.Equal(t, 3, obj.len())
+	obj.set("a", testOrderedObject("y", []interface{}{testOrderedObject("c", "3", "b", "4")}, "x", "<&>"))
+	obj.set("b", []interface{}{})
+	assert.Equal(t, 4, obj.len())
+	v, found := obj.get("z")
+	assert.True(t, found)
+	assert.Equal(t, "1", v)
+	v, found = obj.get("non-existing")
+	assert.False(t, found)
+	assert.Nil(t, v)
+
+	b, err := json.Marshal(obj)
+	assert.NoError(t, err)
+	// Note keys and values are HTML-escaped the same way as json.Marshal does to a map.
+	assert.Equal(t,
+		`{"z":"1","a":{"y":[{"c":"3","b":"4"}],"x":"\u003c\u0026\u003e"},"m":null,"b":[]}`, string(b))
+	b, err = json.Marshal(newOrderedObject())
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, string(b))
+	_, err = json.Marshal(testOrderedObject("f", func() {}))
+	assert.Error(t, err)
+}
+
+func TestPlainValue(t *testing.T) {
+	assert.Equal(t,
+		map[string]interface{}{
+			"a": []interface{}{map[string]interface{}{"c": "3"}, "4"},
+			"b": []interface{}(nil),
+			"e": map[string]interface{}{},
+		},
+		plainValue(testOrderedObject(
+			"a", []interface{}{testOrderedObject("c", "3"), "4"},
+			"b", []interface{}(nil),
+			"e", newOrderedObject())))
+	assert.Equal(t, "x", plainValue("x"))
+	assert.Nil(t, plainValue(nil))
+}
@@ -0,0 +1,127 @@
+package transform
+
+import "encoding/json"
+
+const (
+	jsonSchemaDraft07 = "http://json-schema.org/draft-07/schema#"
+)
+
+var resultTypeToJSONSchemaType = map[resultType]string{
+	resultTypeInt:     "integer",
+	resultTypeFloat:   "number",
+	resultTypeBoolean: "boolean",
+	resultTypeString:  "string",
+	resultTypeDecimal: "number",
+}
+
+// resultJSONSchemaType returns the JSON Schema "type" value of the decl's 'type' cast.
+func (d *Decl) resultJSONSchemaType() string {
+	if *d.ResultType == resultTypeDateTime {
+		if d.DateTime != nil && d.DateTime.Format != nil {
+			switch *d.DateTime.Format {
+			case dateTimeFormatEpoch, dateTimeFormatEpochMillis:
+				return "integer"
+			}
+		}
+		return "string"
+	}
+	return resultTypeToJSONSchemaType[*d.ResultType]
+}
+
+// OutputJSONSchema returns a draft-07 JSON Schema describing the records produced by a validated
+// FINAL_OUTPUT Decl.
+func (d *Decl) OutputJSONSchema(title string) ([]byte, error) {
+	s := d.outputJSONSchema()
+	s["$schema"] = jsonSchemaDraft07
+	if title != "" {
+		s["title"] = title
+	}
+	return json.Marshal(s)
+}
+
+func (d *Decl) outputJSONSchema() map[string]interface{} {
+	s := map[string]interface{}{}
+	switch d.kind {
+	case kindObject:
+		s["type"] = d.nullable("object")
+		properties := map[string]interface{}{}
+		for name, childDecl := range d.Object {
+			properties[name] = childDecl.outputJSONSchema()
+		}
+		s["properties"] = properties
+		// Note no property is "required", because empty or null values are omitted from the output
+		// unless 'keep_empty_or_null' is set, and even if it is set, values are also omitted when
+		// a transform yields nothing (e.g. xpath matches nothing).
+		s["additionalProperties"] = false
+	case kindArray:
+		s["type"] = d.nullable("array")
+		var items []interface{}
+		for _, elemDecl := range d.Array {
+			items = append(items, elemDecl.outputJSONSchema())
+		}
+		switch len(items) {
+		case 0:
+		case 1:
+			s["items"] = items[0]
+		default:
+			s["items"] = map[string]interface{}{"anyOf": items}
+		}
+		if d.MaxItems != nil {
+			s["maxItems"] = *d.MaxItems
+		}
+		if len(d.DistinctBy) == 1 && d.DistinctBy[0] == elemItself {
+			s["uniqueItems"] = true
+		}
+	case kindPivot:
+		s["type"] = d.nullable("object")
+		// field names are data-driven, thus unknown until transform time.
+		s["additionalProperties"] = d.Pivot.Value.outputJSONSchema()
+	case kindUnpivot:
+		s["type"] = d.nullable("array")
+		s["items"] = map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				d.Unpivot.keyName():   map[string]interface{}{"type": "string"},
+				d.Unpivot.valueName(): d.Unpivot.Value.outputJSONSchema(),
+			},
+			"required":             []string{d.Unpivot.keyName(), d.Unpivot.valueName()},
+			"additionalProperties": false,
+		}
+	case kindSwitch:
+		if d.ResultType != nil {
+			s["type"] = d.nullable(d.resultJSONSchemaType())
+			break
+		}
+		var cases []interface{}
+		for _, caseDecl := range d.Switch {
+			cases = append(cases, caseDecl.Then.outputJSONSchema())
+		}
+		if d.KeepEmptyOrNull {
+			cases = append(cases, map[string]interface{}{"type": "null"})
+		}
+		s["anyOf"] = cases
+	case kindCustomFunc, kindCustomParse, kindVar:
+		// Without a 'type' cast, a custom function (or a variable updated by one) can return a value of
+		// any type.
+		if d.ResultType != nil {
+			s["type"] = d.nullable(d.resultJSONSchemaType())
+		}
+	default:
+		// const, external and field are all strings unless a 'type' cast is specified.
+		t := "string"
+		if d.ResultType != nil {
+			t = d.resultJSONSchemaType()
+		}
+		s["type"] = d.nullable(t)
+	}
+	return s
+}
+
+// nullable returns the JSON Schema "type" value for the decl: null is allowed only if 'keep_empty_or_null'
+// is set, otherwise null values are never present in the output.
+func (d *Decl) nullable(jsonSchemaType string) interface{} {
+	if d.KeepEmptyOrNull {
+		return []string{jsonSchemaType, "null"}
+	}
+	return jsonSchemaType
+}
@@ -0,0 +1,49 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy"
+	"github.com/jf-tech/go-corelib/jsons"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jf-tech/omniparser/customfuncs"
+	"github.com/jf-tech/omniparser/transformctx"
+)
+
+func TestDecl_OutputJSONSchema(t *testing.T) {
+	finalOutputDecl, err := ValidateTransformDeclarations([]byte(`{
+        "transform_declarations": {
+            "FINAL_OUTPUT": { "object": {
+                "const_str": { "const": "abc" },
+                "const_int": { "const": "123", "type": "int" },
+                "external": { "external": "ext", "keep_empty_or_null": true },
+                "field_float": { "xpath": "price", "type": "float" },
+                "field_bool": { "xpath": "flag", "type": "boolean", "keep_empty_or_null": true },
+                "field_decimal": { "xpath": "amount", "type": "decimal" },
+                "field_datetime": { "xpath": "date", "type": "datetime" },
+                "field_epoch": { "xpath": "date", "type": "datetime", "datetime": { "format": "epoch" } },
+                "func_any": { "custom_func": { "name": "test_func", "args": [ { "const": "x" } ] } },
+                "func_str": { "custom_func": { "name": "test_func" }, "type": "string" },
+                "empty_array": { "array": [] },
+                "single_type_array": { "array": [ { "xpath": "tag" } ], "distinct_by": [ "." ], "max_items": 3 },
+                "multi_type_array": { "array": [ { "xpath": "tag" }, { "template": "obj" } ] },
+                "obj": { "template": "obj" },
+                "switch": { "switch": [
+                    { "when": { "xpath": "flag" }, "then": { "template": "obj" } },
+                    { "then": { "const": "1", "type": "int" } }
+                ]},
+                "switch_int": { "switch": [ { "then": { "xpath": "n" } } ], "type": "int", "keep_empty_or_null": true }
+            }},
+            "obj": { "xpath": "obj", "object": {
+                "id": { "xpath": "id", "type": "int" }
+            }, "keep_empty_or_null": true }
+        }
+    }`), customfuncs.CustomFuncs{
+		"test_func": func(*transformctx.Ctx, ...string) (string, error) { return "", nil },
+	}, nil)
+	assert.NoError(t, err)
+	b, err := finalOutputDecl.OutputJSONSchema("test-schema")
+	assert.NoError(t, err)
+	cupaloy.SnapshotT(t, jsons.BPJ(string(b)))
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/jf-tech/go-corelib/strs"
 
 	"github.com/jf-tech/omniparser/customfuncs"
+	"github.com/jf-tech/omniparser/errs"
 	"github.com/jf-tech/omniparser/idr"
+	"github.com/jf-tech/omniparser/schemahandler"
 	"github.com/jf-tech/omniparser/transformctx"
 )
 
@@ -18,6 +20,10 @@ type parseCtx struct {
 	customParseFuncs      CustomParseFuncs // Deprecated.
 	disableTransformCache bool             // by default, we have caching on. only in some tests we turn caching off.
 	transformCache        map[string]interface{}
+	explain               bool // explain mode, see explain.go for details.
+	traces                []*schemahandler.DeclTrace
+	traceStack            []*schemahandler.DeclTrace
+	warnings              []error
 }
 
 // NewParseCtx creates new context for parsing and transforming a *Node (and its sub-tree) into an output record.
@@ -31,55 +37,107 @@ func NewParseCtx(
 		customParseFuncs:      customParseFuncs,
 		disableTransformCache: false,
 		transformCache:        map[string]interface{}{},
+		explain:               transformCtx != nil && transformCtx.Explain,
 	}
 }
 
+// declErr creates an error occurring on the decl identified by fqdn. The error is an *errs.TransformError
+// with DeclFQDN set, so the offending decl can be retrieved from the error (or the errors wrapping it)
+// using errors.As.
+func declErr(fqdn string, cause error, format string, args ...interface{}) error {
+	return &errs.TransformError{DeclFQDN: fqdn, Err: cause, Msg: fmt.Sprintf(format, args...)}
+}
+
 func (p *parseCtx) ParseNode(n *idr.Node, decl *Decl) (interface{}, error) {
+	if err := p.transformCtx.Err(); err != nil {
+		return nil, err
+	}
 	var cacheKey string
 	if !p.disableTransformCache {
 		cacheKey = strconv.FormatInt(n.ID, 16) + "/" + decl.hash
 		if cacheValue, found := p.transformCache[cacheKey]; found {
+			p.traceCacheHit(decl, cacheValue)
 			return cacheValue, nil
 		}
 	}
-	saveIntoCache := func(value interface{}, err error) (interface{}, error) {
-		if !p.disableTransformCache {
+	p.beginTrace(decl)
+	// finalize applies the decl's 'default', if the value resolves to nothing, checks the value against the
+	// decl's 'validate' rules, deals with the error, if any, according to the decl's 'on_error', saves the
+	// value into cache, and records the result in explain mode.
+	finalize := func(value interface{}, err error) (interface{}, error) {
+		if err == nil && decl.Default != nil && (value == nil || isEmpty(value)) {
+			value, err = p.parseDefault(n, decl)
+		}
+		if err == nil && decl.Validate != nil {
+			err = p.checkValidate(decl, value)
 			if err != nil {
-				return value, err
+				value = nil
 			}
+		}
+		if err != nil && decl.OnError != nil {
+			value, err = p.onError(n, decl, err)
+		}
+		p.endTrace(decl, value, err)
+		if !p.disableTransformCache && err == nil {
 			p.transformCache[cacheKey] = value
 		}
 		return value, err
 	}
 	switch decl.kind {
 	case kindConst:
-		return saveIntoCache(p.parseConst(decl))
+		return finalize(p.parseConst(decl))
 	case kindExternal:
-		return saveIntoCache(p.parseExternal(decl))
+		return finalize(p.parseExternal(decl))
+	case kindVar:
+		return finalize(p.parseVar(decl))
 	case kindField:
-		return saveIntoCache(p.parseField(n, decl))
+		return finalize(p.parseField(n, decl))
 	case kindObject:
-		return saveIntoCache(p.parseObject(n, decl))
+		return finalize(p.parseObject(n, decl))
 	case kindArray:
-		return saveIntoCache(p.parseArray(n, decl))
+		return finalize(p.parseArray(n, decl))
 	case kindCustomFunc:
-		return saveIntoCache(p.parseCustomFunc(n, decl))
+		return finalize(p.parseCustomFunc(n, decl))
 	case kindCustomParse:
-		return saveIntoCache(p.parseCustomParse(n, decl))
+		return finalize(p.parseCustomParse(n, decl))
+	case kindSwitch:
+		return finalize(p.parseSwitch(n, decl))
+	case kindPivot:
+		return finalize(p.parsePivot(n, decl))
+	case kindUnpivot:
+		return finalize(p.parseUnpivot(n, decl))
 	default:
-		return nil, fmt.Errorf("unexpected decl kind '%s' on '%s'", decl.kind, decl.fqdn)
+		return finalize(nil, declErr(decl.fqdn, nil, "unexpected decl kind '%s' on '%s'", decl.kind, decl.fqdn))
 	}
 }
 
+// parseDefault computes the value of the decl's 'default', which is then converted according to the decl
+// itself, e.g. its 'type'.
+func (p *parseCtx) parseDefault(n *idr.Node, decl *Decl) (interface{}, error) {
+	v, err := p.ParseNode(n, decl.Default)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeAndReturnValue(decl, v)
+}
+
 func (p *parseCtx) parseConst(decl *Decl) (interface{}, error) {
+	p.traceRawValue(*decl.Const)
 	return normalizeAndReturnValue(decl, *decl.Const)
 }
 
 func (p *parseCtx) parseExternal(decl *Decl) (interface{}, error) {
 	if v, found := p.transformCtx.External(*decl.External); found {
+		p.traceRawValue(v)
 		return normalizeAndReturnValue(decl, v)
 	}
-	return nil, fmt.Errorf("cannot find external property '%s' on '%s'", *decl.External, decl.fqdn)
+	return nil, declErr(decl.fqdn, nil, "cannot find external property '%s' on '%s'", *decl.External, decl.fqdn)
+}
+
+func (p *parseCtx) parseVar(decl *Decl) (interface{}, error) {
+	v, _ := p.transformCtx.Vars.Get(*decl.Var)
+	p.traceRawValue(v)
+	return normalizeAndReturnValue(decl, v)
 }
 
 func xpathQueryNeeded(decl *Decl) bool {
@@ -114,14 +172,15 @@ func (p *parseCtx) computeXPathDynamic(n *idr.Node, xpathDynamicDecl *Decl) (str
 		return "", err
 	}
 	if v == nil {
-		return "", fmt.Errorf("xpath_dynamic on '%s' yields empty value", xpathDynamicDecl.fqdn)
+		return "", declErr(xpathDynamicDecl.fqdn, nil, "xpath_dynamic on '%s' yields empty value", xpathDynamicDecl.fqdn)
 	}
 	if reflect.ValueOf(v).Kind() != reflect.String {
-		return "", fmt.Errorf("xpath_dynamic on '%s' yields a non-string value '%v'", xpathDynamicDecl.fqdn, v)
+		return "", declErr(xpathDynamicDecl.fqdn, nil,
+			"xpath_dynamic on '%s' yields a non-string value '%v'", xpathDynamicDecl.fqdn, v)
 	}
 	xpathDynamic := v.(string)
 	if !strs.IsStrNonBlank(xpathDynamic) {
-		return "", fmt.Errorf("xpath_dynamic on '%s' yields empty value", xpathDynamicDecl.fqdn)
+		return "", declErr(xpathDynamicDecl.fqdn, nil, "xpath_dynamic on '%s' yields empty value", xpathDynamicDecl.fqdn)
 	}
 	return xpathDynamic, nil
 }
@@ -139,17 +198,20 @@ func (p *parseCtx) querySingleNodeFromXPath(n *idr.Node, decl *Decl) (*idr.Node,
 	}
 	xpath, dynamic, err := p.computeXPath(n, decl)
 	if err != nil {
+		p.traceOmitted("xpath_dynamic failed: %s", err.Error())
 		return nil, nil
 	}
 	resultNode, err := idr.MatchSingle(n, xpath, xpathMatchFlags(dynamic))
 	switch {
 	case err == idr.ErrNoMatch:
+		p.traceXPath(xpath, dynamic)
 		return nil, nil
 	case err == idr.ErrMoreThanExpected:
-		return nil, fmt.Errorf("xpath query '%s' on '%s' yielded more than one result", xpath, decl.fqdn)
+		return nil, declErr(decl.fqdn, err, "xpath query '%s' on '%s' yielded more than one result", xpath, decl.fqdn)
 	case err != nil:
-		return nil, fmt.Errorf("xpath query '%s' on '%s' failed: %s", xpath, decl.fqdn, err.Error())
+		return nil, declErr(decl.fqdn, err, "xpath query '%s' on '%s' failed: %s", xpath, decl.fqdn, err.Error())
 	}
+	p.traceXPath(xpath, dynamic, resultNode)
 	return resultNode, nil
 }
 
@@ -161,6 +223,7 @@ func (p *parseCtx) parseField(n *idr.Node, decl *Decl) (interface{}, error) {
 	if n == nil {
 		return nil, nil
 	}
+	p.traceRawValue(n.InnerText())
 	return normalizeAndReturnValue(decl, n.InnerText())
 }
 
@@ -176,6 +239,7 @@ func (p *parseCtx) parseCustomFunc(n *idr.Node, decl *Decl) (interface{}, error)
 	if err != nil {
 		return nil, err
 	}
+	p.traceRawValue(funcResult)
 	return normalizeAndReturnValue(decl, funcResult)
 }
 
@@ -189,8 +253,9 @@ func (p *parseCtx) parseCustomParse(n *idr.Node, decl *Decl) (interface{}, error
 	}
 	v, err := p.invokeCustomParse(p.customParseFuncs[*decl.CustomParse], n)
 	if err != nil {
-		return nil, err
+		return nil, declErr(decl.fqdn, err, "%s", err.Error())
 	}
+	p.traceRawValue(v)
 	return normalizeAndReturnValue(decl, v)
 }
 
@@ -202,16 +267,15 @@ func (p *parseCtx) parseObject(n *idr.Node, decl *Decl) (interface{}, error) {
 	if n == nil {
 		return nil, nil
 	}
-	obj := map[string]interface{}{}
+	obj := newOrderedObject()
 	for _, childDecl := range decl.children {
 		childValue, err := p.ParseNode(n, childDecl)
 		if err != nil {
 			return nil, err
 		}
-		// value returned by p.ParseNode is already normalized, thus this
-		// normalizeAndSaveValue won't fail.
-		_ = normalizeAndSaveValue(childDecl, childValue, func(normalizedValue interface{}) {
-			obj[strs.LastNameletOfFQDNWithEsc(childDecl.fqdn)] = normalizedValue
+		// value returned by p.ParseNode is already normalized, only need to decide whether to save it.
+		saveNormalizedValue(childDecl, childValue, func(normalizedValue interface{}) {
+			obj.set(strs.LastNameletOfFQDNWithEsc(childDecl.fqdn), normalizedValue)
 		})
 	}
 	return normalizeAndReturnValue(decl, obj)
@@ -229,23 +293,59 @@ func (p *parseCtx) parseArray(n *idr.Node, decl *Decl) (interface{}, error) {
 		// node.
 		xpath, dynamic, err := p.computeXPath(n, childDecl)
 		if err != nil {
+			p.traceArrayElemXPath(childDecl, "", false, nil, err)
 			continue
 		}
 		childNodes, err := idr.MatchAll(n, xpath, xpathMatchFlags(dynamic))
 		if err != nil {
-			return nil, fmt.Errorf("xpath query '%s' on '%s' failed: %s", xpath, childDecl.fqdn, err.Error())
+			return nil, declErr(
+				childDecl.fqdn, err, "xpath query '%s' on '%s' failed: %s", xpath, childDecl.fqdn, err.Error())
 		}
+		p.traceArrayElemXPath(childDecl, xpath, dynamic, childNodes, nil)
 		for _, childNode := range childNodes {
 			childValue, err := p.ParseNode(childNode, childDecl)
 			if err != nil {
 				return nil, err
 			}
-			// value returned by p.ParseNode is already normalized, thus this
-			// normalizeAndSaveValue won't fail.
-			_ = normalizeAndSaveValue(childDecl, childValue, func(normalizedValue interface{}) {
+			// value returned by p.ParseNode is already normalized, only need to decide whether to save it.
+			saveNormalizedValue(childDecl, childValue, func(normalizedValue interface{}) {
 				array = append(array, normalizedValue)
 			})
 		}
 	}
+	array, err := postProcessArray(decl, array)
+	if err != nil {
+		return nil, err
+	}
 	return normalizeAndReturnValue(decl, array)
 }
+
+func (p *parseCtx) parseSwitch(n *idr.Node, decl *Decl) (interface{}, error) {
+	n, err := p.querySingleNodeFromXPath(n, decl)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, nil
+	}
+	for _, caseDecl := range decl.Switch {
+		if caseDecl.When != nil {
+			whenValue, err := p.ParseNode(n, caseDecl.When)
+			if err != nil {
+				return nil, err
+			}
+			if !isPredicateTruthy(caseDecl.When, whenValue) {
+				continue
+			}
+		}
+		thenValue, err := p.ParseNode(n, caseDecl.Then)
+		if err != nil {
+			return nil, err
+		}
+		p.traceRawValue(thenValue)
+		return normalizeAndReturnValue(decl, thenValue)
+	}
+	// no case is chosen.
+	p.traceOmitted("no switch case chosen")
+	return nil, nil
+}
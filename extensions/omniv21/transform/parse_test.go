@@ -1,6 +1,7 @@
 package transform
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -29,6 +30,15 @@ func testNode() *idr.Node {
 	return nodeA
 }
 
+// testNodeWithChild returns an element node A with a single child element node of the given name and text.
+func testNodeWithChild(name, text string) *idr.Node {
+	nodeA := idr.CreateNode(idr.ElementNode, "A")
+	child := idr.CreateNode(idr.ElementNode, name)
+	idr.AddChild(nodeA, child)
+	idr.AddChild(child, idr.CreateNode(idr.TextNode, text))
+	return nodeA
+}
+
 func testParseCtx() *parseCtx {
 	ctx := NewParseCtx(
 		&transformctx.Ctx{
@@ -64,6 +74,10 @@ func testResultType(typ resultType) *resultType {
 	return &typ
 }
 
+func testMaxItems(maxItems int) *int {
+	return &maxItems
+}
+
 func TestComputeXPath(t *testing.T) {
 	for _, test := range []struct {
 		name     string
@@ -232,10 +246,8 @@ func TestParseCtx_ParseNode(t *testing.T) {
 				children: []*Decl{{XPath: strs.StrPtr("C"), kind: kindField, fqdn: "test_key"}},
 				kind:     kindObject,
 			},
-			expectedValue: map[string]interface{}{
-				"test_key": "c",
-			},
-			expectedErr: "",
+			expectedValue: testOrderedObject("test_key", "c"),
+			expectedErr:   "",
 		},
 		{
 			name: "array kind",
@@ -298,6 +310,21 @@ func TestParseCtx_ParseNode(t *testing.T) {
 	}
 }
 
+func TestParseCtx_ParseNode_Canceled(t *testing.T) {
+	ctx := testParseCtx()
+	c, cancel := context.WithCancel(context.Background())
+	ctx.transformCtx.Context = c
+	decl := &Decl{Const: strs.StrPtr("test_const"), kind: kindConst}
+	value, err := ctx.ParseNode(testNode(), decl)
+	assert.NoError(t, err)
+	assert.Equal(t, "test_const", value)
+	cancel()
+	value, err = ctx.ParseNode(testNode(), decl)
+	assert.Error(t, err)
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, value)
+}
+
 func TestParseConst(t *testing.T) {
 	value, err := testParseCtx().parseConst(&Decl{Const: strs.StrPtr("test_const")})
 	assert.NoError(t, err)
@@ -572,7 +599,7 @@ func TestParseCtx_ParseObject(t *testing.T) {
 	for _, test := range []struct {
 		name          string
 		decl          *Decl
-		expectedValue map[string]interface{}
+		expectedValue *orderedObject
 		expectedErr   string
 	}{
 		{
@@ -588,10 +615,8 @@ func TestParseCtx_ParseObject(t *testing.T) {
 					},
 				},
 			},
-			expectedValue: map[string]interface{}{
-				"test_key": "c",
-			},
-			expectedErr: "",
+			expectedValue: testOrderedObject("test_key", "c"),
+			expectedErr:   "",
 		},
 		{
 			name: "computeXPath failed",
@@ -784,6 +809,36 @@ func TestParseCtx_ParseArray(t *testing.T) {
 			expectedValue: nil,
 			expectedErr:   `unable to convert value 'abc' to type 'int' on 'test_fqdn.test_key', err: strconv.ParseInt: parsing "abc": invalid syntax`,
 		},
+		{
+			name: "distinct, sorted and limited",
+			decl: &Decl{
+				fqdn:       "test_fqdn",
+				kind:       kindArray,
+				DistinctBy: []string{"."},
+				SortBy:     []*SortKeyDecl{{Order: strs.StrPtr("desc")}},
+				MaxItems:   testMaxItems(2),
+				children: []*Decl{
+					{fqdn: "test_fqdn.elem[1]", kind: kindField, XPath: strs.StrPtr("*")},
+					{fqdn: "test_fqdn.elem[2]", kind: kindConst, Const: strs.StrPtr("b")},
+					{fqdn: "test_fqdn.elem[3]", kind: kindConst, Const: strs.StrPtr("a")},
+				},
+			},
+			expectedValue: []interface{}{"c", "b"},
+			expectedErr:   "",
+		},
+		{
+			name: "failed sorting",
+			decl: &Decl{
+				fqdn:   "test_fqdn",
+				kind:   kindArray,
+				SortBy: []*SortKeyDecl{{Type: strs.StrPtr("number")}},
+				children: []*Decl{
+					{fqdn: "test_fqdn.elem[1]", kind: kindField, XPath: strs.StrPtr("*")},
+				},
+			},
+			expectedValue: nil,
+			expectedErr:   `unable to convert sort key value 'b' to type 'number' on 'test_fqdn', err: 'b' is not a valid decimal`,
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			linkParent(test.decl)
@@ -803,3 +858,200 @@ func TestParseCtx_ParseArray(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCtx_ParseSwitch(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		node          *idr.Node // if nil, testNode() is used.
+		declJSON      string
+		expectedValue interface{}
+		expectedErr   string
+	}{
+		{
+			name: "first truthy case chosen",
+			declJSON: `{ "switch": [
+				{ "when": { "xpath": "X" }, "then": { "const": "x" } },
+				{ "when": { "const": "false" }, "then": { "const": "false" } },
+				{ "when": { "xpath": "B" }, "then": { "object": { "b": { "xpath": "B" } } } },
+				{ "when": { "custom_func": { "name": "test_func" } }, "then": { "const": "test_func" } },
+				{ "then": { "const": "default" } }
+			]}`,
+			expectedValue: testOrderedObject("b", "b"),
+		},
+		{
+			name: "field predicate on node text 'F'",
+			node: testNodeWithChild("gender", "F"),
+			declJSON: `{ "switch": [
+				{ "when": { "xpath": "gender[. = 'F']" }, "then": { "const": "female" } },
+				{ "then": { "const": "other" } }
+			]}`,
+			expectedValue: "female",
+		},
+		{
+			name: "field predicate on node text '0'",
+			node: testNodeWithChild("qty", "0"),
+			declJSON: `{ "switch": [
+				{ "when": { "xpath": "qty[. = '0']" }, "then": { "const": "out of stock" } },
+				{ "then": { "const": "in stock" } }
+			]}`,
+			expectedValue: "out of stock",
+		},
+		{
+			name: "const predicate '0'",
+			declJSON: `{ "switch": [
+				{ "when": { "const": "0" }, "then": { "const": "x" } },
+				{ "then": { "const": "default" } }
+			]}`,
+			expectedValue: "default",
+		},
+		{
+			name: "default case chosen",
+			declJSON: `{ "switch": [
+				{ "when": { "xpath": "X" }, "then": { "const": "x" } },
+				{ "then": { "const": "default" } }
+			]}`,
+			expectedValue: "default",
+		},
+		{
+			name: "no case chosen",
+			declJSON: `{ "switch": [
+				{ "when": { "xpath": "X" }, "then": { "const": "x" } }
+			]}`,
+			expectedValue: nil,
+		},
+		{
+			name: "xpath anchoring and type cast",
+			declJSON: `{ "xpath": "C", "type": "int", "switch": [
+				{ "when": { "xpath": ".[. = 'c']" }, "then": { "const": "123" } }
+			]}`,
+			expectedValue: int64(123),
+		},
+		{
+			name: "no nodes matched for xpath",
+			declJSON: `{ "xpath": "X", "switch": [
+				{ "then": { "const": "x" } }
+			]}`,
+			expectedValue: nil,
+		},
+		{
+			name: "when failed",
+			declJSON: `{ "switch": [
+				{ "when": { "xpath": "<" }, "then": { "const": "x" } }
+			]}`,
+			expectedErr: "xpath query '<' on 'FINAL_OUTPUT.test.case[1].when' failed: xpath '<' compilation failed: expression must evaluate to a node-set",
+		},
+		{
+			name: "then failed",
+			declJSON: `{ "switch": [
+				{ "then": { "const": "x", "type": "int" } }
+			]}`,
+			expectedErr: `unable to convert value 'x' to type 'int' on 'FINAL_OUTPUT.test.case[1].then', err: strconv.ParseInt: parsing "x": invalid syntax`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			finalOutputDecl, err := ValidateTransformDeclarations(
+				[]byte(`{"transform_declarations": { "FINAL_OUTPUT": { "object": { "test": `+test.declJSON+` }}}}`),
+				testParseCtx().customFuncs, nil)
+			assert.NoError(t, err)
+			n := test.node
+			if n == nil {
+				n = testNode()
+			}
+			value, err := testParseCtx().ParseNode(n, finalOutputDecl.Object["test"])
+			switch test.expectedErr {
+			case "":
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedValue, value)
+			default:
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+				assert.Nil(t, value)
+			}
+		})
+	}
+}
+
+func TestParseCtx_ParseDefault(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		declJSON      string
+		expectedValue interface{}
+		expectedErr   string
+	}{
+		{
+			name:          "const shorthand default",
+			declJSON:      `{ "xpath": "X", "default": "n/a" }`,
+			expectedValue: "n/a",
+		},
+		{
+			name:          "default converted by type",
+			declJSON:      `{ "xpath": "X", "type": "int", "default": "0" }`,
+			expectedValue: int64(0),
+		},
+		{
+			name:          "nested decl default",
+			declJSON:      `{ "xpath": "X", "default": { "xpath": "B" } }`,
+			expectedValue: "b",
+		},
+		{
+			name:          "default not used",
+			declJSON:      `{ "xpath": "B", "default": "n/a" }`,
+			expectedValue: "b",
+		},
+		{
+			name:          "default used for empty value",
+			declJSON:      `{ "const": "  ", "keep_empty_or_null": true, "default": "n/a" }`,
+			expectedValue: "n/a",
+		},
+		{
+			name:          "default resolves to nothing",
+			declJSON:      `{ "xpath": "X", "default": { "xpath": "Y" } }`,
+			expectedValue: nil,
+		},
+		{
+			name:          "object default",
+			declJSON:      `{ "xpath": "X", "object": { "a": { "xpath": "." } }, "default": { "object": { "a": { "const": "none" } } } }`,
+			expectedValue: testOrderedObject("a", "none"),
+		},
+		{
+			name:          "template default",
+			declJSON:      `{ "template": "t" }`,
+			expectedValue: "template default",
+		},
+		{
+			name:          "template site default",
+			declJSON:      `{ "template": "t", "default": "site default" }`,
+			expectedValue: "site default",
+		},
+		{
+			name:        "default conversion failed",
+			declJSON:    `{ "xpath": "X", "type": "int", "default": "abc" }`,
+			expectedErr: `unable to convert value 'abc' to type 'int' on 'FINAL_OUTPUT.test', err: strconv.ParseInt: parsing "abc": invalid syntax`,
+		},
+		{
+			name:        "default failed",
+			declJSON:    `{ "xpath": "X", "default": { "const": "abc", "type": "int" } }`,
+			expectedErr: `unable to convert value 'abc' to type 'int' on 'FINAL_OUTPUT.test.default', err: strconv.ParseInt: parsing "abc": invalid syntax`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			finalOutputDecl, err := ValidateTransformDeclarations(
+				[]byte(`{"transform_declarations": {
+					"FINAL_OUTPUT": { "object": { "test": `+test.declJSON+` }},
+					"t": { "xpath": "X", "default": "template default" }
+				}}`),
+				testParseCtx().customFuncs, nil)
+			assert.NoError(t, err)
+			value, err := testParseCtx().ParseNode(testNode(), finalOutputDecl.Object["test"])
+			switch test.expectedErr {
+			case "":
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedValue, value)
+			default:
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+				assert.Nil(t, value)
+			}
+		})
+	}
+}
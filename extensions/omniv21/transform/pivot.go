@@ -0,0 +1,142 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/jf-tech/go-corelib/caches"
+	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/jf-tech/omniparser/idr"
+)
+
+const (
+	pivotDefaultXPath       = "*"
+	unpivotDefaultKeyName   = "key"
+	unpivotDefaultValueName = "value"
+)
+
+func validatePivotXPath(fqdn, name string, xpath *string) error {
+	if xpath == nil {
+		return nil
+	}
+	if _, err := caches.GetXPathExpr(*xpath); err != nil {
+		return fmt.Errorf("'%s' has invalid '%s.xpath' value '%s': %s", fqdn, name, *xpath, err.Error())
+	}
+	return nil
+}
+
+func (ctx *validateCtx) validatePivot(fqdn string, decl *Decl, templateRefStack []string) error {
+	err := validatePivotXPath(fqdn, "pivot", decl.Pivot.XPath)
+	if err != nil {
+		return err
+	}
+	decl.Pivot.Key, err = ctx.validateDecl(strs.BuildFQDN(fqdn, "pivot.key"), decl.Pivot.Key, templateRefStack)
+	if err != nil {
+		return err
+	}
+	decl.Pivot.Value, err = ctx.validateDecl(strs.BuildFQDN(fqdn, "pivot.value"), decl.Pivot.Value, templateRefStack)
+	if err != nil {
+		return err
+	}
+	decl.children = append(decl.children, decl.Pivot.Key, decl.Pivot.Value)
+	return nil
+}
+
+func (ctx *validateCtx) validateUnpivot(fqdn string, decl *Decl, templateRefStack []string) error {
+	err := validatePivotXPath(fqdn, "unpivot", decl.Unpivot.XPath)
+	if err != nil {
+		return err
+	}
+	if decl.Unpivot.keyName() == decl.Unpivot.valueName() {
+		return fmt.Errorf("'%s' cannot have the same 'unpivot.key_name' and 'unpivot.value_name'", fqdn)
+	}
+	if decl.Unpivot.Value == nil {
+		// a decl without xpath yields the text of the node it's evaluated on.
+		decl.Unpivot.Value = &Decl{}
+	}
+	decl.Unpivot.Value, err = ctx.validateDecl(
+		strs.BuildFQDN(fqdn, "unpivot.value"), decl.Unpivot.Value, templateRefStack)
+	if err != nil {
+		return err
+	}
+	decl.children = append(decl.children, decl.Unpivot.Value)
+	return nil
+}
+
+func (d *UnpivotDecl) keyName() string {
+	return strs.StrPtrOrElse(d.KeyName, unpivotDefaultKeyName)
+}
+
+func (d *UnpivotDecl) valueName() string {
+	return strs.StrPtrOrElse(d.ValueName, unpivotDefaultValueName)
+}
+
+// parsePivot builds an object out of the nodes selected by the pivot's xpath: for each of the nodes, the
+// pivot's key yields the field name, and the pivot's value yields the field value. Nodes whose key yields
+// nothing are skipped. If multiple nodes yield the same key, the last one wins.
+func (p *parseCtx) parsePivot(n *idr.Node, decl *Decl) (interface{}, error) {
+	n, err := p.querySingleNodeFromXPath(n, decl)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, nil
+	}
+	xpath := strs.StrPtrOrElse(decl.Pivot.XPath, pivotDefaultXPath)
+	pairNodes, err := idr.MatchAll(n, xpath)
+	if err != nil {
+		return nil, declErr(decl.fqdn, err, "xpath query '%s' on '%s' failed: %s", xpath, decl.fqdn, err.Error())
+	}
+	obj := newOrderedObject()
+	for _, pairNode := range pairNodes {
+		key, err := p.ParseNode(pairNode, decl.Pivot.Key)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil || isEmpty(key) {
+			continue
+		}
+		value, err := p.ParseNode(pairNode, decl.Pivot.Value)
+		if err != nil {
+			return nil, err
+		}
+		// value returned by p.ParseNode is already normalized, only need to decide whether to save it.
+		saveNormalizedValue(decl.Pivot.Value, value, func(normalizedValue interface{}) {
+			obj.set(valueString(key), normalizedValue)
+		})
+	}
+	return normalizeAndReturnValue(decl, obj)
+}
+
+// parseUnpivot builds an array of name/value pair objects out of the nodes selected by the unpivot's
+// xpath: the name is the node's name, and the value is what the unpivot's value yields on the node. Nodes
+// whose value yields nothing are skipped, unless the value has 'keep_empty_or_null' set.
+func (p *parseCtx) parseUnpivot(n *idr.Node, decl *Decl) (interface{}, error) {
+	n, err := p.querySingleNodeFromXPath(n, decl)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, nil
+	}
+	xpath := strs.StrPtrOrElse(decl.Unpivot.XPath, pivotDefaultXPath)
+	pairNodes, err := idr.MatchAll(n, xpath)
+	if err != nil {
+		return nil, declErr(decl.fqdn, err, "xpath query '%s' on '%s' failed: %s", xpath, decl.fqdn, err.Error())
+	}
+	var array []interface{}
+	for _, pairNode := range pairNodes {
+		value, err := p.ParseNode(pairNode, decl.Unpivot.Value)
+		if err != nil {
+			return nil, err
+		}
+		// value returned by p.ParseNode is already normalized, only need to decide whether to save it.
+		saveNormalizedValue(decl.Unpivot.Value, value, func(normalizedValue interface{}) {
+			pair := newOrderedObject()
+			pair.set(decl.Unpivot.keyName(), pairNode.Data)
+			pair.set(decl.Unpivot.valueName(), normalizedValue)
+			array = append(array, pair)
+		})
+	}
+	return normalizeAndReturnValue(decl, array)
+}
@@ -0,0 +1,200 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jf-tech/omniparser/idr"
+)
+
+func testPivotNode(t *testing.T) *idr.Node {
+	sp, err := idr.NewXMLStreamReader(strings.NewReader(`
+        <order>
+            <attr name="color" value="red"/>
+            <attr name="size" value=" 10 "/>
+            <attr name="" value="no name"/>
+            <attr name="note" value=""/>
+            <attr name="color" value="blue"/>
+            <dims><w>3</w><h>4</h><d></d></dims>
+        </order>`), "/order")
+	assert.NoError(t, err)
+	n, err := sp.Read()
+	assert.NoError(t, err)
+	return n
+}
+
+func TestParseCtx_Pivot(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		declJSON string
+		err      string
+		expected interface{}
+	}{
+		{
+			name: "pivot",
+			declJSON: `{ "pivot": {
+                "xpath": "attr", "key": { "xpath": "@name" }, "value": { "xpath": "@value" } } }`,
+			expected: testOrderedObject("color", "blue", "size", "10"),
+		},
+		{
+			name: "pivot with kept empty values",
+			declJSON: `{ "pivot": {
+                "xpath": "attr[@name != 'color']",
+                "key": { "xpath": "@name" },
+                "value": { "xpath": "@value", "keep_empty_or_null": true } } }`,
+			expected: testOrderedObject("size", "10", "note", ""),
+		},
+		{
+			name: "pivot with typed values",
+			declJSON: `{ "pivot": {
+                "xpath": "attr[@name = 'size']", "key": { "xpath": "@name" }, "value": { "xpath": "@value", "type": "int" } } }`,
+			expected: testOrderedObject("size", int64(10)),
+		},
+		{
+			name: "pivot with datetime values",
+			declJSON: `{ "pivot": {
+                "xpath": "attr[@name = 'size']", "key": { "xpath": "@name" },
+                "value": { "xpath": "@value", "type": "datetime", "datetime": { "layouts": [ "06" ], "format": "2006" } } } }`,
+			expected: testOrderedObject("size", "2010"),
+		},
+		{
+			name:     "pivot with default xpath",
+			declJSON: `{ "xpath": "dims", "pivot": { "key": { "const": "k" }, "value": { "xpath": "." } } }`,
+			expected: testOrderedObject("k", "4"),
+		},
+		{
+			name:     "pivot yields nothing",
+			declJSON: `{ "pivot": { "xpath": "nothing", "key": { "xpath": "@name" }, "value": { "xpath": "@value" } } }`,
+			expected: nil,
+		},
+		{
+			name:     "pivot anchor yields nothing",
+			declJSON: `{ "xpath": "nothing", "pivot": { "key": { "xpath": "@name" }, "value": { "xpath": "@value" } } }`,
+			expected: nil,
+		},
+		{
+			name:     "pivot value fails",
+			declJSON: `{ "pivot": { "xpath": "attr", "key": { "xpath": "@name" }, "value": { "xpath": "@value", "type": "int" } } }`,
+			err:      "unable to convert value 'red' to type 'int' on 'FINAL_OUTPUT.test.pivot.value', err: strconv.ParseInt: parsing \"red\": invalid syntax",
+		},
+		{
+			name:     "invalid pivot xpath",
+			declJSON: `{ "pivot": { "xpath": "<", "key": { "xpath": "@name" }, "value": { "xpath": "@value" } } }`,
+			err:      "'FINAL_OUTPUT.test' has invalid 'pivot.xpath' value '<': expression must evaluate to a node-set",
+		},
+		{
+			name:     "unpivot",
+			declJSON: `{ "xpath": "dims", "unpivot": {} }`,
+			expected: []interface{}{
+				testOrderedObject("key", "w", "value", "3"),
+				testOrderedObject("key", "h", "value", "4"),
+			},
+		},
+		{
+			name: "unpivot with datetime values",
+			declJSON: `{ "xpath": "dims", "unpivot": {
+                "value": { "type": "datetime", "datetime": { "layouts": [ "1" ], "format": "January" } } } }`,
+			expected: []interface{}{
+				testOrderedObject("key", "w", "value", "March"),
+				testOrderedObject("key", "h", "value", "April"),
+			},
+		},
+		{
+			name: "unpivot attributes with names and value",
+			declJSON: `{ "xpath": "attr[1]", "unpivot": {
+                "xpath": "@*", "key_name": "attr", "value_name": "v",
+                "value": { "custom_func": { "name": "upper", "args": [ { "xpath": "." } ] } } } }`,
+			expected: []interface{}{
+				testOrderedObject("attr", "name", "v", "COLOR"),
+				testOrderedObject("attr", "value", "v", "RED"),
+			},
+		},
+		{
+			name:     "unpivot keeps empty",
+			declJSON: `{ "xpath": "dims", "unpivot": { "xpath": "d", "value": { "keep_empty_or_null": true } } }`,
+			expected: []interface{}{testOrderedObject("key", "d", "value", "")},
+		},
+		{
+			name:     "unpivot yields nothing",
+			declJSON: `{ "xpath": "dims", "unpivot": { "xpath": "d" } }`,
+			expected: nil,
+		},
+		{
+			name:     "unpivot same key and value names",
+			declJSON: `{ "unpivot": { "key_name": "value" } }`,
+			err:      "'FINAL_OUTPUT.test' cannot have the same 'unpivot.key_name' and 'unpivot.value_name'",
+		},
+		{
+			name:     "invalid unpivot xpath",
+			declJSON: `{ "unpivot": { "xpath": "<" } }`,
+			err:      "'FINAL_OUTPUT.test' has invalid 'unpivot.xpath' value '<': expression must evaluate to a node-set",
+		},
+		{
+			name:     "pivot via template",
+			declJSON: `{ "template": "t" }`,
+			expected: testOrderedObject("color", "blue", "size", "10"),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			finalOutputDecl, err := ValidateTransformDeclarations(
+				[]byte(`{"transform_declarations": {
+					"FINAL_OUTPUT": { "object": { "test": `+test.declJSON+` }},
+					"t": { "pivot": { "xpath": "attr", "key": { "xpath": "@name" }, "value": { "xpath": "@value" } } }
+				}}`),
+				testParseCtx().customFuncs, nil)
+			if err == nil {
+				var value interface{}
+				value, err = testParseCtx().ParseNode(testPivotNode(t), finalOutputDecl.Object["test"])
+				if err == nil {
+					assert.Equal(t, test.expected, value)
+				}
+			}
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPivot_DescribeAndOutputJSONSchema(t *testing.T) {
+	finalOutputDecl, err := ValidateTransformDeclarations([]byte(`{"transform_declarations": {
+        "FINAL_OUTPUT": { "object": {
+            "attrs": { "pivot": { "key": { "xpath": "@name" }, "value": { "xpath": "@value", "type": "int" } } },
+            "dims": { "unpivot": { "key_name": "dim" } }
+        }}
+    }}`), nil, nil)
+	assert.NoError(t, err)
+	desc := finalOutputDecl.Describe()
+	assert.Equal(t, "pivot", desc.Children[0].Kind)
+	assert.Equal(t, "*", desc.Children[0].Pivot.XPath)
+	assert.Equal(t, "FINAL_OUTPUT.attrs.pivot.key", desc.Children[0].Pivot.Key.FQDN)
+	assert.Equal(t, "FINAL_OUTPUT.attrs.pivot.value", desc.Children[0].Pivot.Value.FQDN)
+	assert.Equal(t, "unpivot", desc.Children[1].Kind)
+	assert.Equal(t, "dim", desc.Children[1].Unpivot.KeyName)
+	assert.Equal(t, "value", desc.Children[1].Unpivot.ValueName)
+	assert.Equal(t, "field", desc.Children[1].Unpivot.Value.Kind)
+	b, err := finalOutputDecl.OutputJSONSchema("")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+        "$schema": "http://json-schema.org/draft-07/schema#",
+        "type": "object",
+        "properties": {
+            "attrs": { "type": "object", "additionalProperties": { "type": "integer" } },
+            "dims": {
+                "type": "array",
+                "items": {
+                    "type": "object",
+                    "properties": { "dim": { "type": "string" }, "value": { "type": "string" } },
+                    "required": [ "dim", "value" ],
+                    "additionalProperties": false
+                }
+            }
+        },
+        "additionalProperties": false
+    }`, string(b))
+}
@@ -8,39 +8,218 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/jf-tech/go-corelib/caches"
 	"github.com/jf-tech/go-corelib/strs"
 
 	"github.com/jf-tech/omniparser/customfuncs"
+	"github.com/jf-tech/omniparser/decimal"
+	v21validation "github.com/jf-tech/omniparser/extensions/omniv21/validation"
+	"github.com/jf-tech/omniparser/schemahandler"
+	"github.com/jf-tech/omniparser/validation"
 )
 
 type validateCtx struct {
-	Decls            map[string]*Decl `json:"transform_declarations"`
+	Decls            map[string]*Decl            `json:"transform_declarations"`
+	Imports          []string                    `json:"imports"`
+	LookupTables     map[string]*LookupTableDecl `json:"lookup_tables"`
+	Variables        map[string]*VarDecl         `json:"variables"`
+	Coercion         *CoercionDecl               `json:"coercion"`
 	customFuncs      customfuncs.CustomFuncs
 	customParseFuncs CustomParseFuncs // Deprecated.
+	importResolver   schemahandler.ImportResolver
+	importScopes     map[string]*importScope // import ref -> what the imported document provides.
+	lookupTables     lookupTables
 	declHashes       map[string]string
 }
 
+// importScope contains the decls and lookup tables a document provides to the document importing it, i.e.
+// its own ones and those it imports that aren't shadowed by its own ones, along with, for each of them, the
+// import ref of the document that declares it.
+type importScope struct {
+	decls     map[string]*Decl
+	declFrom  map[string]string
+	tables    map[string]*LookupTableDecl
+	tableFrom map[string]string
+}
+
+func newImportScope() *importScope {
+	return &importScope{
+		decls:     map[string]*Decl{},
+		declFrom:  map[string]string{},
+		tables:    map[string]*LookupTableDecl{},
+		tableFrom: map[string]string{},
+	}
+}
+
+// merge adds the decls and lookup tables provided by an imported document into s, except those shadowed by
+// the importing document's own decls and lookup tables. The same name declared by two different documents
+// is an error, since it's ambiguous which one to use.
+func (s *importScope) merge(
+	imported *importScope, ownDecls map[string]*Decl, ownTables map[string]*LookupTableDecl) error {
+	for name, decl := range imported.decls {
+		if _, found := ownDecls[name]; found {
+			continue
+		}
+		from := imported.declFrom[name]
+		if existingFrom, found := s.declFrom[name]; found && existingFrom != from {
+			return fmt.Errorf("'%s' is imported from both '%s' and '%s'", name, existingFrom, from)
+		}
+		s.decls[name] = decl
+		s.declFrom[name] = from
+	}
+	for name, tableDecl := range imported.tables {
+		if _, found := ownTables[name]; found {
+			continue
+		}
+		from := imported.tableFrom[name]
+		if existingFrom, found := s.tableFrom[name]; found && existingFrom != from {
+			return fmt.Errorf("lookup table '%s' is imported from both '%s' and '%s'", name, existingFrom, from)
+		}
+		s.tables[name] = tableDecl
+		s.tableFrom[name] = from
+	}
+	return nil
+}
+
 // ValidateTransformDeclarations validates `transform_declarations` section of an omni schema and returns
 // the `FINAL_OUTPUT` corresponding Decl.
 func ValidateTransformDeclarations(
 	schemaContent []byte, customFuncs customfuncs.CustomFuncs, customParseFuncs CustomParseFuncs) (*Decl, error) {
+	return ValidateTransformDeclarationsWithImports(schemaContent, customFuncs, customParseFuncs, nil)
+}
+
+// ValidateTransformDeclarationsWithImports is the same as ValidateTransformDeclarations, except it also
+// resolves the schema's `imports`, if any, using the given importResolver, and makes the declarations in
+// the imported documents available as templates. The importResolver is also used for loading the schema's
+// file based `lookup_tables`, if any.
+func ValidateTransformDeclarationsWithImports(
+	schemaContent []byte,
+	customFuncs customfuncs.CustomFuncs,
+	customParseFuncs CustomParseFuncs,
+	importResolver schemahandler.ImportResolver) (*Decl, error) {
 
 	var ctx validateCtx
 	// We did json schema validation earlier, so this unmarshal guarantees to succeed.
 	_ = json.Unmarshal(schemaContent, &ctx)
 	ctx.customFuncs = customFuncs
 	ctx.customParseFuncs = customParseFuncs
+	ctx.importResolver = importResolver
+	ctx.importScopes = map[string]*importScope{}
 	ctx.declHashes = map[string]string{}
 
+	if ctx.Coercion != nil {
+		if err := ctx.Coercion.validate(); err != nil {
+			return nil, fmt.Errorf("invalid top level 'coercion': %s", err.Error())
+		}
+	}
+
+	imported, err := ctx.resolveImports(ctx.Imports, nil, ctx.Decls, ctx.LookupTables)
+	if err != nil {
+		return nil, err
+	}
+	for name, decl := range imported.decls {
+		ctx.Decls[name] = decl
+	}
+	for name, tableDecl := range imported.tables {
+		if ctx.LookupTables == nil {
+			ctx.LookupTables = map[string]*LookupTableDecl{}
+		}
+		ctx.LookupTables[name] = tableDecl
+	}
+
+	err = ctx.loadLookupTables()
+	if err != nil {
+		return nil, err
+	}
+
+	vars, err := ctx.validateVars()
+	if err != nil {
+		return nil, err
+	}
+
 	// We did json schema validation earlier, so "FINAL_OUTPUT" must exist.
 	finalOutputDecl, err := ctx.validateDecl(finalOutput, ctx.Decls[finalOutput], []string{finalOutput})
 	if err != nil {
 		return nil, err
 	}
 	linkParent(finalOutputDecl)
+	finalOutputDecl.vars = vars
+
+	finalOutputDecl.filter, err = ctx.validateFilter()
+	if err != nil {
+		return nil, err
+	}
 	return finalOutputDecl, nil
 }
 
+// Similar to circular template references, in order to detect circular imports (e.g. document A imports B
+// which imports C and C imports A), we keep an import stack, and everytime we see an import, we push it onto
+// the stack and check if it has appeared before or not. resolveImports returns the decls and lookup tables
+// provided by all the given imports, except those shadowed by the importing document's own ownDecls and
+// ownTables. That is, just like the declarations in the schema itself take precedence over the imported ones
+// with the same names, the declarations in an imported document take precedence over the ones it imports:
+// the nearer import wins. But the same name provided by two different imports is an error, unless both are
+// from the same document, since it's ambiguous which one to use.
+func (ctx *validateCtx) resolveImports(imports []string, importStack []string,
+	ownDecls map[string]*Decl, ownTables map[string]*LookupTableDecl) (*importScope, error) {
+	scope := newImportScope()
+	for _, ref := range imports {
+		stack := append(strs.CopySlice(importStack), ref)
+		if strs.HasDup(stack) {
+			return nil, fmt.Errorf("import circular dependency detected on '%s': %s",
+				ref, strings.Join(
+					strs.NoErrMapSlice(stack, func(s string) string { return "'" + s + "'" }),
+					"->"))
+		}
+		imported, err := ctx.resolveImport(ref, stack)
+		if err != nil {
+			return nil, err
+		}
+		if err = scope.merge(imported, ownDecls, ownTables); err != nil {
+			return nil, err
+		}
+	}
+	return scope, nil
+}
+
+// resolveImport returns the decls and lookup tables provided by the imported document ref.
+func (ctx *validateCtx) resolveImport(ref string, importStack []string) (*importScope, error) {
+	if scope, found := ctx.importScopes[ref]; found {
+		return scope, nil
+	}
+	if ctx.importResolver == nil {
+		return nil, fmt.Errorf("unable to resolve import '%s': no import resolver provided", ref)
+	}
+	content, err := ctx.importResolver.Resolve(ref)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve import '%s': %s", ref, err.Error())
+	}
+	err = validation.SchemaValidate(ref, content, v21validation.JSONSchemaTransformDeclarationsImport)
+	if err != nil {
+		return nil, err
+	}
+	var imported validateCtx
+	// We just did json schema validation, so this unmarshal guarantees to succeed.
+	_ = json.Unmarshal(content, &imported)
+	scope, err := ctx.resolveImports(imported.Imports, importStack, imported.Decls, imported.LookupTables)
+	if err != nil {
+		return nil, err
+	}
+	for name, decl := range imported.Decls {
+		if name == finalOutput || name == filter {
+			continue
+		}
+		scope.decls[name] = decl
+		scope.declFrom[name] = ref
+	}
+	for name, tableDecl := range imported.LookupTables {
+		scope.tables[name] = tableDecl
+		scope.tableFrom[name] = ref
+	}
+	ctx.importScopes[ref] = scope
+	return scope, nil
+}
+
 // In order to detect circular template references (e.g. template A has a reference to template B which
 // has a reference to C and C has one back to A), we need to keep a template reference stack, starting
 // from the root template 'FINAL_OUTPUT'. Everytime we see a template, we push its name onto the stack.
@@ -52,7 +231,46 @@ func (ctx *validateCtx) validateDecl(fqdn string, decl *Decl, templateRefStack [
 	}
 	decl.fqdn = fqdn
 	decl.resolveKind()
+	err = ctx.validateDecimal(fqdn, decl)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.validateDateTime(fqdn, decl)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.validateCoercion(fqdn, decl)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.validateExplode(fqdn, decl)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.validateValidate(fqdn, decl)
+	if err != nil {
+		return nil, err
+	}
+	// a template's 'default' and 'on_error' (or the ones at the template site) are validated along with
+	// the template.
+	if decl.kind != kindTemplate {
+		err = ctx.validateOnError(fqdn, decl)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if decl.Default != nil && decl.kind != kindTemplate {
+		decl.Default, err = ctx.validateDecl(strs.BuildFQDN(fqdn, "default"), decl.Default, templateRefStack)
+		if err != nil {
+			return nil, err
+		}
+		linkParent(decl.Default)
+	}
 	switch decl.kind {
+	case kindVar:
+		if _, found := ctx.Variables[*decl.Var]; !found {
+			return nil, fmt.Errorf("'%s' refers to non-existing variable '%s'", fqdn, *decl.Var)
+		}
 	case kindObject:
 		err := ctx.validateObject(fqdn, decl, templateRefStack)
 		if err != nil {
@@ -78,6 +296,21 @@ func (ctx *validateCtx) validateDecl(fqdn string, decl *Decl, templateRefStack [
 		if err != nil {
 			return nil, err
 		}
+	case kindSwitch:
+		err := ctx.validateSwitch(fqdn, decl, templateRefStack)
+		if err != nil {
+			return nil, err
+		}
+	case kindPivot:
+		err := ctx.validatePivot(fqdn, decl, templateRefStack)
+		if err != nil {
+			return nil, err
+		}
+	case kindUnpivot:
+		err := ctx.validateUnpivot(fqdn, decl, templateRefStack)
+		if err != nil {
+			return nil, err
+		}
 	}
 	decl.hash = computeDeclHash(decl, ctx.declHashes)
 	return decl, nil
@@ -101,23 +334,73 @@ func (ctx *validateCtx) validateXPath(fqdn string, decl *Decl, templateRefStack
 	return nil
 }
 
+func (ctx *validateCtx) validateDecimal(fqdn string, decl *Decl) error {
+	if decl.Scale == nil && decl.Rounding == nil {
+		return nil
+	}
+	if decl.ResultType == nil || *decl.ResultType != resultTypeDecimal {
+		return fmt.Errorf("'%s' cannot set 'scale' or 'rounding' unless 'type' is 'decimal'", fqdn)
+	}
+	if decl.Rounding != nil && decl.Scale == nil {
+		return fmt.Errorf("'%s' cannot set 'rounding' without 'scale'", fqdn)
+	}
+	if decl.Scale != nil && (*decl.Scale < 0 || *decl.Scale > decimal.MaxExponent) {
+		return fmt.Errorf("'%s' has invalid 'scale' value %d: must be within [0, %d]",
+			fqdn, *decl.Scale, decimal.MaxExponent)
+	}
+	if decl.Rounding != nil && !decimal.RoundingMode(*decl.Rounding).IsValid() {
+		return fmt.Errorf("'%s' has invalid 'rounding' value '%s'", fqdn, *decl.Rounding)
+	}
+	return nil
+}
+
+func (ctx *validateCtx) validateDateTime(fqdn string, decl *Decl) error {
+	if decl.DateTime == nil {
+		return nil
+	}
+	if decl.ResultType == nil || *decl.ResultType != resultTypeDateTime {
+		return fmt.Errorf("'%s' cannot set 'datetime' unless 'type' is 'datetime'", fqdn)
+	}
+	for _, layout := range decl.DateTime.Layouts {
+		if !isValidLayout(layout) {
+			return fmt.Errorf("'%s' has invalid 'datetime.layouts' value '%s'", fqdn, layout)
+		}
+	}
+	for _, tz := range []struct {
+		name  string
+		value *string
+	}{{"from_tz", decl.DateTime.FromTZ}, {"to_tz", decl.DateTime.ToTZ}} {
+		if tz.value == nil {
+			continue
+		}
+		if _, err := caches.GetTimeLocation(*tz.value); err != nil {
+			return fmt.Errorf("'%s' has invalid 'datetime.%s' value '%s': %s",
+				fqdn, tz.name, *tz.value, err.Error())
+		}
+	}
+	switch format := strs.StrPtrOrElse(decl.DateTime.Format, dateTimeFormatRFC3339); format {
+	case dateTimeFormatRFC3339, dateTimeFormatEpoch, dateTimeFormatEpochMillis:
+	default:
+		if !isValidLayout(format) {
+			return fmt.Errorf("'%s' has invalid 'datetime.format' value '%s'", fqdn, format)
+		}
+	}
+	return nil
+}
+
 func (ctx *validateCtx) validateObject(fqdn string, decl *Decl, templateRefStack []string) error {
-	for childName, childDecl := range decl.Object {
+	for _, childName := range decl.objectFieldNames() {
 		childDecl, err := ctx.validateDecl(
 			// childName can contain '.' or '%', it needs to be escaped.
-			strs.BuildFQDN(fqdn, strs.BuildFQDNWithEsc(childName)), childDecl, templateRefStack)
+			strs.BuildFQDN(fqdn, strs.BuildFQDNWithEsc(childName)), decl.Object[childName], templateRefStack)
 		if err != nil {
 			return err
 		}
 		decl.Object[childName] = childDecl
 		decl.children = append(decl.children, childDecl)
 	}
-	// Sort the `children` array for unit test snapshot stability.
-	// Given this schema parsing/loading is usually done infrequently, the sorting here shouldn't
-	// incur too much latency penalty for production code path.
-	if len(decl.children) > 0 {
-		sort.Slice(decl.children, func(i, j int) bool { return decl.children[i].fqdn < decl.children[j].fqdn })
-	}
+	// Note the `children` array is in the declared order of the fields, which is also the order of
+	// the fields in the output.
 	return nil
 }
 
@@ -138,6 +421,30 @@ func (ctx *validateCtx) validateArray(fqdn string, decl *Decl, templateRefStack
 	return nil
 }
 
+func (ctx *validateCtx) validateSwitch(fqdn string, decl *Decl, templateRefStack []string) error {
+	for i, caseDecl := range decl.Switch {
+		caseFQDN := strs.BuildFQDN(fqdn, fmt.Sprintf("case[%d]", i+1))
+		if caseDecl.When == nil && i != len(decl.Switch)-1 {
+			return fmt.Errorf("'%s' is a default case (without 'when') but not the last case", caseFQDN)
+		}
+		if caseDecl.When != nil {
+			whenDecl, err := ctx.validateDecl(strs.BuildFQDN(caseFQDN, "when"), caseDecl.When, templateRefStack)
+			if err != nil {
+				return err
+			}
+			caseDecl.When = whenDecl
+			decl.children = append(decl.children, whenDecl)
+		}
+		thenDecl, err := ctx.validateDecl(strs.BuildFQDN(caseFQDN, "then"), caseDecl.Then, templateRefStack)
+		if err != nil {
+			return err
+		}
+		caseDecl.Then = thenDecl
+		decl.children = append(decl.children, thenDecl)
+	}
+	return nil
+}
+
 func (ctx *validateCtx) validateCustomFunc(fqdn string, decl *Decl, templateRefStack []string) error {
 	fn, found := ctx.customFuncs[decl.CustomFunc.Name]
 	if !found {
@@ -159,6 +466,13 @@ func (ctx *validateCtx) validateCustomFunc(fqdn string, decl *Decl, templateRefS
 			decl.CustomFunc.Name, fnType.Out(1))
 	}
 	decl.CustomFunc.fqdn = strs.BuildFQDN(fqdn, fmt.Sprintf("custom_func(%s)", decl.CustomFunc.Name))
+	decl.CustomFunc.fn = fn
+	if decl.CustomFunc.Name == lookupCustomFuncName && ctx.lookupTables != nil {
+		err := ctx.validateLookupArgs(decl.CustomFunc.fqdn, decl.CustomFunc)
+		if err != nil {
+			return err
+		}
+	}
 	for i := 0; i < len(decl.CustomFunc.Args); i++ {
 		argDecl, err := ctx.validateDecl(
 			strs.BuildFQDN(decl.CustomFunc.fqdn, fmt.Sprintf("arg[%d]", i+1)),
@@ -210,6 +524,16 @@ func (ctx *validateCtx) validateTemplate(fqdn string, decl *Decl, templateRefSta
 		declNew.XPath = decl.XPath
 		declNew.XPathDynamic = decl.XPathDynamic
 	}
+	// the 'default', 'validate' and 'on_error' at the template site take precedence over the template's own.
+	if decl.Default != nil {
+		declNew.Default = decl.Default
+	}
+	if decl.Validate != nil {
+		declNew.Validate = decl.Validate
+	}
+	if decl.OnError != nil {
+		declNew.OnError = decl.OnError
+	}
 
 	return ctx.validateDecl(fqdn, declNew, templateRefStack)
 }
@@ -1,6 +1,8 @@
 package transform
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/bradleyjkemp/cupaloy"
@@ -10,6 +12,7 @@ import (
 
 	"github.com/jf-tech/omniparser/customfuncs"
 	"github.com/jf-tech/omniparser/idr"
+	"github.com/jf-tech/omniparser/schemahandler"
 	"github.com/jf-tech/omniparser/transformctx"
 )
 
@@ -211,6 +214,202 @@ func TestValidateTransformDeclarations(t *testing.T) {
             }`,
 			err: "cannot specify 'xpath' or 'xpath_dynamic' on both 'FINAL_OUTPUT.field_1' and the template 'template1' it references",
 		},
+		{
+			name: "success - switch",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "xpath": "A", "switch": [
+                        { "when": { "xpath": "B" }, "then": { "template": "template_b" } },
+                        { "when": { "custom_func": { "name": "test_func" } }, "then": { "const": "c" } },
+                        { "then": { "switch": [ { "then": { "xpath": "D" } } ] } }
+                    ]},
+                    "template_b": { "object": { "b": { "xpath": "B" } } }
+                }
+            }`,
+			err: "",
+		},
+		{
+			name: "failure - switch default case not last",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "switch": [
+                        { "then": { "const": "default" } },
+                        { "when": { "xpath": "B" }, "then": { "const": "b" } }
+                    ]}
+                }
+            }`,
+			err: "'FINAL_OUTPUT.case[1]' is a default case (without 'when') but not the last case",
+		},
+		{
+			name: "failure - switch case decl validation failure",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "switch": [
+                        { "when": { "xpath": "B" }, "then": { "template": "non_existing" } }
+                    ]}
+                }
+            }`,
+			err: "'FINAL_OUTPUT.case[1].then' contains non-existing template reference 'non_existing'",
+		},
+		{
+			name: "failure - switch when decl validation failure",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "switch": [
+                        { "when": { "template": "non_existing" }, "then": { "const": "b" } }
+                    ]}
+                }
+            }`,
+			err: "'FINAL_OUTPUT.case[1].when' contains non-existing template reference 'non_existing'",
+		},
+		{
+			name: "success - decimal",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "amount": { "xpath": "A", "type": "decimal", "scale": 2, "rounding": "half_even" },
+                        "rate": { "xpath": "B", "type": "decimal", "scale": 4 },
+                        "total": { "xpath": "C", "type": "decimal" }
+                    }}
+                }
+            }`,
+			err: "",
+		},
+		{
+			name: "failure - scale without decimal type",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "amount": { "xpath": "A", "type": "float", "scale": 2 }
+                    }}
+                }
+            }`,
+			err: "'FINAL_OUTPUT.amount' cannot set 'scale' or 'rounding' unless 'type' is 'decimal'",
+		},
+		{
+			name: "failure - rounding without scale",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "amount": { "xpath": "A", "type": "decimal", "rounding": "up" }
+                    }}
+                }
+            }`,
+			err: "'FINAL_OUTPUT.amount' cannot set 'rounding' without 'scale'",
+		},
+		{
+			name: "failure - negative scale",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "amount": { "xpath": "A", "type": "decimal", "scale": -1 }
+                    }}
+                }
+            }`,
+			err: "'FINAL_OUTPUT.amount' has invalid 'scale' value -1: must be within [0, 308]",
+		},
+		{
+			name: "failure - scale too large",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "amount": { "xpath": "A", "type": "decimal", "scale": 309 }
+                    }}
+                }
+            }`,
+			err: "'FINAL_OUTPUT.amount' has invalid 'scale' value 309: must be within [0, 308]",
+		},
+		{
+			name: "failure - invalid rounding",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "amount": { "xpath": "A", "type": "decimal", "scale": 2, "rounding": "nearest" }
+                    }}
+                }
+            }`,
+			err: "'FINAL_OUTPUT.amount' has invalid 'rounding' value 'nearest'",
+		},
+		{
+			name: "success - datetime",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "date": { "xpath": "A", "type": "datetime" },
+                        "date_epoch": { "xpath": "A", "type": "datetime", "datetime": {
+                            "layouts": [ "01/02/2006 15:04", "2006-01-02" ],
+                            "from_tz": "America/Los_Angeles",
+                            "to_tz": "UTC",
+                            "format": "epoch"
+                        }},
+                        "date_custom": { "xpath": "A", "type": "datetime", "datetime": { "format": "Jan 2, 2006" } }
+                    }}
+                }
+            }`,
+			err: "",
+		},
+		{
+			name: "failure - datetime without datetime type",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "date": { "xpath": "A", "datetime": { "format": "epoch" } }
+                    }}
+                }
+            }`,
+			err: "'FINAL_OUTPUT.date' cannot set 'datetime' unless 'type' is 'datetime'",
+		},
+		{
+			name: "failure - invalid datetime layout",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "date": { "xpath": "A", "type": "datetime", "datetime": { "layouts": [ "2006-01-02", "yyyy" ] } }
+                    }}
+                }
+            }`,
+			err: "'FINAL_OUTPUT.date' has invalid 'datetime.layouts' value 'yyyy'",
+		},
+		{
+			name: "failure - invalid datetime tz",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "date": { "xpath": "A", "type": "datetime", "datetime": { "from_tz": "UTC", "to_tz": "Mars/Olympus" } }
+                    }}
+                }
+            }`,
+			err: "'FINAL_OUTPUT.date' has invalid 'datetime.to_tz' value 'Mars/Olympus': unknown time zone Mars/Olympus",
+		},
+		{
+			name: "failure - invalid datetime format",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "date": { "xpath": "A", "type": "datetime", "datetime": { "format": "yyyy-MM-dd" } }
+                    }}
+                }
+            }`,
+			err: "'FINAL_OUTPUT.date' has invalid 'datetime.format' value 'yyyy-MM-dd'",
+		},
+		{
+			name: "success - array post processing",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "lines": { "array": [ { "xpath": "LINE", "object": {
+                            "line_no": { "xpath": "NO", "type": "int" },
+                            "ref": { "xpath": "REF" }
+                        }}],
+                        "sort_by": [ { "field": "line_no", "type": "number", "order": "desc" }, { "field": "ref" } ],
+                        "distinct_by": [ "ref" ],
+                        "max_items": 10 },
+                        "refs": { "array": [ { "xpath": "LINE/REF" } ], "sort_by": [ {} ], "distinct_by": [ "." ] }
+                    }}
+                }
+            }`,
+			err: "",
+		},
 		{
 			name: "failure - unknown custom_parse",
 			declJSON: ` {
@@ -282,3 +481,173 @@ func TestComputeDeclHash(t *testing.T) {
 
 	assert.NotEqual(t, jsons.BPM(decl1), jsons.BPM(decl1Copy))
 }
+
+func TestValidateTransformDeclarationsWithImports(t *testing.T) {
+	docs := map[string]string{
+		"lib1": `{
+            "imports": [ "lib2" ],
+            "transform_declarations": {
+                "FINAL_OUTPUT": { "const": "ignored" },
+                "t1": { "const": "lib1.t1" },
+                "t_overridden": { "const": "lib1.t_overridden" }
+            }
+        }`,
+		"lib2": `{
+            "transform_declarations": {
+                "t2": { "object": { "x": { "template": "t1" } } }
+            }
+        }`,
+		"lib3": `{
+            "imports": [ "lib2" ],
+            "transform_declarations": { "t3": { "const": "lib3.t3" } }
+        }`,
+		"lib_invalid":   `{ "imports": [] }`,
+		"lib_conflict":  `{ "transform_declarations": { "t1": { "const": "lib_conflict.t1" } } }`,
+		"lib_near":      `{ "imports": [ "lib1", "lib_conflict" ], "transform_declarations": { "t1": { "const": "lib_near.t1" } } }`,
+		"lib_ambiguous": `{ "imports": [ "lib1", "lib_conflict" ], "transform_declarations": {} }`,
+		"lib_circular1": `{ "imports": [ "lib_circular2" ], "transform_declarations": {} }`,
+		"lib_circular2": `{ "imports": [ "lib1", "lib_circular1" ], "transform_declarations": {} }`,
+	}
+	resolver := schemahandler.ImportResolverFunc(func(ref string) ([]byte, error) {
+		if doc, found := docs[ref]; found {
+			return []byte(doc), nil
+		}
+		return nil, errors.New("not found")
+	})
+	for _, test := range []struct {
+		name     string
+		declJSON string
+		resolver schemahandler.ImportResolver
+		expected map[string]string
+		err      string
+	}{
+		{
+			name: "success",
+			declJSON: `{
+                "imports": [ "lib1", "lib3" ],
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "a": { "template": "t1" },
+                        "b": { "template": "t2" },
+                        "c": { "template": "t3" },
+                        "d": { "template": "t_overridden" }
+                    }},
+                    "t_overridden": { "const": "local.t_overridden" }
+                }
+            }`,
+			resolver: resolver,
+			expected: map[string]string{
+				"a":   "lib1.t1",
+				"b.x": "lib1.t1",
+				"c":   "lib3.t3",
+				"d":   "local.t_overridden",
+			},
+		},
+		{
+			name: "no resolver",
+			declJSON: `{
+                "imports": [ "lib1" ],
+                "transform_declarations": { "FINAL_OUTPUT": { "const": "x" } }
+            }`,
+			resolver: nil,
+			err:      `unable to resolve import 'lib1': no import resolver provided`,
+		},
+		{
+			name: "resolve failure",
+			declJSON: `{
+                "imports": [ "lib_missing" ],
+                "transform_declarations": { "FINAL_OUTPUT": { "const": "x" } }
+            }`,
+			resolver: resolver,
+			err:      `unable to resolve import 'lib_missing': not found`,
+		},
+		{
+			name: "imported document json schema validation failure",
+			declJSON: `{
+                "imports": [ "lib_invalid" ],
+                "transform_declarations": { "FINAL_OUTPUT": { "const": "x" } }
+            }`,
+			resolver: resolver,
+			err:      `schema 'lib_invalid' validation failed: (root): transform_declarations is required`,
+		},
+		{
+			name: "same name imported from different documents",
+			declJSON: `{
+                "imports": [ "lib1", "lib_conflict" ],
+                "transform_declarations": { "FINAL_OUTPUT": { "const": "x" } }
+            }`,
+			resolver: resolver,
+			err:      `'t1' is imported from both 'lib1' and 'lib_conflict'`,
+		},
+		{
+			name: "nearer import takes precedence",
+			declJSON: `{
+                "imports": [ "lib_near" ],
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "a": { "template": "t1" },
+                        "b": { "template": "t2" },
+                        "d": { "template": "t_overridden" }
+                    }}
+                }
+            }`,
+			resolver: resolver,
+			expected: map[string]string{
+				"a":   "lib_near.t1",
+				"b.x": "lib_near.t1",
+				"d":   "lib1.t_overridden",
+			},
+		},
+		{
+			name: "same name imported from different documents by an imported document",
+			declJSON: `{
+                "imports": [ "lib_ambiguous" ],
+                "transform_declarations": { "FINAL_OUTPUT": { "const": "x" } }
+            }`,
+			resolver: resolver,
+			err:      `'t1' is imported from both 'lib1' and 'lib_conflict'`,
+		},
+		{
+			name: "local template shadows conflicting imports",
+			declJSON: `{
+                "imports": [ "lib1", "lib_conflict" ],
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": { "a": { "template": "t1" } } },
+                    "t1": { "const": "local.t1" }
+                }
+            }`,
+			resolver: resolver,
+			expected: map[string]string{"a": "local.t1"},
+		},
+		{
+			name: "circular imports",
+			declJSON: `{
+                "imports": [ "lib_circular1" ],
+                "transform_declarations": { "FINAL_OUTPUT": { "const": "x" } }
+            }`,
+			resolver: resolver,
+			err:      `import circular dependency detected on 'lib_circular1': 'lib_circular1'->'lib_circular2'->'lib_circular1'`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			finalOutputDecl, err := ValidateTransformDeclarationsWithImports(
+				[]byte(test.declJSON), nil, nil, test.resolver)
+			switch {
+			case strs.IsStrNonBlank(test.err):
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Nil(t, finalOutputDecl)
+			default:
+				assert.NoError(t, err)
+				for path, expected := range test.expected {
+					decl := finalOutputDecl
+					for _, name := range strings.Split(path, ".") {
+						decl = decl.Object[name]
+					}
+					assert.Equal(t, kindConst, decl.kind)
+					assert.Equal(t, expected, *decl.Const)
+				}
+			}
+		})
+	}
+}
@@ -6,10 +6,20 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+
+	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/jf-tech/omniparser/decimal"
 )
 
+// defaultRounding is the rounding mode used when a 'decimal' typed decl has 'scale' but no 'rounding'.
+const defaultRounding = string(decimal.RoundHalfUp)
+
 // Note: isEmpty panics if v is nil.
 func isEmpty(v interface{}) bool {
+	if obj, ok := v.(*orderedObject); ok {
+		return obj.len() == 0
+	}
 	value := reflect.ValueOf(v)
 	switch value.Kind() {
 	case reflect.Slice, reflect.Map, reflect.Array, reflect.String, reflect.Chan:
@@ -18,6 +28,44 @@ func isEmpty(v interface{}) bool {
 	return false
 }
 
+// isTruthy tells whether a value is considered true, e.g. by a "switch" case's predicate. nil, false,
+// zero numbers, empty strings/slices/maps and strings parsed as false (such as "false" or "0") are
+// considered false; everything else is true.
+func isTruthy(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	if d, ok := v.(decimal.Decimal); ok {
+		return !d.IsZero()
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Bool:
+		return value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Uint() != 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() != 0
+	case reflect.String:
+		if b, err := strconv.ParseBool(value.String()); err == nil {
+			return b
+		}
+	}
+	return !isEmpty(v)
+}
+
+// isPredicateTruthy tells whether the value v of a predicate decl, such as a "switch" case's 'when', is
+// considered true. A field predicate tests whether its node exists and has a non-empty value, so its string
+// value, e.g. "0" or "F", isn't parsed as a boolean; all other values are subject to isTruthy.
+func isPredicateTruthy(decl *Decl, v interface{}) bool {
+	if s, ok := v.(string); ok && decl.kind == kindField {
+		return s != ""
+	}
+	return isTruthy(v)
+}
+
 type convFunc func(v interface{}) (interface{}, error)
 
 var convStrToInt convFunc = func(v interface{}) (interface{}, error) { return strconv.ParseInt(v.(string), 10, 64) }
@@ -27,10 +75,37 @@ var convIntToFloat convFunc = func(v interface{}) (interface{}, error) { return
 var convUintToFloat convFunc = func(v interface{}) (interface{}, error) { return float64(reflect.ValueOf(v).Uint()), nil }
 var convFloatToInt convFunc = func(v interface{}) (interface{}, error) { return int64(reflect.ValueOf(v).Float()), nil }
 var convToStr convFunc = func(v interface{}) (interface{}, error) { return fmt.Sprintf("%v", v), nil }
+var convStrToDecimal convFunc = func(v interface{}) (interface{}, error) { return decimal.Parse(v.(string)) }
+var convIntToDecimal convFunc = func(v interface{}) (interface{}, error) {
+	return decimal.FromInt64(reflect.ValueOf(v).Int()), nil
+}
+var convUintToDecimal convFunc = func(v interface{}) (interface{}, error) {
+	return decimal.FromUint64(reflect.ValueOf(v).Uint()), nil
+}
+var convFloatToDecimal convFunc = func(v interface{}) (interface{}, error) {
+	return decimal.FromFloat64(reflect.ValueOf(v).Float())
+}
 
 var errTypeConversionNotSupported = errors.New("type conversion not supported")
 
 func resultTypeConversion(v interface{}, resultType resultType) (interface{}, error) {
+	if d, ok := v.(decimal.Decimal); ok {
+		switch resultType {
+		case resultTypeInt:
+			i, err := d.Int64()
+			if err != nil {
+				return nil, err
+			}
+			return i, nil
+		case resultTypeFloat:
+			return d.Float64(), nil
+		case resultTypeString:
+			return d.String(), nil
+		case resultTypeDecimal:
+			return d, nil
+		}
+		return nil, errTypeConversionNotSupported
+	}
 	switch reflect.ValueOf(v).Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		switch resultType {
@@ -40,6 +115,8 @@ func resultTypeConversion(v interface{}, resultType resultType) (interface{}, er
 			return convIntToFloat(v)
 		case resultTypeString:
 			return convToStr(v)
+		case resultTypeDecimal:
+			return convIntToDecimal(v)
 		}
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		switch resultType {
@@ -49,6 +126,8 @@ func resultTypeConversion(v interface{}, resultType resultType) (interface{}, er
 			return convUintToFloat(v)
 		case resultTypeString:
 			return convToStr(v)
+		case resultTypeDecimal:
+			return convUintToDecimal(v)
 		}
 	case reflect.Float32, reflect.Float64:
 		switch resultType {
@@ -58,6 +137,8 @@ func resultTypeConversion(v interface{}, resultType resultType) (interface{}, er
 			return v, nil
 		case resultTypeString:
 			return convToStr(v)
+		case resultTypeDecimal:
+			return convFloatToDecimal(v)
 		}
 	case reflect.Bool:
 		switch resultType {
@@ -76,45 +157,52 @@ func resultTypeConversion(v interface{}, resultType resultType) (interface{}, er
 			return convStrToBool(v)
 		case resultTypeString:
 			return v, nil
+		case resultTypeDecimal:
+			return convStrToDecimal(v)
 		}
 	}
 	return nil, errTypeConversionNotSupported
 }
 
 func normalizeAndSaveValue(decl *Decl, v interface{}, save func(interface{})) error {
-	vv := reflect.ValueOf(v)
-	if vv.Kind() == reflect.String && !decl.NoTrim {
-		v = strings.TrimSpace(v.(string))
-		vv = reflect.ValueOf(v)
-	}
-	checkToSave := func(v interface{}) {
-		if v != nil && !isEmpty(v) {
-			save(v)
-			return
-		}
-		if !decl.KeepEmptyOrNull {
-			return
-		}
-		if v == nil || vv.Kind() != reflect.String {
-			save(v)
-			return
-		}
-		save(v)
-		return
+	if s, ok := v.(string); ok && !decl.NoTrim {
+		v = strings.TrimSpace(s)
 	}
 	if v == nil || decl.ResultType == nil {
-		checkToSave(v)
+		saveNormalizedValue(decl, v, save)
 		return nil
 	}
-	converted, err := resultTypeConversion(v, *decl.ResultType)
+	var converted interface{}
+	var err error
+	if *decl.ResultType == resultTypeDateTime {
+		// 'datetime' isn't one of the plain type casts of resultTypeConversion: the value is parsed with
+		// the decl's 'layouts' and then formatted with its 'format', possibly into an epoch number, so it
+		// needs the decl's 'datetime' settings, and 'coercion' doesn't apply to it.
+		converted, err = dateTimeConversion(v, decl.DateTime)
+	} else {
+		converted, err = resultTypeConversion(decl.coercion.coerce(v, *decl.ResultType), *decl.ResultType)
+	}
 	if err != nil {
-		return fmt.Errorf("unable to convert value '%v' to type '%s' on '%s', err: %s",
+		return declErr(decl.fqdn, err, "unable to convert value '%v' to type '%s' on '%s', err: %s",
 			v, *decl.ResultType, decl.fqdn, err.Error())
 	}
-	checkToSave(converted)
+	if d, ok := converted.(decimal.Decimal); ok && decl.Scale != nil {
+		converted = d.Round(*decl.Scale, decimal.RoundingMode(strs.StrPtrOrElse(decl.Rounding, defaultRounding)))
+	}
+	saveNormalizedValue(decl, converted, save)
 	return nil
 }
 
+// saveNormalizedValue saves a value that is already normalized according to the decl, e.g. the value
+// returned by ParseNode, unless it is null or empty and the decl doesn't have 'keep_empty_or_null' set.
+// Note a normalized value must not be normalized again: the conversion isn't idempotent for all types,
+// e.g. a 'datetime' value formatted with a custom 'format' no longer parses with the decl's 'layouts'.
+func saveNormalizedValue(decl *Decl, v interface{}, save func(interface{})) {
+	if (v != nil && !isEmpty(v)) || decl.KeepEmptyOrNull {
+		save(v)
+	}
+}
+
 func normalizeAndReturnValue(decl *Decl, v interface{}) (interface{}, error) {
 	var ret interface{}
 	err := normalizeAndSaveValue(decl, v, func(normalizedValue interface{}) {
@@ -4,9 +4,24 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/jf-tech/go-corelib/strs"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/jf-tech/omniparser/decimal"
 )
 
+func testDecimal(s string) decimal.Decimal {
+	d, err := decimal.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func testScale(scale int) *int {
+	return &scale
+}
+
 func TestIsEmpty(t *testing.T) {
 	for _, test := range []struct {
 		name     string
@@ -23,6 +38,16 @@ func TestIsEmpty(t *testing.T) {
 			v:        map[string]string{},
 			expected: true,
 		},
+		{
+			name:     "object empty",
+			v:        newOrderedObject(),
+			expected: true,
+		},
+		{
+			name:     "object non-empty",
+			v:        testOrderedObject("a", "b"),
+			expected: false,
+		},
 		{
 			name:     "slice nil",
 			v:        []interface{}(nil),
@@ -95,6 +120,39 @@ func TestIsEmpty(t *testing.T) {
 	}
 }
 
+func TestIsTruthy(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		v        interface{}
+		expected bool
+	}{
+		{name: "nil", v: nil, expected: false},
+		{name: "bool true", v: true, expected: true},
+		{name: "bool false", v: false, expected: false},
+		{name: "int non-zero", v: -1, expected: true},
+		{name: "int zero", v: int64(0), expected: false},
+		{name: "uint non-zero", v: uint8(1), expected: true},
+		{name: "uint zero", v: uint(0), expected: false},
+		{name: "float non-zero", v: 0.1, expected: true},
+		{name: "float zero", v: float32(0), expected: false},
+		{name: "string true", v: "TRUE", expected: true},
+		{name: "string false", v: "false", expected: false},
+		{name: "string 0", v: "0", expected: false},
+		{name: "string non-empty", v: "abc", expected: true},
+		{name: "string empty", v: "", expected: false},
+		{name: "map non-empty", v: map[string]interface{}{"a": 1}, expected: true},
+		{name: "map empty", v: map[string]interface{}{}, expected: false},
+		{name: "object non-empty", v: testOrderedObject("a", 1), expected: true},
+		{name: "object empty", v: newOrderedObject(), expected: false},
+		{name: "slice non-empty", v: []interface{}{1}, expected: true},
+		{name: "slice empty", v: []interface{}{}, expected: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, isTruthy(test.v))
+		})
+	}
+}
+
 func TestResultTypeConversion(t *testing.T) {
 	for _, test := range []struct {
 		name     string
@@ -236,6 +294,90 @@ func TestResultTypeConversion(t *testing.T) {
 			err:      ``,
 			expected: "1234567890",
 		},
+		{
+			name:     "int -> decimal",
+			v:        int32(-12),
+			typ:      resultTypeDecimal,
+			err:      ``,
+			expected: testDecimal("-12"),
+		},
+		{
+			name:     "uint -> decimal",
+			v:        uint64(18446744073709551615),
+			typ:      resultTypeDecimal,
+			err:      ``,
+			expected: testDecimal("18446744073709551615"),
+		},
+		{
+			name:     "float64 -> decimal",
+			v:        0.1,
+			typ:      resultTypeDecimal,
+			err:      ``,
+			expected: testDecimal("0.1"),
+		},
+		{
+			name:     "string -> decimal, failure",
+			v:        "1.2.3",
+			typ:      resultTypeDecimal,
+			err:      `'1.2.3' is not a valid decimal`,
+			expected: decimal.Decimal{},
+		},
+		{
+			name:     "string -> decimal, success",
+			v:        "12345678901234.990",
+			typ:      resultTypeDecimal,
+			err:      ``,
+			expected: testDecimal("12345678901234.990"),
+		},
+		{
+			name:     "bool -> decimal, failure",
+			v:        true,
+			typ:      resultTypeDecimal,
+			err:      errTypeConversionNotSupported.Error(),
+			expected: nil,
+		},
+		{
+			name:     "decimal -> int",
+			v:        testDecimal("-12.99"),
+			typ:      resultTypeInt,
+			err:      ``,
+			expected: int64(-12),
+		},
+		{
+			name:     "decimal -> int, overflow",
+			v:        testDecimal("92233720368547758070"),
+			typ:      resultTypeInt,
+			err:      `'92233720368547758070' is out of the int64 range`,
+			expected: nil,
+		},
+		{
+			name:     "decimal -> float",
+			v:        testDecimal("12.5"),
+			typ:      resultTypeFloat,
+			err:      ``,
+			expected: 12.5,
+		},
+		{
+			name:     "decimal -> string",
+			v:        testDecimal("12.50"),
+			typ:      resultTypeString,
+			err:      ``,
+			expected: "12.50",
+		},
+		{
+			name:     "decimal -> decimal",
+			v:        testDecimal("12.50"),
+			typ:      resultTypeDecimal,
+			err:      ``,
+			expected: testDecimal("12.50"),
+		},
+		{
+			name:     "decimal -> bool, failure",
+			v:        testDecimal("1"),
+			typ:      resultTypeBoolean,
+			err:      errTypeConversionNotSupported.Error(),
+			expected: nil,
+		},
 		{
 			name:     "map -> string, failure",
 			v:        map[string]string{},
@@ -334,6 +476,71 @@ func TestNormalizeAndSaveValue(t *testing.T) {
 			expectedSaveCalled: false,
 			expectedErr:        `unable to convert value 'abc' to type 'int' on 'test_fqdn', err: strconv.ParseInt: parsing "abc": invalid syntax`,
 		},
+		{
+			name:               "value is string converted to decimal without scale",
+			decl:               &Decl{ResultType: testResultType(resultTypeDecimal)},
+			value:              " 12.345 ",
+			expectedValue:      testDecimal("12.345"),
+			expectedSaveCalled: true,
+			expectedErr:        "",
+		},
+		{
+			name: "value is string converted to decimal with scale and default rounding",
+			decl: &Decl{
+				ResultType: testResultType(resultTypeDecimal),
+				Scale:      testScale(2),
+			},
+			value:              "12.345",
+			expectedValue:      testDecimal("12.35"),
+			expectedSaveCalled: true,
+			expectedErr:        "",
+		},
+		{
+			name: "value is string converted to decimal with scale and rounding",
+			decl: &Decl{
+				ResultType: testResultType(resultTypeDecimal),
+				Scale:      testScale(2),
+				Rounding:   strs.StrPtr("half_even"),
+			},
+			value:              "12.345",
+			expectedValue:      testDecimal("12.34"),
+			expectedSaveCalled: true,
+			expectedErr:        "",
+		},
+		{
+			name: "value is int converted to decimal with scale",
+			decl: &Decl{
+				ResultType: testResultType(resultTypeDecimal),
+				Scale:      testScale(2),
+			},
+			value:              12,
+			expectedValue:      testDecimal("12.00"),
+			expectedSaveCalled: true,
+			expectedErr:        "",
+		},
+		{
+			name: "value is string converted to datetime",
+			decl: &Decl{
+				ResultType: testResultType(resultTypeDateTime),
+				DateTime:   &DateTimeDecl{Layouts: []string{"01/02/2006"}, Format: strs.StrPtr("2006-01-02")},
+			},
+			value:              " 09/22/2020 ",
+			expectedValue:      "2020-09-22",
+			expectedSaveCalled: true,
+			expectedErr:        "",
+		},
+		{
+			name: "value is string but can't convert to datetime",
+			decl: &Decl{
+				ResultType: testResultType(resultTypeDateTime),
+				DateTime:   &DateTimeDecl{Layouts: []string{"01/02/2006"}},
+				fqdn:       "test_fqdn",
+			},
+			value:              "2020-09-22",
+			expectedValue:      nil,
+			expectedSaveCalled: false,
+			expectedErr:        `unable to convert value '2020-09-22' to type 'datetime' on 'test_fqdn', err: unable to parse '2020-09-22' with any of the layouts`,
+		},
 		{
 			name:               "value is empty slice and KeepEmptyOrNull false",
 			decl:               &Decl{},
@@ -0,0 +1,104 @@
+package transform
+
+import (
+	"sort"
+
+	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/jf-tech/omniparser/idr"
+	"github.com/jf-tech/omniparser/transformctx"
+)
+
+// VarDecl is the decl for a transform-scoped variable in the schema's `variables` section. The values
+// of the variables persist across records, and are read by "var" decls.
+type VarDecl struct {
+	// Initial is the value of the variable before any record is transformed. If not specified, the
+	// variable is initially null.
+	Initial *string `json:"initial,omitempty"`
+	// Update is the decl computing the new value of the variable from each record, before the record
+	// is transformed. If it yields null (e.g. its xpath doesn't match anything in the record), the
+	// variable keeps its current value.
+	Update *Decl `json:"update,omitempty"`
+
+	// Internal fields are computed at schema loading time.
+	name string
+}
+
+// validateVars validates the update decls of the variables and returns the variables sorted by name.
+func (ctx *validateCtx) validateVars() ([]*VarDecl, error) {
+	if len(ctx.Variables) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(ctx.Variables))
+	for name := range ctx.Variables {
+		names = append(names, name)
+	}
+	// validate the variables in a stable order so that errors are deterministic.
+	sort.Strings(names)
+	vars := make([]*VarDecl, 0, len(names))
+	for _, name := range names {
+		varDecl := ctx.Variables[name]
+		varDecl.name = name
+		if varDecl.Update != nil {
+			updateDecl, err := ctx.validateDecl(
+				strs.BuildFQDN("variables", strs.BuildFQDNWithEsc(name), "update"), varDecl.Update, nil)
+			if err != nil {
+				return nil, err
+			}
+			linkParent(updateDecl)
+			varDecl.Update = updateDecl
+		}
+		vars = append(vars, varDecl)
+	}
+	return vars, nil
+}
+
+// HasVars tells whether the schema, whose FINAL_OUTPUT decl is d, declares any variables. HasVars is nil
+// safe.
+func (d *Decl) HasVars() bool {
+	return d != nil && len(d.vars) > 0
+}
+
+// InitVars sets the variables, declared in the schema whose FINAL_OUTPUT decl is d, to their initial
+// values, unless they're already set, e.g. by a previous input stream of a multi-input transform or
+// seeded by the caller.
+func (d *Decl) InitVars(vars *transformctx.Vars) {
+	for _, v := range d.vars {
+		if _, found := vars.Get(v.name); found {
+			continue
+		}
+		if v.Initial != nil {
+			vars.Set(v.name, *v.Initial)
+		} else {
+			vars.Set(v.name, nil)
+		}
+	}
+}
+
+// UpdateVars computes the new values of the variables, declared in the schema whose FINAL_OUTPUT decl
+// is finalOutputDecl, from the record node n. All the update decls see the values of the variables before
+// the updates, i.e. the variables are updated simultaneously.
+func (p *parseCtx) UpdateVars(n *idr.Node, finalOutputDecl *Decl) error {
+	if !finalOutputDecl.HasVars() {
+		return nil
+	}
+	values := make([]interface{}, len(finalOutputDecl.vars))
+	for i, v := range finalOutputDecl.vars {
+		if v.Update == nil {
+			continue
+		}
+		value, err := p.ParseNode(n, v.Update)
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+	for i, v := range finalOutputDecl.vars {
+		if values[i] != nil {
+			p.transformCtx.Vars.Set(v.name, values[i])
+		}
+	}
+	// the cached results of the decls reading the variables are now stale.
+	p.transformCache = map[string]interface{}{}
+	return nil
+}
@@ -0,0 +1,127 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jf-tech/omniparser/transformctx"
+)
+
+func TestVars(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		declJSON string
+		seed     map[string]interface{}
+		err      string
+		expected []interface{}
+	}{
+		{
+			name: "success",
+			declJSON: `{
+                "variables": {
+                    "seq": { "initial": "0", "update": { "custom_func": { "name": "decimalAdd", "args": [ { "var": "seq" }, { "const": "1" } ] } } },
+                    "prev_seq": { "update": { "var": "seq" } },
+                    "b": { "update": { "xpath": "B" } },
+                    "missing": { "initial": "kept", "update": { "xpath": "X" } },
+                    "never_updated": { "initial": "init" }
+                },
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "seq": { "var": "seq", "type": "int" },
+                        "prev_seq": { "var": "prev_seq", "type": "int" },
+                        "b": { "var": "b" },
+                        "missing": { "var": "missing" },
+                        "never_updated": { "var": "never_updated" }
+                    }}
+                }
+            }`,
+			err: "",
+			expected: []interface{}{
+				testOrderedObject(
+					"seq", int64(1), "prev_seq", int64(0), "b", "b", "missing", "kept", "never_updated", "init"),
+				testOrderedObject(
+					"seq", int64(2), "prev_seq", int64(1), "b", "b", "missing", "kept", "never_updated", "init"),
+			},
+		},
+		{
+			name: "seeded",
+			declJSON: `{
+                "variables": {
+                    "seq": { "initial": "0", "update": { "custom_func": { "name": "decimalAdd", "args": [ { "var": "seq" }, { "const": "1" } ] } } }
+                },
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "var": "seq", "type": "int" }
+                }
+            }`,
+			seed:     map[string]interface{}{"seq": "41"},
+			err:      "",
+			expected: []interface{}{int64(42), int64(43)},
+		},
+		{
+			name: "non-existing variable",
+			declJSON: `{
+                "variables": { "v": { "initial": "0" } },
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": { "a": { "var": "v2" } } }
+                }
+            }`,
+			err: "'FINAL_OUTPUT.a' refers to non-existing variable 'v2'",
+		},
+		{
+			name: "invalid update",
+			declJSON: `{
+                "variables": { "v": { "update": { "template": "t" } } },
+                "transform_declarations": { "FINAL_OUTPUT": { "var": "v" } }
+            }`,
+			err: "'variables.v.update' contains non-existing template reference 't'",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			finalOutputDecl, err := ValidateTransformDeclarations([]byte(test.declJSON), testParseCtx().customFuncs, nil)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Nil(t, finalOutputDecl)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, finalOutputDecl.HasVars())
+			vars := transformctx.NewVars(test.seed)
+			finalOutputDecl.InitVars(vars)
+			for _, expected := range test.expected {
+				p := testParseCtx()
+				p.transformCtx.Vars = vars
+				// turn on the transform cache to verify the cached variable values are discarded after updates.
+				p.disableTransformCache = false
+				assert.NoError(t, p.UpdateVars(testNode(), finalOutputDecl))
+				value, err := p.ParseNode(testNode(), finalOutputDecl)
+				assert.NoError(t, err)
+				assert.Equal(t, expected, value)
+			}
+		})
+	}
+}
+
+func TestUpdateVars_Failure(t *testing.T) {
+	finalOutputDecl, err := ValidateTransformDeclarations([]byte(`{
+        "variables": { "v": { "update": { "xpath": "B", "type": "int" } } },
+        "transform_declarations": { "FINAL_OUTPUT": { "var": "v" } }
+    }`), testParseCtx().customFuncs, nil)
+	assert.NoError(t, err)
+	vars := transformctx.NewVars()
+	finalOutputDecl.InitVars(vars)
+	p := testParseCtx()
+	p.transformCtx.Vars = vars
+	err = p.UpdateVars(testNode(), finalOutputDecl)
+	assert.Error(t, err)
+	assert.Equal(t, `unable to convert value 'b' to type 'int' on 'variables.v.update', err: strconv.ParseInt: parsing "b": invalid syntax`, err.Error())
+	v, found := vars.Get("v")
+	assert.True(t, found)
+	assert.Nil(t, v)
+}
+
+func TestHasVars(t *testing.T) {
+	assert.False(t, (*Decl)(nil).HasVars())
+	assert.False(t, (&Decl{}).HasVars())
+}
@@ -18,13 +18,30 @@ const (
                     "oneOf": [
                         { "$ref": "#/definitions/const" },
                         { "$ref": "#/definitions/external" },
+                        { "$ref": "#/definitions/var" },
                         { "$ref": "#/definitions/field" },
                         { "$ref": "#/definitions/object" },
+                        { "$ref": "#/definitions/pivot" },
                         { "$ref": "#/definitions/custom_func" },
                         { "$ref": "#/definitions/custom_parse", "$comment": "Deprecated. Use custom_func." },
                         { "$ref": "#/definitions/array" },
-                        { "$ref": "#/definitions/template" }
+                        { "$ref": "#/definitions/unpivot" },
+                        { "$ref": "#/definitions/template" },
+                        { "$ref": "#/definitions/switch" }
                     ]
+                },
+                "FILTER": {
+                    "oneOf": [
+                        { "$ref": "#/definitions/const" },
+                        { "$ref": "#/definitions/external" },
+                        { "$ref": "#/definitions/var" },
+                        { "$ref": "#/definitions/field" },
+                        { "$ref": "#/definitions/custom_func" },
+                        { "$ref": "#/definitions/custom_parse", "$comment": "Deprecated. Use custom_func." },
+                        { "$ref": "#/definitions/template" },
+                        { "$ref": "#/definitions/switch" }
+                    ],
+                    "$comment": "target records on which FILTER yields a falsy value are skipped"
                 }
             },
             "patternProperties": {
@@ -32,21 +49,75 @@ const (
                     "oneOf": [
                         { "$ref": "#/definitions/const" },
                         { "$ref": "#/definitions/external" },
+                        { "$ref": "#/definitions/var" },
                         { "$ref": "#/definitions/field" },
                         { "$ref": "#/definitions/object" },
+                        { "$ref": "#/definitions/pivot" },
                         { "$ref": "#/definitions/custom_func" },
                         { "$ref": "#/definitions/custom_parse", "$comment": "Deprecated. Use custom_func." },
                         { "$ref": "#/definitions/array" },
-                        { "$ref": "#/definitions/template" }
+                        { "$ref": "#/definitions/unpivot" },
+                        { "$ref": "#/definitions/template" },
+                        { "$ref": "#/definitions/switch" }
                     ]
                 }
             },
             "required": [ "FINAL_OUTPUT" ],
             "additionalProperties": false
-        }
+        },
+        "imports": {
+            "type": "array",
+            "items": { "type": "string", "minLength": 1 }
+        },
+        "lookup_tables": {
+            "type": "object",
+            "additionalProperties": { "$ref": "#/definitions/lookup_table" }
+        },
+        "variables": {
+            "type": "object",
+            "additionalProperties": { "$ref": "#/definitions/variable" }
+        },
+        "coercion": { "$ref": "#/definitions/value_coercion" }
     },
     "required": [ "transform_declarations" ],
     "definitions": {
+        "lookup_table": {
+            "type": "object",
+            "properties": {
+                "map": {
+                    "type": "object",
+                    "additionalProperties": { "type": "string" }
+                },
+                "file": { "type": "string", "minLength": 1 },
+                "default": { "type": "string" },
+                "on_miss": { "type": "string", "enum": [ "empty", "key", "error" ] },
+                "_comment": { "$ref": "#/definitions/value_comment" }
+            },
+            "oneOf": [
+                { "required": [ "map" ] },
+                { "required": [ "file" ] }
+            ],
+            "additionalProperties": false
+        },
+        "variable": {
+            "type": "object",
+            "properties": {
+                "initial": { "type": "string" },
+                "update": {
+                    "oneOf": [
+                        { "$ref": "#/definitions/const" },
+                        { "$ref": "#/definitions/external" },
+                        { "$ref": "#/definitions/var" },
+                        { "$ref": "#/definitions/field" },
+                        { "$ref": "#/definitions/custom_func" },
+                        { "$ref": "#/definitions/template" },
+                        { "$ref": "#/definitions/switch" }
+                    ]
+                },
+                "_comment": { "$ref": "#/definitions/value_comment" }
+            },
+            "additionalProperties": false
+        },
         "value_comment": { "type": "string" },
         "value_no_trim": { "type": "boolean" },
         "value_ignore_error": { "type": "boolean" },
@@ -65,6 +136,11 @@ const (
             "minLength": 1,
             "$comment": "external can not be empty string"
         },
+        "value_var": {
+            "type": "string",
+            "minLength": 1,
+            "$comment": "var can not be empty string"
+        },
         "value_xpath": {
             "type": "string",
             "minLength": 1,
@@ -76,6 +152,7 @@ const (
                 "oneOf": [
                     { "$ref": "#/definitions/const" },
                     { "$ref": "#/definitions/external" },
+                    { "$ref": "#/definitions/var" },
                     { "$ref": "#/definitions/field" },
                     { "$ref": "#/definitions/custom_func" },
                     { "$ref": "#/definitions/custom_parse", "$comment": "Deprecated. Use custom_func." },
@@ -83,6 +160,11 @@ const (
                 ]
             }
         },
+        "value_explode": {
+            "type": "string",
+            "minLength": 1,
+            "$comment": "explode is only allowed on FINAL_OUTPUT"
+        },
         "value_template": {
             "type": "string",
             "minLength": 1,
@@ -95,12 +177,16 @@ const (
                     "oneOf": [
                         { "$ref": "#/definitions/const" },
                         { "$ref": "#/definitions/external" },
+                        { "$ref": "#/definitions/var" },
                         { "$ref": "#/definitions/field" },
                         { "$ref": "#/definitions/object" },
+                        { "$ref": "#/definitions/pivot" },
                         { "$ref": "#/definitions/custom_func" },
                         { "$ref": "#/definitions/custom_parse", "$comment": "Deprecated. Use custom_func." },
                         { "$ref": "#/definitions/array" },
-                        { "$ref": "#/definitions/template" }
+                        { "$ref": "#/definitions/unpivot" },
+                        { "$ref": "#/definitions/template" },
+                        { "$ref": "#/definitions/switch" }
                     ],
                     "$comment": "object's field can be any kind of transform"
                 }
@@ -117,11 +203,14 @@ const (
                         "oneOf": [
                             { "$ref": "#/definitions/const" },
                             { "$ref": "#/definitions/external" },
+                            { "$ref": "#/definitions/var" },
                             { "$ref": "#/definitions/field" },
                             { "$ref": "#/definitions/custom_func" },
                             { "$ref": "#/definitions/custom_parse", "$comment": "Deprecated. Use custom_func." },
                             { "$ref": "#/definitions/array" },
-                            { "$ref": "#/definitions/template" }
+                            { "$ref": "#/definitions/unpivot" },
+                            { "$ref": "#/definitions/template" },
+                            { "$ref": "#/definitions/switch" }
                         ]
                     },
                     "$comment": "args length can be 0"
@@ -136,22 +225,239 @@ const (
             "minLength": 1,
             "$comment": "custom_parse can not be empty string. Deprecated."
         },
+        "value_switch": {
+            "type": "array",
+            "minItems": 1,
+            "items": {
+                "type": "object",
+                "properties": {
+                    "when": {
+                        "oneOf": [
+                            { "$ref": "#/definitions/const" },
+                            { "$ref": "#/definitions/external" },
+                            { "$ref": "#/definitions/var" },
+                            { "$ref": "#/definitions/field" },
+                            { "$ref": "#/definitions/custom_func" },
+                            { "$ref": "#/definitions/custom_parse", "$comment": "Deprecated. Use custom_func." },
+                            { "$ref": "#/definitions/template" }
+                        ],
+                        "$comment": "when can be omitted in the last case, which then becomes the default case"
+                    },
+                    "then": {
+                        "oneOf": [
+                            { "$ref": "#/definitions/const" },
+                            { "$ref": "#/definitions/external" },
+                            { "$ref": "#/definitions/var" },
+                            { "$ref": "#/definitions/field" },
+                            { "$ref": "#/definitions/object" },
+                            { "$ref": "#/definitions/pivot" },
+                            { "$ref": "#/definitions/custom_func" },
+                            { "$ref": "#/definitions/custom_parse", "$comment": "Deprecated. Use custom_func." },
+                            { "$ref": "#/definitions/array" },
+                            { "$ref": "#/definitions/unpivot" },
+                            { "$ref": "#/definitions/template" },
+                            { "$ref": "#/definitions/switch" }
+                        ]
+                    },
+                    "_comment": { "$ref": "#/definitions/value_comment" }
+                },
+                "required": [ "then" ],
+                "additionalProperties": false
+            }
+        },
+        "value_pivot": {
+            "type": "object",
+            "properties": {
+                "xpath": { "$ref": "#/definitions/value_xpath" },
+                "key": {
+                    "oneOf": [
+                        { "$ref": "#/definitions/const" },
+                        { "$ref": "#/definitions/external" },
+                        { "$ref": "#/definitions/var" },
+                        { "$ref": "#/definitions/field" },
+                        { "$ref": "#/definitions/custom_func" },
+                        { "$ref": "#/definitions/custom_parse", "$comment": "Deprecated. Use custom_func." },
+                        { "$ref": "#/definitions/template" },
+                        { "$ref": "#/definitions/switch" }
+                    ],
+                    "$comment": "key is evaluated on each of the nodes selected by xpath, yielding the field name"
+                },
+                "value": {
+                    "oneOf": [
+                        { "$ref": "#/definitions/const" },
+                        { "$ref": "#/definitions/external" },
+                        { "$ref": "#/definitions/var" },
+                        { "$ref": "#/definitions/field" },
+                        { "$ref": "#/definitions/object" },
+                        { "$ref": "#/definitions/pivot" },
+                        { "$ref": "#/definitions/custom_func" },
+                        { "$ref": "#/definitions/custom_parse", "$comment": "Deprecated. Use custom_func." },
+                        { "$ref": "#/definitions/array" },
+                        { "$ref": "#/definitions/unpivot" },
+                        { "$ref": "#/definitions/template" },
+                        { "$ref": "#/definitions/switch" }
+                    ],
+                    "$comment": "value is evaluated on each of the nodes selected by xpath, yielding the field value"
+                },
+                "_comment": { "$ref": "#/definitions/value_comment" }
+            },
+            "required": [ "key", "value" ],
+            "additionalProperties": false
+        },
+        "value_unpivot": {
+            "type": "object",
+            "properties": {
+                "xpath": { "$ref": "#/definitions/value_xpath" },
+                "key_name": { "type": "string", "minLength": 1 },
+                "value_name": { "type": "string", "minLength": 1 },
+                "value": {
+                    "oneOf": [
+                        { "$ref": "#/definitions/const" },
+                        { "$ref": "#/definitions/external" },
+                        { "$ref": "#/definitions/var" },
+                        { "$ref": "#/definitions/field" },
+                        { "$ref": "#/definitions/object" },
+                        { "$ref": "#/definitions/pivot" },
+                        { "$ref": "#/definitions/custom_func" },
+                        { "$ref": "#/definitions/custom_parse", "$comment": "Deprecated. Use custom_func." },
+                        { "$ref": "#/definitions/array" },
+                        { "$ref": "#/definitions/unpivot" },
+                        { "$ref": "#/definitions/template" },
+                        { "$ref": "#/definitions/switch" }
+                    ],
+                    "$comment": "value is evaluated on each of the nodes selected by xpath; if omitted, the node's text is used"
+                },
+                "_comment": { "$ref": "#/definitions/value_comment" }
+            },
+            "additionalProperties": false
+        },
+        "value_default": {
+            "oneOf": [
+                { "type": "string", "$comment": "shorthand for a const" },
+                { "$ref": "#/definitions/const" },
+                { "$ref": "#/definitions/external" },
+                { "$ref": "#/definitions/var" },
+                { "$ref": "#/definitions/field" },
+                { "$ref": "#/definitions/object" },
+                { "$ref": "#/definitions/pivot" },
+                { "$ref": "#/definitions/custom_func" },
+                { "$ref": "#/definitions/custom_parse", "$comment": "Deprecated. Use custom_func." },
+                { "$ref": "#/definitions/array" },
+                { "$ref": "#/definitions/unpivot" },
+                { "$ref": "#/definitions/template" },
+                { "$ref": "#/definitions/switch" }
+            ],
+            "$comment": "default is used when the value resolves to nil or empty"
+        },
+        "value_validate": {
+            "type": "object",
+            "properties": {
+                "required": { "type": "boolean" },
+                "pattern": { "type": "string", "minLength": 1 },
+                "enum": {
+                    "type": "array",
+                    "items": { "type": "string" },
+                    "minItems": 1
+                },
+                "min_length": { "type": "integer", "minimum": 0 },
+                "max_length": { "type": "integer", "minimum": 0 },
+                "min": { "type": "number" },
+                "max": { "type": "number" },
+                "on_fail": { "type": "string", "enum": [ "error", "warn" ] },
+                "_comment": { "$ref": "#/definitions/value_comment" }
+            },
+            "additionalProperties": false
+        },
+        "value_on_error": {
+            "type": "string",
+            "enum": [ "fail", "null", "default", "skip" ],
+            "$comment": "on_error decides what to do when the value fails to be computed; 'default' requires default"
+        },
         "value_type": {
             "type": "string",
             "enum": [
                 "boolean",
                 "float",
                 "int",
-                "string"
+                "string",
+                "decimal",
+                "datetime"
             ]
         },
+        "value_datetime": {
+            "type": "object",
+            "properties": {
+                "layouts": {
+                    "type": "array",
+                    "items": { "type": "string", "minLength": 1 },
+                    "minItems": 1
+                },
+                "from_tz": { "type": "string", "minLength": 1 },
+                "to_tz": { "type": "string", "minLength": 1 },
+                "format": { "type": "string", "minLength": 1 }
+            },
+            "additionalProperties": false,
+            "$comment": "datetime is only allowed when type is datetime"
+        },
+        "value_coercion": {
+            "type": "object",
+            "properties": {
+                "thousands_separator": { "$ref": "#/definitions/value_separator" },
+                "decimal_separator": { "$ref": "#/definitions/value_separator" },
+                "trailing_sign": { "type": "boolean" },
+                "parenthesized_negative": { "type": "boolean" },
+                "true_values": {
+                    "type": "array",
+                    "items": { "type": "string", "minLength": 1 },
+                    "minItems": 1
+                },
+                "false_values": {
+                    "type": "array",
+                    "items": { "type": "string", "minLength": 1 },
+                    "minItems": 1
+                },
+                "_comment": { "$ref": "#/definitions/value_comment" }
+            },
+            "additionalProperties": false,
+            "$comment": "coercion is only allowed when type is int, float, decimal or boolean"
+        },
+        "value_separator": {
+            "type": "string",
+            "pattern": "^[^0-9+()-]$",
+            "$comment": "a separator is a single character other than digits, signs and parentheses"
+        },
+        "value_scale": {
+            "type": "integer",
+            "minimum": 0,
+            "maximum": 308,
+            "$comment": "scale is only allowed when type is decimal"
+        },
+        "value_rounding": {
+            "type": "string",
+            "enum": [
+                "half_up",
+                "half_even",
+                "down",
+                "up",
+                "floor",
+                "ceiling"
+            ],
+            "$comment": "rounding is only allowed when type is decimal and scale is specified"
+        },
         "const": {
             "type": "object",
             "properties": {
                 "const": { "$ref": "#/definitions/value_const" },
                 "type": { "$ref": "#/definitions/value_type" },
+                "scale": { "$ref": "#/definitions/value_scale" },
+                "rounding": { "$ref": "#/definitions/value_rounding" },
+                "datetime": { "$ref": "#/definitions/value_datetime" },
+                "coercion": { "$ref": "#/definitions/value_coercion" },
                 "no_trim": { "$ref": "#/definitions/value_no_trim" },
                 "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
+                "default": { "$ref": "#/definitions/value_default" },
+                "validate": { "$ref": "#/definitions/value_validate" },
+                "on_error": { "$ref": "#/definitions/value_on_error" },
                 "_comment": { "$ref": "#/definitions/value_comment" }
             },
             "required": [ "const" ],
@@ -162,21 +468,54 @@ const (
             "properties": {
                 "external": { "$ref": "#/definitions/value_external" },
                 "type": { "$ref": "#/definitions/value_type" },
+                "scale": { "$ref": "#/definitions/value_scale" },
+                "rounding": { "$ref": "#/definitions/value_rounding" },
+                "datetime": { "$ref": "#/definitions/value_datetime" },
+                "coercion": { "$ref": "#/definitions/value_coercion" },
                 "no_trim": { "$ref": "#/definitions/value_no_trim" },
                 "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
+                "default": { "$ref": "#/definitions/value_default" },
+                "validate": { "$ref": "#/definitions/value_validate" },
+                "on_error": { "$ref": "#/definitions/value_on_error" },
                 "_comment": { "$ref": "#/definitions/value_comment" }
             },
             "required": [ "external" ],
             "additionalProperties": false
         },
+        "var": {
+            "type": "object",
+            "properties": {
+                "var": { "$ref": "#/definitions/value_var" },
+                "type": { "$ref": "#/definitions/value_type" },
+                "scale": { "$ref": "#/definitions/value_scale" },
+                "rounding": { "$ref": "#/definitions/value_rounding" },
+                "datetime": { "$ref": "#/definitions/value_datetime" },
+                "coercion": { "$ref": "#/definitions/value_coercion" },
+                "no_trim": { "$ref": "#/definitions/value_no_trim" },
+                "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
+                "default": { "$ref": "#/definitions/value_default" },
+                "validate": { "$ref": "#/definitions/value_validate" },
+                "on_error": { "$ref": "#/definitions/value_on_error" },
+                "_comment": { "$ref": "#/definitions/value_comment" }
+            },
+            "required": [ "var" ],
+            "additionalProperties": false
+        },
         "field": {
             "type": "object",
             "properties": {
                 "xpath": { "$ref": "#/definitions/value_xpath" },
                 "xpath_dynamic": { "$ref": "#/definitions/value_xpath_dynamic" },
                 "type": { "$ref": "#/definitions/value_type" },
+                "scale": { "$ref": "#/definitions/value_scale" },
+                "rounding": { "$ref": "#/definitions/value_rounding" },
+                "datetime": { "$ref": "#/definitions/value_datetime" },
+                "coercion": { "$ref": "#/definitions/value_coercion" },
                 "no_trim": { "$ref": "#/definitions/value_no_trim" },
                 "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
+                "default": { "$ref": "#/definitions/value_default" },
+                "validate": { "$ref": "#/definitions/value_validate" },
+                "on_error": { "$ref": "#/definitions/value_on_error" },
                 "_comment": { "$ref": "#/definitions/value_comment" }
             },
             "additionalProperties": false
@@ -187,7 +526,11 @@ const (
                 "xpath": { "$ref": "#/definitions/value_xpath" },
                 "xpath_dynamic": { "$ref": "#/definitions/value_xpath_dynamic" },
                 "object": { "$ref": "#/definitions/value_object" },
+                "explode": { "$ref": "#/definitions/value_explode" },
                 "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
+                "default": { "$ref": "#/definitions/value_default" },
+                "validate": { "$ref": "#/definitions/value_validate" },
+                "on_error": { "$ref": "#/definitions/value_on_error" },
                 "_comment": { "$ref": "#/definitions/value_comment" }
             },
             "required": [ "object" ],
@@ -202,27 +545,85 @@ const (
                         "oneOf": [
                             { "$ref": "#/definitions/const" },
                             { "$ref": "#/definitions/external" },
+                            { "$ref": "#/definitions/var" },
                             { "$ref": "#/definitions/field" },
                             { "$ref": "#/definitions/object" },
+                            { "$ref": "#/definitions/pivot" },
                             { "$ref": "#/definitions/custom_func" },
                             { "$ref": "#/definitions/custom_parse", "$comment": "Deprecated. Use custom_func." },
-                            { "$ref": "#/definitions/template" }
+                            { "$ref": "#/definitions/template" },
+                            { "$ref": "#/definitions/switch" }
                         ],
                         "$comment": "array's element can be any kind of transform, except array. might support in the future, but not now"
                     }
                 },
+                "sort_by": {
+                    "type": "array",
+                    "items": {
+                        "type": "object",
+                        "properties": {
+                            "field": { "type": "string", "minLength": 1 },
+                            "type": { "type": "string", "enum": [ "string", "number", "datetime" ] },
+                            "order": { "type": "string", "enum": [ "asc", "desc" ] }
+                        },
+                        "additionalProperties": false
+                    },
+                    "minItems": 1
+                },
+                "distinct_by": {
+                    "type": "array",
+                    "items": { "type": "string", "minLength": 1 },
+                    "minItems": 1
+                },
+                "max_items": { "type": "integer", "minimum": 0 },
                 "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
+                "default": { "$ref": "#/definitions/value_default" },
+                "validate": { "$ref": "#/definitions/value_validate" },
+                "on_error": { "$ref": "#/definitions/value_on_error" },
                 "_comment": { "$ref": "#/definitions/value_comment" }
             },
             "required": [ "array" ],
             "additionalProperties": false
         },
+        "pivot": {
+            "type": "object",
+            "properties": {
+                "xpath": { "$ref": "#/definitions/value_xpath" },
+                "xpath_dynamic": { "$ref": "#/definitions/value_xpath_dynamic" },
+                "pivot": { "$ref": "#/definitions/value_pivot" },
+                "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
+                "default": { "$ref": "#/definitions/value_default" },
+                "validate": { "$ref": "#/definitions/value_validate" },
+                "on_error": { "$ref": "#/definitions/value_on_error" },
+                "_comment": { "$ref": "#/definitions/value_comment" }
+            },
+            "required": [ "pivot" ],
+            "additionalProperties": false
+        },
+        "unpivot": {
+            "type": "object",
+            "properties": {
+                "xpath": { "$ref": "#/definitions/value_xpath" },
+                "xpath_dynamic": { "$ref": "#/definitions/value_xpath_dynamic" },
+                "unpivot": { "$ref": "#/definitions/value_unpivot" },
+                "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
+                "default": { "$ref": "#/definitions/value_default" },
+                "validate": { "$ref": "#/definitions/value_validate" },
+                "on_error": { "$ref": "#/definitions/value_on_error" },
+                "_comment": { "$ref": "#/definitions/value_comment" }
+            },
+            "required": [ "unpivot" ],
+            "additionalProperties": false
+        },
         "template": {
             "type": "object",
             "properties": {
                 "xpath": { "$ref": "#/definitions/value_xpath" },
                 "xpath_dynamic": { "$ref": "#/definitions/value_xpath_dynamic" },
                 "template": { "$ref": "#/definitions/value_template" },
+                "default": { "$ref": "#/definitions/value_default" },
+                "validate": { "$ref": "#/definitions/value_validate" },
+                "on_error": { "$ref": "#/definitions/value_on_error" },
                 "_comment": { "$ref": "#/definitions/value_comment" }
             },
             "required": [ "template" ],
@@ -235,13 +636,41 @@ const (
                 "xpath_dynamic": { "$ref": "#/definitions/value_xpath_dynamic" },
                 "custom_func": { "$ref": "#/definitions/value_custom_func" },
                 "type": { "$ref": "#/definitions/value_type" },
+                "scale": { "$ref": "#/definitions/value_scale" },
+                "rounding": { "$ref": "#/definitions/value_rounding" },
+                "datetime": { "$ref": "#/definitions/value_datetime" },
+                "coercion": { "$ref": "#/definitions/value_coercion" },
                 "no_trim": { "$ref": "#/definitions/value_no_trim" },
                 "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
+                "default": { "$ref": "#/definitions/value_default" },
+                "validate": { "$ref": "#/definitions/value_validate" },
+                "on_error": { "$ref": "#/definitions/value_on_error" },
                 "_comment": { "$ref": "#/definitions/value_comment" }
             },
             "required": [ "custom_func" ],
             "additionalProperties": false
         },
+        "switch": {
+            "type": "object",
+            "properties": {
+                "xpath": { "$ref": "#/definitions/value_xpath" },
+                "xpath_dynamic": { "$ref": "#/definitions/value_xpath_dynamic" },
+                "switch": { "$ref": "#/definitions/value_switch" },
+                "type": { "$ref": "#/definitions/value_type" },
+                "scale": { "$ref": "#/definitions/value_scale" },
+                "rounding": { "$ref": "#/definitions/value_rounding" },
+                "datetime": { "$ref": "#/definitions/value_datetime" },
+                "coercion": { "$ref": "#/definitions/value_coercion" },
+                "no_trim": { "$ref": "#/definitions/value_no_trim" },
+                "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
+                "default": { "$ref": "#/definitions/value_default" },
+                "validate": { "$ref": "#/definitions/value_validate" },
+                "on_error": { "$ref": "#/definitions/value_on_error" },
+                "_comment": { "$ref": "#/definitions/value_comment" }
+            },
+            "required": [ "switch" ],
+            "additionalProperties": false
+        },
         "custom_parse": {
             "type": "object",
             "properties": {
@@ -249,8 +678,15 @@ const (
                 "xpath_dynamic": { "$ref": "#/definitions/value_xpath_dynamic" },
                 "custom_parse": { "$ref": "#/definitions/value_custom_parse" },
                 "type": { "$ref": "#/definitions/value_type" },
+                "scale": { "$ref": "#/definitions/value_scale" },
+                "rounding": { "$ref": "#/definitions/value_rounding" },
+                "datetime": { "$ref": "#/definitions/value_datetime" },
+                "coercion": { "$ref": "#/definitions/value_coercion" },
                 "no_trim": { "$ref": "#/definitions/value_no_trim" },
                 "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
+                "default": { "$ref": "#/definitions/value_default" },
+                "validate": { "$ref": "#/definitions/value_validate" },
+                "on_error": { "$ref": "#/definitions/value_on_error" },
                 "_comment": { "$ref": "#/definitions/value_comment" }
             },
             "required": [ "custom_parse" ],
@@ -0,0 +1,9 @@
+package validation
+
+import "strings"
+
+// JSONSchemaTransformDeclarationsImport is the json schema for documents imported by an omni.2.1 schema
+// (via its top level "imports"). It is the same as JSONSchemaTransformDeclarations, except that
+// 'FINAL_OUTPUT' isn't required, given imported documents are mostly template libraries.
+var JSONSchemaTransformDeclarationsImport = strings.Replace(
+	JSONSchemaTransformDeclarations, `"required": [ "FINAL_OUTPUT" ],`, "", 1)
@@ -173,6 +173,28 @@ recycle:
 	recycle(n)
 }
 
+// CopyTree makes a deep copy of the entire IDR tree 'n' is in (i.e. starting from the root of
+// 'n'), and returns the root of the new tree as well as the copy of 'n' in the new tree. Caller
+// owns the new tree and should release it by calling RemoveAndReleaseTree on the new root.
+// Note FormatSpecific of each node is shallow copied.
+func CopyTree(n *Node) (root *Node, copied *Node) {
+	for root = n; root.Parent != nil; root = root.Parent {
+	}
+	var copyNode func(src *Node) *Node
+	copyNode = func(src *Node) *Node {
+		dest := CreateNode(src.Type, src.Data)
+		dest.FormatSpecific = src.FormatSpecific
+		if src == n {
+			copied = dest
+		}
+		for c := src.FirstChild; c != nil; c = c.NextSibling {
+			AddChild(dest, copyNode(c))
+		}
+		return dest
+	}
+	return copyNode(root), copied
+}
+
 func recycle(n *Node) {
 	if !nodeCaching {
 		return
@@ -200,6 +200,27 @@ func TestRemoveAndReleaseTree(t *testing.T) {
 	})
 }
 
+func TestCopyTree(t *testing.T) {
+	setupTestNodeCaching(testNodeCachingOn)
+	tt := newTestTree(t, testTreeXML)
+	root, copied := CopyTree(tt.elemC3)
+	checkPointersInTree(t, root)
+	assert.True(t, root != tt.root)
+	assert.True(t, copied != tt.elemC3)
+	assert.Equal(t, JSONify1(tt.root), JSONify1(root))
+	assert.Equal(t, JSONify1(tt.elemC3), JSONify1(copied))
+	assert.True(t, root == rootOf(copied))
+	assert.Equal(t, tt.elemC3.FormatSpecific, copied.FormatSpecific)
+	// Releasing the copy doesn't affect the original tree.
+	RemoveAndReleaseTree(root)
+	checkPointersInTree(t, tt.root)
+	assert.Equal(t, "textC3", tt.elemC3.InnerText())
+
+	root, copied = CopyTree(tt.root)
+	assert.True(t, root == copied)
+	assert.Equal(t, JSONify1(tt.root), JSONify1(root))
+}
+
 // go test -bench=. -benchmem -benchtime=30s
 // BenchmarkCreateAndDestroyTree_NoCache-4     	20421031	      1736 ns/op	    1872 B/op	      19 allocs/op
 // BenchmarkCreateAndDestroyTree_WithCache-4   	22744428	      1559 ns/op	     144 B/op	       1 allocs/op
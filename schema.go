@@ -1,7 +1,9 @@
 package omniparser
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -31,8 +33,19 @@ import (
 // within the same go routine.
 type Schema interface {
 	NewTransform(name string, input io.Reader, ctx *transformctx.Ctx) (Transform, error)
+	NewTransformWithContext(
+		c context.Context, name string, input io.Reader, ctx *transformctx.Ctx) (Transform, error)
+	NewMultiInputTransform(inputs []NamedInput, ctx *transformctx.Ctx) (Transform, error)
 	Header() header.Header
 	Content() []byte
+	// Describe returns a read-only view of the schema, such as the parsed transform declaration tree
+	// and file declaration, for tooling to introspect the schema. The returned view must not be modified.
+	// errs.ErrDescribeNotSupported is returned if the schema's handler doesn't support introspection.
+	Describe() (*schemahandler.Description, error)
+	// OutputJSONSchema returns a JSON Schema (draft-07) describing the records produced by the schema's
+	// transforms, i.e. the contract of the output. errs.ErrOutputJSONSchemaNotSupported is returned if the
+	// schema's handler doesn't support it.
+	OutputJSONSchema() ([]byte, error)
 }
 
 type schema struct {
@@ -48,6 +61,10 @@ type Extension struct {
 	CreateSchemaHandler       schemahandler.CreateFunc
 	CreateSchemaHandlerParams interface{}
 	CustomFuncs               customfuncs.CustomFuncs
+	// ImportResolver resolves schema imports. Optional. If an extension doesn't specify one, the first
+	// ImportResolver specified among all the extensions passed to NewSchema is used, thus an extension
+	// with only ImportResolver specified can be used to enable imports for the builtin schema handlers.
+	ImportResolver schemahandler.ImportResolver
 }
 
 var (
@@ -82,16 +99,28 @@ func NewSchema(name string, schemaReader io.Reader, exts ...Extension) (Schema,
 
 	allExts := append([]Extension(nil), exts...)
 	allExts = append(allExts, defaultExt)
+	var importResolver schemahandler.ImportResolver
+	for _, ext := range allExts {
+		if ext.ImportResolver != nil {
+			importResolver = ext.ImportResolver
+			break
+		}
+	}
 	for _, ext := range allExts {
 		if ext.CreateSchemaHandler == nil {
 			continue
 		}
+		extImportResolver := ext.ImportResolver
+		if extImportResolver == nil {
+			extImportResolver = importResolver
+		}
 		handler, err := ext.CreateSchemaHandler(&schemahandler.CreateCtx{
-			Name:         name,
-			Header:       h,
-			Content:      content,
-			CustomFuncs:  ext.CustomFuncs,
-			CreateParams: ext.CreateSchemaHandlerParams,
+			Name:           name,
+			Header:         h,
+			Content:        content,
+			CustomFuncs:    ext.CustomFuncs,
+			CreateParams:   ext.CreateSchemaHandlerParams,
+			ImportResolver: extImportResolver,
 		})
 		if err == errs.ErrSchemaNotSupported {
 			continue
@@ -112,14 +141,7 @@ func NewSchema(name string, schemaReader io.Reader, exts ...Extension) (Schema,
 
 // NewTransform creates and returns an instance of Transform for a given input stream.
 func (s *schema) NewTransform(name string, input io.Reader, ctx *transformctx.Ctx) (Transform, error) {
-	br, err := ios.StripBOM(s.header.ParserSettings.WrapEncoding(input))
-	if err != nil {
-		return nil, err
-	}
-	if ctx.InputName != name {
-		ctx.InputName = name
-	}
-	ingester, err := s.handler.NewIngester(ctx, br)
+	ingester, err := s.newIngester(name, input, ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -129,7 +151,110 @@ func (s *schema) NewTransform(name string, input io.Reader, ctx *transformctx.Ct
 	if ctx.CtxAwareErr == nil {
 		ctx.CtxAwareErr = ingester
 	}
-	return &transform{ingester: ingester}, nil
+	return &transform{ingester: ingester, ctx: ctx}, nil
+}
+
+// NamedInput is an input stream along with its name.
+type NamedInput struct {
+	Name  string
+	Input io.Reader
+}
+
+// NewMultiInputTransform creates and returns an instance of Transform that processes multiple input
+// streams sequentially, in the given order, as one logical stream, e.g. a batch split into many files.
+// Each input stream is ingested as if it were a separate NewTransform call (so each of the input
+// streams must be complete by itself, e.g. with its own header or envelope), but they all share the
+// same Transform: the error policy, the stats, etc. ctx.InputName is updated to the name of the input
+// stream being processed, so after each Transform.Read call, ctx.InputName tells which input stream the
+// returned record or error came from.
+func (s *schema) NewMultiInputTransform(inputs []NamedInput, ctx *transformctx.Ctx) (Transform, error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("no input")
+	}
+	// Unless caller specified a way to do context aware error formatting, use the Ingester of the
+	// input stream being processed.
+	ownCtxAwareErr := ctx.CtxAwareErr == nil
+	newIngester := func(input NamedInput) (schemahandler.Ingester, error) {
+		// Set InputName upfront so even if the input stream fails to start, caller can tell which one.
+		ctx.InputName = input.Name
+		ingester, err := s.newIngester(input.Name, input.Input, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if ownCtxAwareErr {
+			ctx.CtxAwareErr = ingester
+		}
+		return ingester, nil
+	}
+	ingester, err := newIngester(inputs[0])
+	if err != nil {
+		return nil, err
+	}
+	return &transform{
+		ingester:    ingester,
+		ctx:         ctx,
+		nextInputs:  inputs[1:],
+		newIngester: newIngester,
+	}, nil
+}
+
+func (s *schema) newIngester(name string, input io.Reader, ctx *transformctx.Ctx) (schemahandler.Ingester, error) {
+	// If caller already specified a StatsRecorder (e.g. to aggregate stats across multiple transforms),
+	// use it; otherwise create one for this transform.
+	if ctx != nil && ctx.StatsRecorder == nil {
+		ctx.StatsRecorder = transformctx.NewStatsRecorder()
+	}
+	if ctx != nil && ctx.Vars == nil {
+		ctx.Vars = transformctx.NewVars()
+	}
+	input = &statsReader{stats: ctx.Stats(), r: input}
+	br, err := ios.StripBOM(s.header.ParserSettings.WrapEncoding(input))
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Context != nil {
+		br = &ctxAwareReader{ctx: ctx, r: br}
+	}
+	if ctx.InputName != name {
+		ctx.InputName = name
+	}
+	return s.handler.NewIngester(ctx, br)
+}
+
+// NewTransformWithContext creates and returns an instance of Transform for a given input stream, with
+// its operation bound to context c: once c is canceled or its deadline exceeded, the input stream
+// reading, the transform of the current record (including any running javascript custom funcs) are
+// interrupted, and all subsequent Transform.Read calls return c.Err().
+func (s *schema) NewTransformWithContext(
+	c context.Context, name string, input io.Reader, ctx *transformctx.Ctx) (Transform, error) {
+	ctx.Context = c
+	return s.NewTransform(name, input, ctx)
+}
+
+// ctxAwareReader fails all the reads on the underlying input stream once the transform operation
+// is canceled, so that FormatReader's reading loops can be stopped promptly.
+type ctxAwareReader struct {
+	ctx *transformctx.Ctx
+	r   io.Reader
+}
+
+func (r *ctxAwareReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// statsReader counts the bytes consumed from the underlying input stream.
+type statsReader struct {
+	stats *transformctx.StatsRecorder
+	r     io.Reader
+}
+
+func (r *statsReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.stats.AddBytesConsumed(n)
+	return n, err
 }
 
 // Header returns the schema header.
@@ -141,3 +266,21 @@ func (s *schema) Header() header.Header {
 func (s *schema) Content() []byte {
 	return s.content
 }
+
+// Describe returns a read-only view of the schema.
+func (s *schema) Describe() (*schemahandler.Description, error) {
+	describer, ok := s.handler.(schemahandler.Describer)
+	if !ok {
+		return nil, errs.ErrDescribeNotSupported
+	}
+	return describer.Describe(), nil
+}
+
+// OutputJSONSchema returns a JSON Schema describing the records produced by the schema's transforms.
+func (s *schema) OutputJSONSchema() ([]byte, error) {
+	generator, ok := s.handler.(schemahandler.OutputJSONSchemaGenerator)
+	if !ok {
+		return nil, errs.ErrOutputJSONSchemaNotSupported
+	}
+	return generator.OutputJSONSchema()
+}
@@ -0,0 +1,343 @@
+package omniparser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jf-tech/omniparser/schemahandler"
+)
+
+// SchemaFileSuffix is the file name suffix of schema files recognized by NewDirSchemaSource. It is also
+// stripped from a schema file's base name to form the schema's name in a SchemaRegistry.
+const SchemaFileSuffix = ".schema.json"
+
+// SchemaFile describes a schema file in a SchemaSource.
+type SchemaFile struct {
+	// Path uniquely identifies a schema file in its SchemaSource. Always '/' separated.
+	Path string
+	// ModTime is the last modification time of the schema file. SchemaRegistry reloads a schema
+	// file whenever its ModTime changes.
+	ModTime time.Time
+}
+
+// SchemaSource is an abstraction of where SchemaRegistry loads schema files from, e.g. a local
+// directory, an embedded file set, a remote repository, etc.
+type SchemaSource interface {
+	// List returns all the schema files currently in the source.
+	List() ([]SchemaFile, error)
+	// Open opens a schema file, identified by its Path, in the source for reading.
+	Open(path string) (io.ReadCloser, error)
+}
+
+// SchemaDependencySource is an optional interface a SchemaSource can implement, so that SchemaRegistry
+// also reloads a schema file whenever any of the files it depends on changes, i.e. the documents the
+// schema imports and the lookup table files it loads, both identified by the references passed to the
+// ImportResolver.
+type SchemaDependencySource interface {
+	// DependencyModTime returns the last modification time of the file identified by ref.
+	DependencyModTime(ref string) (time.Time, error)
+}
+
+type dirSchemaSource struct {
+	dir string
+}
+
+// NewDirSchemaSource creates a SchemaSource that contains all the files with SchemaFileSuffix in
+// directory dir and its sub-directories. The SchemaSource is also a schemahandler.ImportResolver and a
+// SchemaDependencySource, both of which take a reference as a '/' separated file path relative to dir.
+func NewDirSchemaSource(dir string) SchemaSource {
+	return &dirSchemaSource{dir: dir}
+}
+
+// List returns all the schema files in the directory and its sub-directories.
+func (s *dirSchemaSource) List() ([]SchemaFile, error) {
+	var files []SchemaFile
+	err := filepath.Walk(s.dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), SchemaFileSuffix) {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, SchemaFile{Path: filepath.ToSlash(rel), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Open opens a schema file in the directory for reading.
+func (s *dirSchemaSource) Open(p string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.dir, filepath.FromSlash(p)))
+}
+
+// Resolve reads the file ref, relative to the directory, for a schema's imports or lookup tables.
+func (s *dirSchemaSource) Resolve(ref string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(s.dir, filepath.FromSlash(ref)))
+}
+
+// DependencyModTime returns the last modification time of the file ref, relative to the directory.
+func (s *dirSchemaSource) DependencyModTime(ref string) (time.Time, error) {
+	info, err := os.Stat(filepath.Join(s.dir, filepath.FromSlash(ref)))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// SchemaKey identifies a schema in a SchemaRegistry.
+type SchemaKey struct {
+	// Name is the schema file's base name without SchemaFileSuffix.
+	Name string
+	// Version is the schema's 'parser_settings.version'.
+	Version string
+}
+
+type registryEntry struct {
+	file   SchemaFile
+	deps   map[string]time.Time // the ModTimes of the files the schema depends on, keyed by reference.
+	key    SchemaKey
+	schema Schema
+}
+
+// SchemaRegistry loads, validates and caches all the schemas from a SchemaSource, and keeps them
+// up-to-date by reloading the schema files that are added, changed or removed in the source.
+// SchemaRegistry is safe for concurrent use. Reloading never disrupts in-flight transforms: a
+// Transform keeps using the Schema it was created from, even if the Schema has since been reloaded
+// or removed from the registry.
+type SchemaRegistry struct {
+	source SchemaSource
+	exts   []Extension
+
+	reloadMtx sync.Mutex // serializes Reload calls.
+	mtx       sync.RWMutex
+	entries   map[string]*registryEntry // keyed by SchemaFile.Path
+	schemas   map[SchemaKey]*registryEntry
+	errs      map[string]error // keyed by SchemaFile.Path
+}
+
+// NewSchemaRegistry creates a SchemaRegistry and does an initial load of all the schemas from source.
+// Extensions exts are used for creating all the schemas, see NewSchema for details. If none of exts
+// specifies an ImportResolver and source is a schemahandler.ImportResolver, e.g. the one created by
+// NewDirSchemaSource, source is used for resolving the schemas' imports and lookup table files. Only the
+// failure of listing the source fails NewSchemaRegistry; a schema file failing to load is instead
+// reported by Errors.
+func NewSchemaRegistry(source SchemaSource, exts ...Extension) (*SchemaRegistry, error) {
+	r := &SchemaRegistry{
+		source:  source,
+		exts:    exts,
+		entries: map[string]*registryEntry{},
+		schemas: map[SchemaKey]*registryEntry{},
+		errs:    map[string]error{},
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload checks the source for schema file changes: new schema files are loaded, changed ones (including
+// the ones whose dependencies changed, see SchemaDependencySource) are reloaded, and removed ones are
+// dropped from the registry. If a changed schema file fails to reload, its previously loaded schema stays
+// in the registry, and the failure is reported by Errors. Reload only returns an error if it fails to list
+// the source.
+func (r *SchemaRegistry) Reload() error {
+	r.reloadMtx.Lock()
+	defer r.reloadMtx.Unlock()
+	files, err := r.source.List()
+	if err != nil {
+		return fmt.Errorf("unable to list schema source: %s", err.Error())
+	}
+	// Loading schemas can be slow, so do it without holding mtx to not block Get calls. Note r.entries
+	// is only ever written by Reload, which is serialized by reloadMtx, so it is safe to read it here.
+	entries := map[string]*registryEntry{}
+	errs := map[string]error{}
+	for _, file := range files {
+		entry, found := r.entries[file.Path]
+		if found && entry.file.ModTime.Equal(file.ModTime) && !r.depsChanged(entry) {
+			entries[file.Path] = entry
+			continue
+		}
+		newEntry, err := r.load(file)
+		if err != nil {
+			errs[file.Path] = err
+			if found {
+				entries[file.Path] = entry
+			}
+			continue
+		}
+		entries[file.Path] = newEntry
+	}
+
+	// Multiple schema files (in different sub-directories) can end up with the same key. Resolve the
+	// conflicts deterministically: the one with the lexically smallest path wins. The losing entries are
+	// kept in entries (but not in schemas) so that they aren't reloaded by every Reload until they change;
+	// the conflicts are reported by every Reload, as they are re-detected from entries every time.
+	paths := make([]string, 0, len(entries))
+	for p := range entries {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	schemas := map[SchemaKey]*registryEntry{}
+	for _, p := range paths {
+		entry := entries[p]
+		if existing, found := schemas[entry.key]; found {
+			// a failure to reload the schema file takes precedence, as it is more actionable.
+			if _, failed := errs[p]; !failed {
+				errs[p] = fmt.Errorf("schema '%s' has the same name '%s' and version '%s' as schema '%s'",
+					p, entry.key.Name, entry.key.Version, existing.file.Path)
+			}
+			continue
+		}
+		schemas[entry.key] = entry
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.entries, r.schemas, r.errs = entries, schemas, errs
+	return nil
+}
+
+func (r *SchemaRegistry) load(file SchemaFile) (*registryEntry, error) {
+	rc, err := r.source.Open(file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open schema '%s': %s", file.Path, err.Error())
+	}
+	defer rc.Close()
+	deps := map[string]time.Time{}
+	schema, err := NewSchema(file.Path, rc, r.extsTrackingDeps(deps)...)
+	if err != nil {
+		return nil, err
+	}
+	return &registryEntry{
+		file: file,
+		deps: deps,
+		key: SchemaKey{
+			Name:    strings.TrimSuffix(path.Base(file.Path), SchemaFileSuffix),
+			Version: schema.Header().ParserSettings.Version,
+		},
+		schema: schema,
+	}, nil
+}
+
+// extsTrackingDeps returns r.exts, with their ImportResolvers (or the source, if it's an ImportResolver
+// and none of r.exts specifies one) wrapped to record into deps the ModTimes of all the references they
+// resolve.
+func (r *SchemaRegistry) extsTrackingDeps(deps map[string]time.Time) []Extension {
+	track := func(resolver schemahandler.ImportResolver) schemahandler.ImportResolver {
+		return schemahandler.ImportResolverFunc(func(ref string) ([]byte, error) {
+			// get the ModTime before reading the file, so a change in between is picked up by the next Reload.
+			deps[ref] = r.dependencyModTime(ref)
+			return resolver.Resolve(ref)
+		})
+	}
+	exts := make([]Extension, 0, len(r.exts)+1)
+	hasResolver := false
+	for _, ext := range r.exts {
+		if ext.ImportResolver != nil {
+			ext.ImportResolver = track(ext.ImportResolver)
+			hasResolver = true
+		}
+		exts = append(exts, ext)
+	}
+	if resolver, ok := r.source.(schemahandler.ImportResolver); ok && !hasResolver {
+		exts = append(exts, Extension{ImportResolver: track(resolver)})
+	}
+	return exts
+}
+
+// depsChanged tells whether any of the files the schema of entry depends on has changed since it's loaded.
+func (r *SchemaRegistry) depsChanged(entry *registryEntry) bool {
+	for ref, modTime := range entry.deps {
+		if !r.dependencyModTime(ref).Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencyModTime returns the ModTime of the file ref, if the source is a SchemaDependencySource. A file
+// whose ModTime can't be determined has the zero ModTime, so it's considered unchanged until it can be.
+func (r *SchemaRegistry) dependencyModTime(ref string) time.Time {
+	depSource, ok := r.source.(SchemaDependencySource)
+	if !ok {
+		return time.Time{}
+	}
+	modTime, err := depSource.DependencyModTime(ref)
+	if err != nil {
+		return time.Time{}
+	}
+	return modTime
+}
+
+// Watch calls Reload every interval until c is canceled. Watch blocks, so typically caller runs it
+// in a separate goroutine. Reload errors, if any, are passed to onErr, if onErr isn't nil.
+func (r *SchemaRegistry) Watch(c context.Context, interval time.Duration, onErr func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reload(); err != nil && onErr != nil {
+				onErr(err)
+			}
+		}
+	}
+}
+
+// Get returns the schema identified by name and version.
+func (r *SchemaRegistry) Get(name, version string) (Schema, bool) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	entry, found := r.schemas[SchemaKey{Name: name, Version: version}]
+	if !found {
+		return nil, false
+	}
+	return entry.schema, true
+}
+
+// Keys returns the keys of all the schemas in the registry, sorted by name then version.
+func (r *SchemaRegistry) Keys() []SchemaKey {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	keys := make([]SchemaKey, 0, len(r.schemas))
+	for key := range r.schemas {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Name != keys[j].Name {
+			return keys[i].Name < keys[j].Name
+		}
+		return keys[i].Version < keys[j].Version
+	})
+	return keys
+}
+
+// Errors returns the errors of the schema files that failed to load during the last Reload, keyed
+// by the schema files' paths.
+func (r *SchemaRegistry) Errors() map[string]error {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	errs := make(map[string]error, len(r.errs))
+	for p, err := range r.errs {
+		errs[p] = err
+	}
+	return errs
+}
@@ -0,0 +1,262 @@
+package omniparser
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jf-tech/omniparser/transformctx"
+)
+
+const (
+	testRegistrySchemaV1 = `{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+		"transform_declarations": { "FINAL_OUTPUT": { "const": "v1" } }
+	}`
+	testRegistrySchemaV2 = `{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+		"transform_declarations": { "FINAL_OUTPUT": { "const": "v2" } }
+	}`
+	testRegistrySchemaInvalid = `{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+		"transform_declarations": { "FINAL_OUTPUT": { "template": "non-existing" } }
+	}`
+)
+
+func writeTestSchemaFile(t *testing.T, dir, p, content string, modTime time.Time) {
+	fullPath := filepath.Join(dir, filepath.FromSlash(p))
+	assert.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+	assert.NoError(t, ioutil.WriteFile(fullPath, []byte(content), 0644))
+	assert.NoError(t, os.Chtimes(fullPath, modTime, modTime))
+}
+
+func transformOne(t *testing.T, s Schema) string {
+	tfm, err := s.NewTransform("test-input", strings.NewReader(`{}`), &transformctx.Ctx{})
+	assert.NoError(t, err)
+	b, err := tfm.Read()
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func TestSchemaRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "omniparser_registry_test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	t0 := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	writeTestSchemaFile(t, dir, "a.schema.json", testRegistrySchemaV1, t0)
+	writeTestSchemaFile(t, dir, "sub/b.schema.json", testRegistrySchemaV1, t0)
+	writeTestSchemaFile(t, dir, "sub/c.schema.json", testRegistrySchemaInvalid, t0)
+	writeTestSchemaFile(t, dir, "sub/d.json", "not a schema file", t0)
+
+	r, err := NewSchemaRegistry(NewDirSchemaSource(dir))
+	assert.NoError(t, err)
+	assert.Equal(t,
+		[]SchemaKey{{Name: "a", Version: "omni.2.1"}, {Name: "b", Version: "omni.2.1"}},
+		r.Keys())
+	errs := r.Errors()
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t,
+		`schema 'sub/c.schema.json' 'transform_declarations' validation failed: 'FINAL_OUTPUT' contains non-existing template reference 'non-existing'`,
+		errs["sub/c.schema.json"].Error())
+	s, found := r.Get("a", "omni.2.1")
+	assert.True(t, found)
+	assert.Equal(t, `"v1"`, transformOne(t, s))
+	s, found = r.Get("a", "omni.2.0")
+	assert.False(t, found)
+	assert.Nil(t, s)
+
+	// A transform created before reload isn't disrupted by the reload.
+	oldA, _ := r.Get("a", "omni.2.1")
+	inFlight, err := oldA.NewTransform("test-input", strings.NewReader(`{}`), &transformctx.Ctx{})
+	assert.NoError(t, err)
+
+	t1 := t0.Add(time.Hour)
+	// a changed.
+	writeTestSchemaFile(t, dir, "a.schema.json", testRegistrySchemaV2, t1)
+	// b changed to invalid: previous version is retained.
+	writeTestSchemaFile(t, dir, "sub/b.schema.json", testRegistrySchemaInvalid, t1)
+	// c fixed.
+	writeTestSchemaFile(t, dir, "sub/c.schema.json", testRegistrySchemaV1, t1)
+	// e added, but with the same name and version as a.
+	writeTestSchemaFile(t, dir, "sub2/a.schema.json", testRegistrySchemaV1, t1)
+	assert.NoError(t, r.Reload())
+	assert.Equal(t,
+		[]SchemaKey{
+			{Name: "a", Version: "omni.2.1"}, {Name: "b", Version: "omni.2.1"}, {Name: "c", Version: "omni.2.1"},
+		},
+		r.Keys())
+	s, _ = r.Get("a", "omni.2.1")
+	assert.Equal(t, `"v2"`, transformOne(t, s))
+	s, _ = r.Get("b", "omni.2.1")
+	assert.Equal(t, `"v1"`, transformOne(t, s))
+	s, _ = r.Get("c", "omni.2.1")
+	assert.Equal(t, `"v1"`, transformOne(t, s))
+	errs = r.Errors()
+	assert.Equal(t, 2, len(errs))
+	assert.Equal(t,
+		`schema 'sub/b.schema.json' 'transform_declarations' validation failed: 'FINAL_OUTPUT' contains non-existing template reference 'non-existing'`,
+		errs["sub/b.schema.json"].Error())
+	assert.Equal(t,
+		`schema 'sub2/a.schema.json' has the same name 'a' and version 'omni.2.1' as schema 'a.schema.json'`,
+		errs["sub2/a.schema.json"].Error())
+	b, err := inFlight.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `"v1"`, string(b))
+
+	// removal.
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "sub")))
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "sub2")))
+	assert.NoError(t, r.Reload())
+	assert.Equal(t, []SchemaKey{{Name: "a", Version: "omni.2.1"}}, r.Keys())
+	assert.Equal(t, 0, len(r.Errors()))
+}
+
+type testSchemaSource struct {
+	files   []SchemaFile
+	listErr error
+	openErr error
+	opens   int
+}
+
+func (s *testSchemaSource) List() ([]SchemaFile, error) {
+	return s.files, s.listErr
+}
+
+func (s *testSchemaSource) Open(_ string) (io.ReadCloser, error) {
+	s.opens++
+	if s.openErr != nil {
+		return nil, s.openErr
+	}
+	return ioutil.NopCloser(strings.NewReader(testRegistrySchemaV1)), nil
+}
+
+func TestSchemaRegistry_SourceFailures(t *testing.T) {
+	r, err := NewSchemaRegistry(&testSchemaSource{listErr: errors.New("list failure")})
+	assert.Error(t, err)
+	assert.Equal(t, "unable to list schema source: list failure", err.Error())
+	assert.Nil(t, r)
+
+	r, err = NewSchemaRegistry(&testSchemaSource{
+		files:   []SchemaFile{{Path: "x.schema.json"}},
+		openErr: errors.New("open failure"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(r.Keys()))
+	assert.Equal(t, "unable to open schema 'x.schema.json': open failure", r.Errors()["x.schema.json"].Error())
+
+	r, err = NewSchemaRegistry(NewDirSchemaSource(filepath.Join(os.TempDir(), "omniparser_non_existing_dir")))
+	assert.Error(t, err)
+	assert.Nil(t, r)
+}
+
+func TestSchemaRegistry_DuplicateKeys(t *testing.T) {
+	t0 := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	src := &testSchemaSource{
+		files: []SchemaFile{{Path: "y/a.schema.json", ModTime: t0}, {Path: "x/a.schema.json", ModTime: t0}},
+	}
+	r, err := NewSchemaRegistry(src)
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, r.Reload())
+		assert.Equal(t, []SchemaKey{{Name: "a", Version: "omni.2.1"}}, r.Keys())
+		errs := r.Errors()
+		assert.Equal(t, 1, len(errs))
+		assert.Equal(t,
+			`schema 'y/a.schema.json' has the same name 'a' and version 'omni.2.1' as schema 'x/a.schema.json'`,
+			errs["y/a.schema.json"].Error())
+	}
+	// the conflicting schema file isn't reloaded unless it changes.
+	assert.Equal(t, 2, src.opens)
+
+	// once the conflict is resolved, the schema file is still not reloaded, unless it changes.
+	src.files = src.files[:1]
+	assert.NoError(t, r.Reload())
+	assert.Equal(t, []SchemaKey{{Name: "a", Version: "omni.2.1"}}, r.Keys())
+	assert.Equal(t, 0, len(r.Errors()))
+	assert.Equal(t, 2, src.opens)
+}
+
+func TestSchemaRegistry_Dependencies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "omniparser_registry_test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	t0 := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	writeTestSchemaFile(t, dir, "a.schema.json", `{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+		"imports": [ "lib/common.json" ],
+		"lookup_tables": { "codes": { "file": "lib/codes.csv" } },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "object": {
+				"t": { "template": "common_t" },
+				"l": { "custom_func": { "name": "lookup", "args": [ { "const": "codes" }, { "const": "k" } ] } }
+			}}
+		}
+	}`, t0)
+	writeTestSchemaFile(t, dir, "lib/common.json",
+		`{ "transform_declarations": { "common_t": { "const": "t1" } } }`, t0)
+	writeTestSchemaFile(t, dir, "lib/codes.csv", "k,l1\n", t0)
+
+	r, err := NewSchemaRegistry(NewDirSchemaSource(dir))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]error{}, r.Errors())
+	s, found := r.Get("a", "omni.2.1")
+	assert.True(t, found)
+	assert.Equal(t, `{"t":"t1","l":"l1"}`, transformOne(t, s))
+
+	// nothing changed, schema isn't reloaded.
+	assert.NoError(t, r.Reload())
+	s2, _ := r.Get("a", "omni.2.1")
+	assert.True(t, s == s2)
+
+	// imported document changed.
+	t1 := t0.Add(time.Hour)
+	writeTestSchemaFile(t, dir, "lib/common.json",
+		`{ "transform_declarations": { "common_t": { "const": "t2" } } }`, t1)
+	assert.NoError(t, r.Reload())
+	s, _ = r.Get("a", "omni.2.1")
+	assert.Equal(t, `{"t":"t2","l":"l1"}`, transformOne(t, s))
+
+	// lookup table file changed.
+	writeTestSchemaFile(t, dir, "lib/codes.csv", "k,l2\n", t1)
+	assert.NoError(t, r.Reload())
+	s, _ = r.Get("a", "omni.2.1")
+	assert.Equal(t, `{"t":"t2","l":"l2"}`, transformOne(t, s))
+
+	// imported document removed: the schema fails to reload, and its previous version is retained.
+	assert.NoError(t, os.Remove(filepath.Join(dir, "lib", "common.json")))
+	assert.NoError(t, r.Reload())
+	s, _ = r.Get("a", "omni.2.1")
+	assert.Equal(t, `{"t":"t2","l":"l2"}`, transformOne(t, s))
+	assert.Equal(t, 1, len(r.Errors()))
+}
+
+func TestSchemaRegistry_Watch(t *testing.T) {
+	src := &testSchemaSource{}
+	r, err := NewSchemaRegistry(src)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(r.Keys()))
+	src.listErr = errors.New("list failure")
+	c, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Watch(c, time.Millisecond, func(err error) {
+			select {
+			case errCh <- err:
+			default:
+			}
+		})
+	}()
+	assert.Equal(t, "unable to list schema source: list failure", (<-errCh).Error())
+	cancel()
+	<-done
+}
@@ -1,9 +1,13 @@
 package omniparser
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/jf-tech/go-corelib/testlib"
@@ -11,9 +15,11 @@ import (
 
 	"github.com/jf-tech/omniparser/customfuncs"
 	"github.com/jf-tech/omniparser/errs"
+	csv2 "github.com/jf-tech/omniparser/extensions/omniv21/fileformat/flatfile/csv"
 	"github.com/jf-tech/omniparser/header"
 	"github.com/jf-tech/omniparser/schemahandler"
 	"github.com/jf-tech/omniparser/transformctx"
+	"github.com/jf-tech/omniparser/validation"
 )
 
 func TestNewSchema(t *testing.T) {
@@ -165,3 +171,940 @@ func TestSchema_NewTransform_NameAndCtxAwareErrOverwrite(t *testing.T) {
 	assert.Equal(t, h, s.Header())
 	assert.Equal(t, "test schema content", string(s.Content()))
 }
+
+func TestSchema_NewTransformWithContext(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b/*", "object": { "c": { "xpath": "c" } } }
+		}
+	}`))
+	assert.NoError(t, err)
+	c, cancel := context.WithCancel(context.Background())
+	ctx := &transformctx.Ctx{}
+	tfm, err := s.NewTransformWithContext(
+		c, "test-input", strings.NewReader(`{"a":{"b":[{"c":"1"},{"c":"2"},{"c":"3"}]}}`), ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, c, ctx.Context)
+	b, err := tfm.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"c":"1"}`, string(b))
+	cancel()
+	b, err = tfm.Read()
+	assert.Error(t, err)
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, b)
+	// once canceled, all subsequent Read calls get the same error.
+	b, err = tfm.Read()
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, b)
+}
+
+func TestCtxAwareReader(t *testing.T) {
+	c, cancel := context.WithCancel(context.Background())
+	r := &ctxAwareReader{ctx: &transformctx.Ctx{Context: c}, r: strings.NewReader("abcdef")}
+	buf := make([]byte, 3)
+	n, err := r.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", string(buf[:n]))
+	cancel()
+	n, err = r.Read(buf)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestSchema_NewTransform_Parallel(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b/*", "object": {
+				"batch": { "xpath": "../../batch" },
+				"id": { "xpath": "id", "type": "int" },
+				"double": { "custom_func": {
+					"name": "javascript",
+					"args": [ { "const": "id * 2" }, { "const": "id" }, { "xpath": "id", "type": "int" } ]
+				}}
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	var input strings.Builder
+	input.WriteString(`{"a":{"batch":"b1","b":[`)
+	for i := 0; i < 200; i++ {
+		if i > 0 {
+			input.WriteString(",")
+		}
+		if i%50 == 49 {
+			input.WriteString(fmt.Sprintf(`{"id":"bad%d"}`, i))
+			continue
+		}
+		input.WriteString(fmt.Sprintf(`{"id":"%d"}`, i))
+	}
+	input.WriteString(`]}}`)
+	readAll := func(workers int) []string {
+		tfm, err := s.NewTransform(
+			"test-input", strings.NewReader(input.String()), &transformctx.Ctx{Workers: workers})
+		assert.NoError(t, err)
+		var results []string
+		for {
+			b, err := tfm.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				assert.True(t, errs.IsErrTransformFailed(err))
+				results = append(results, err.Error())
+				continue
+			}
+			raw, err := tfm.RawRecord()
+			assert.NoError(t, err)
+			results = append(results, string(b)+" "+raw.Checksum())
+		}
+		return results
+	}
+	expected := readAll(0)
+	assert.Equal(t, 200, len(expected))
+	assert.Equal(t, `{"batch":"b1","id":0,"double":0} c75e290d-2f13-3d42-8691-9684b8dc903f`, expected[0])
+	assert.Equal(t, expected, readAll(8))
+}
+
+func TestSchema_NewTransform_TransformError(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"id": { "xpath": "id", "type": "int" }
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	input := "<a>\n<b><id>1</id></b>\n<b><id>x</id></b>\n<b><id>3</id></b>\n</a>"
+	for _, workers := range []int{0, 4} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			tfm, err := s.NewTransform("test-input", strings.NewReader(input), &transformctx.Ctx{Workers: workers})
+			assert.NoError(t, err)
+			b, err := tfm.Read()
+			assert.NoError(t, err)
+			assert.Equal(t, `{"id":1}`, string(b))
+			b, err = tfm.Read()
+			assert.Nil(t, b)
+			assert.True(t, errs.IsErrTransformFailed(err))
+			assert.Equal(t,
+				`input 'test-input' near line 3: fail to transform. err: unable to convert value 'x' to type 'int' on 'FINAL_OUTPUT.id', err: strconv.ParseInt: parsing "x": invalid syntax`,
+				err.Error())
+			var transformErr *errs.TransformError
+			assert.True(t, errors.As(err, &transformErr))
+			assert.Equal(t, errs.Position{Input: "test-input", Line: 3}, transformErr.Position)
+			assert.Equal(t, "FINAL_OUTPUT.id", transformErr.DeclFQDN)
+			assert.True(t, errors.Is(err, strconv.ErrSyntax))
+			b, err = tfm.Read()
+			assert.NoError(t, err)
+			assert.Equal(t, `{"id":3}`, string(b))
+		})
+	}
+}
+
+func TestNewSchema_Imports(t *testing.T) {
+	schemaContent := `{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"imports": [ "lib" ],
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "template": "id_template" }
+		}
+	}`
+	resolver := schemahandler.ImportResolverFunc(func(ref string) ([]byte, error) {
+		if ref != "lib" {
+			return nil, errors.New("not found")
+		}
+		return []byte(`{ "transform_declarations": {
+			"id_template": { "object": { "id": { "xpath": "id", "type": "int" } } }
+		}}`), nil
+	})
+
+	_, err := NewSchema("test-schema", strings.NewReader(schemaContent))
+	assert.Error(t, err)
+	assert.Equal(t,
+		`schema 'test-schema' 'transform_declarations' validation failed: unable to resolve import 'lib': no import resolver provided`,
+		err.Error())
+
+	s, err := NewSchema("test-schema", strings.NewReader(schemaContent), Extension{ImportResolver: resolver})
+	assert.NoError(t, err)
+	tfm, err := s.NewTransform("test-input", strings.NewReader("<a><b><id>1</id></b></a>"), &transformctx.Ctx{})
+	assert.NoError(t, err)
+	b, err := tfm.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1}`, string(b))
+}
+
+func TestSchema_NewTransform_LookupTables(t *testing.T) {
+	resolver := schemahandler.ImportResolverFunc(func(ref string) ([]byte, error) {
+		if ref != "lookups/carriers.csv" {
+			return nil, errors.New("not found")
+		}
+		return []byte("UPSN,UPS\nFDEG,FedEx Ground\n"), nil
+	})
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"lookup_tables": {
+			"transport_modes": { "map": { "A": "Air", "M": "Motor" }, "default": "Other" },
+			"carriers": { "file": "lookups/carriers.csv", "on_miss": "key" },
+			"statuses": { "map": { "D": "Delivered" }, "on_miss": "error" }
+		},
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"mode": { "custom_func": { "name": "lookup", "args": [
+					{ "const": "transport_modes" }, { "xpath": "mode" }
+				]}},
+				"carrier": { "custom_func": { "name": "lookup", "args": [
+					{ "const": "carriers" }, { "xpath": "scac" }
+				]}},
+				"status": { "custom_func": { "name": "lookup", "args": [
+					{ "const": "statuses" }, { "xpath": "status" }
+				]}}
+			}}
+		}
+	}`), Extension{ImportResolver: resolver})
+	assert.NoError(t, err)
+	tfm, err := s.NewTransform("test-input", strings.NewReader(
+		"<a>"+
+			"<b><mode>A</mode><scac>UPSN</scac><status>D</status></b>"+
+			"<b><mode>R</mode><scac>XXXX</scac><status>D</status></b>"+
+			"<b><mode>M</mode><scac>FDEG</scac><status>?</status></b>"+
+			"</a>"), &transformctx.Ctx{})
+	assert.NoError(t, err)
+	var records []string
+	for {
+		b, err := tfm.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			records = append(records, err.Error())
+			continue
+		}
+		records = append(records, string(b))
+	}
+	assert.Equal(t, []string{
+		`{"mode":"Air","carrier":"UPS","status":"Delivered"}`,
+		`{"mode":"Other","carrier":"XXXX","status":"Delivered"}`,
+		`input 'test-input' near line 1: fail to transform. err: 'FINAL_OUTPUT.status.custom_func(lookup)' ` +
+			`failed: key '?' not found in lookup table 'statuses'`,
+	}, records)
+}
+
+func TestSchema_Describe(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "csv2" },
+		"file_declaration": {
+			"delimiter": ",",
+			"records": [ { "columns": [ { "name": "id" }, { "name": "price" } ] } ]
+		},
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "object": {
+				"id": { "xpath": "id" },
+				"price": { "template": "price_template" }
+			}},
+			"price_template": { "xpath": "price", "type": "float" }
+		}
+	}`))
+	assert.NoError(t, err)
+	desc, err := s.Describe()
+	assert.NoError(t, err)
+	assert.Equal(t, "object", desc.FinalOutput.Kind)
+	assert.Equal(t, 2, len(desc.FinalOutput.Children))
+	price := desc.FinalOutput.Children[1]
+	assert.Equal(t, "price", price.Name)
+	assert.Equal(t, "FINAL_OUTPUT.price", price.FQDN)
+	assert.Equal(t, "field", price.Kind)
+	assert.Equal(t, "price", price.XPath)
+	assert.Equal(t, "float", price.ResultType)
+	fileDecl, ok := desc.FileDeclaration.(*csv2.FileDecl)
+	assert.True(t, ok)
+	assert.Equal(t, ",", fileDecl.Delimiter)
+
+	s = &schema{handler: testSchemaHandler{}}
+	desc, err = s.Describe()
+	assert.Equal(t, errs.ErrDescribeNotSupported, err)
+	assert.Nil(t, desc)
+}
+
+func TestSchema_OutputJSONSchema(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"id": { "xpath": "id", "type": "int" },
+				"name": { "xpath": "name" },
+				"tags": { "array": [ { "xpath": "tag" } ] },
+				"note": { "xpath": "note", "keep_empty_or_null": true }
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	outputJSONSchema, err := s.OutputJSONSchema()
+	assert.NoError(t, err)
+	tfm, err := s.NewTransform("test-input", strings.NewReader(
+		"<a><b><id>1</id><name>n</name><tag>x</tag><tag>y</tag><note>z</note></b><b><id>2</id><note/></b></a>"),
+		&transformctx.Ctx{})
+	assert.NoError(t, err)
+	records := 0
+	for {
+		b, err := tfm.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		assert.NoError(t, validation.SchemaValidate("output", b, string(outputJSONSchema)))
+		records++
+	}
+	assert.Equal(t, 2, records)
+	assert.Error(t, validation.SchemaValidate("output", []byte(`{"id":"1"}`), string(outputJSONSchema)))
+
+	s = &schema{handler: testSchemaHandler{}}
+	outputJSONSchema, err = s.OutputJSONSchema()
+	assert.Equal(t, errs.ErrOutputJSONSchemaNotSupported, err)
+	assert.Nil(t, outputJSONSchema)
+}
+
+func TestSchema_NewTransform_Decimal(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"amount": { "xpath": "amount", "type": "decimal" },
+				"rounded": { "xpath": "amount", "type": "decimal", "scale": 1, "rounding": "down" },
+				"total": { "type": "decimal", "custom_func": {
+					"name": "decimalMul",
+					"args": [ { "xpath": "amount" }, { "xpath": "qty" } ]
+				}}
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	tfm, err := s.NewTransform("test-input", strings.NewReader(
+		"<a><b><amount>12345678901234.99</amount><qty>3</qty></b></a>"), &transformctx.Ctx{})
+	assert.NoError(t, err)
+	b, err := tfm.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"amount":12345678901234.99,"rounded":12345678901234.9,"total":37037036703704.97}`, string(b))
+	_, err = tfm.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSchema_NewTransform_DateTime(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"date": { "xpath": "date", "type": "datetime", "datetime": { "layouts": [ "20060102" ] } },
+				"formatted": { "xpath": "date", "type": "datetime",
+					"datetime": { "layouts": [ "20060102" ], "format": "01/02/2006" } },
+				"epoch": { "xpath": "date", "type": "datetime",
+					"datetime": { "layouts": [ "20060102" ], "to_tz": "UTC", "format": "epoch" } },
+				"smart": { "xpath": "date", "type": "datetime" },
+				"history": { "array": [
+					{ "xpath": "history/date", "type": "datetime",
+						"datetime": { "layouts": [ "20060102" ], "to_tz": "UTC", "format": "epoch_millis" } }
+				]}
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	tfm, err := s.NewTransform("test-input", strings.NewReader(
+		"<a><b><date>20200922</date><history><date>20200101</date><date>20200102</date></history></b></a>"),
+		&transformctx.Ctx{})
+	assert.NoError(t, err)
+	b, err := tfm.Read()
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`{"date":"2020-09-22T00:00:00","formatted":"09/22/2020","epoch":1600732800,"smart":"2020-09-22T00:00:00",`+
+			`"history":[1577836800000,1577923200000]}`,
+		string(b))
+	_, err = tfm.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSchema_NewTransform_Stats(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"id": { "xpath": "id", "type": "int" }
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	input := "<a>\n<b><id>1</id></b>\n<b><id>x</id></b>\n<b><id>3</id></b>\n</a>"
+	for _, workers := range []int{0, 4} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			tfm, err := s.NewTransform("test-input", strings.NewReader(input), &transformctx.Ctx{Workers: workers})
+			assert.NoError(t, err)
+			for {
+				_, err := tfm.Read()
+				if err == io.EOF {
+					break
+				}
+			}
+			stats := tfm.Stats()
+			assert.Equal(t, int64(2), stats.RecordsEmitted)
+			assert.Equal(t, map[string]int64{transformctx.FailureClassTransform: 1}, stats.RecordsFailed)
+			assert.Equal(t, int64(len(input)), stats.BytesConsumed)
+			assert.True(t, stats.ReadDuration > 0)
+			assert.True(t, stats.TransformDuration > 0)
+			assert.True(t, stats.MarshalDuration > 0)
+		})
+	}
+	// A caller specified StatsRecorder is used, e.g. to aggregate stats across multiple transforms.
+	recorder := transformctx.NewStatsRecorder()
+	for i := 0; i < 2; i++ {
+		tfm, err := s.NewTransform(
+			"test-input", strings.NewReader(input), &transformctx.Ctx{StatsRecorder: recorder})
+		assert.NoError(t, err)
+		for {
+			if _, err := tfm.Read(); err == io.EOF {
+				break
+			}
+		}
+	}
+	assert.Equal(t, int64(4), recorder.Stats().RecordsEmitted)
+}
+
+func TestSchema_NewTransform_Default(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"qty": { "xpath": "qty", "type": "int", "default": "1" },
+				"name": { "xpath": "name", "default": { "xpath": "id" } },
+				"tags": { "array": [ { "xpath": "tag" } ], "default": { "array": [ { "const": "none" } ] } }
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	tfm, err := s.NewTransform("test-input", strings.NewReader(
+		"<a><b><id>1</id><qty>3</qty><name>n1</name><tag>t</tag></b><b><id>2</id></b></a>"), &transformctx.Ctx{})
+	assert.NoError(t, err)
+	for _, expected := range []string{
+		`{"qty":3,"name":"n1","tags":["t"]}`,
+		`{"qty":1,"name":"2","tags":["none"]}`,
+	} {
+		b, err := tfm.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(b))
+	}
+	_, err = tfm.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSchema_NewTransform_FieldOrder(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"zip": { "xpath": "zip" },
+				"id": { "xpath": "id", "type": "int" },
+				"lines": { "array": [ { "xpath": "line", "template": "line" } ] },
+				"attrs": { "pivot": { "xpath": "attr", "key": { "xpath": "@n" }, "value": { "xpath": "@v" } } },
+				"customer": { "template": "customer" }
+			}},
+			"line": { "object": { "sku": { "xpath": "@sku" }, "qty": { "xpath": "@qty", "type": "int" } } },
+			"customer": { "object": { "name": { "xpath": "name" }, "address": { "xpath": "addr" } } }
+		}
+	}`))
+	assert.NoError(t, err)
+	for _, workers := range []int{0, 4} {
+		tfm, err := s.NewTransform("test-input", strings.NewReader(`<a><b>
+			<zip>98101</zip><id>1</id><name>c1</name><addr>a1</addr>
+			<line sku="s2" qty="2"/><line sku="s1" qty="1"/>
+			<attr n="size" v="10"/><attr n="color" v="red"/>
+		</b></a>`), &transformctx.Ctx{Workers: workers})
+		assert.NoError(t, err)
+		b, err := tfm.Read()
+		assert.NoError(t, err)
+		assert.Equal(t,
+			`{"zip":"98101","id":1,"lines":[{"sku":"s2","qty":2},{"sku":"s1","qty":1}],`+
+				`"attrs":{"size":"10","color":"red"},"customer":{"name":"c1","address":"a1"}}`,
+			string(b))
+		_, err = tfm.Read()
+		assert.Equal(t, io.EOF, err)
+	}
+}
+
+func TestSchema_NewTransform_Validate(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"id": { "xpath": "id", "validate": { "required": true, "pattern": "^[0-9]+$" } },
+				"status": { "xpath": "status", "validate": { "enum": [ "A", "B" ], "on_fail": "warn" } }
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	input := "<a>" +
+		"<b><id>1</id><status>A</status></b>" +
+		"<b><id>x</id><status>A</status></b>" +
+		"<b><id>3</id><status>Z</status></b>" +
+		"<b><status>B</status></b>" +
+		"</a>"
+	for _, workers := range []int{0, 4} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			var mtx sync.Mutex
+			var warnings []string
+			tfm, err := s.NewTransform("test-input", strings.NewReader(input), &transformctx.Ctx{
+				Workers: workers,
+				WarningSink: func(warning error) {
+					mtx.Lock()
+					defer mtx.Unlock()
+					warnings = append(warnings, warning.Error())
+				},
+			})
+			assert.NoError(t, err)
+			var records []string
+			for {
+				b, err := tfm.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					var verr *errs.ValidationError
+					assert.True(t, errors.As(err, &verr))
+					records = append(records, verr.Error())
+					continue
+				}
+				records = append(records, string(b))
+			}
+			assert.Equal(t, []string{
+				`{"id":"1","status":"A"}`,
+				`'FINAL_OUTPUT.id' failed validation rule 'pattern': value 'x' doesn't match '^[0-9]+$'`,
+				`{"id":"3","status":"Z"}`,
+				`'FINAL_OUTPUT.id' failed validation rule 'required': value is missing`,
+			}, records)
+			assert.Equal(t, []string{
+				`'FINAL_OUTPUT.status' failed validation rule 'enum': value 'Z' is not one of 'A', 'B'`,
+			}, warnings)
+			stats := tfm.Stats()
+			assert.Equal(t, int64(2), stats.RecordsEmitted)
+			assert.Equal(t, int64(1), stats.Warnings)
+			assert.Equal(t, map[string]int64{transformctx.FailureClassValidation: 2}, stats.RecordsFailed)
+		})
+	}
+}
+
+func TestSchema_NewTransform_OnError(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"id": { "xpath": "id" },
+				"qty": { "xpath": "qty", "type": "int", "on_error": "default", "default": "0" },
+				"prices": { "array": [ { "xpath": "price", "type": "float", "on_error": "skip" } ] },
+				"code": { "xpath": "code", "on_error": "null", "keep_empty_or_null": true },
+				"total": { "xpath": "total", "type": "float" }
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	input := "<a>" +
+		"<b><id>1</id><qty>2</qty><price>1.5</price><code>c</code></b>" +
+		"<b><id>2</id><qty>x</qty><price>2.5</price><price>?</price><code>c1</code><code>c2</code></b>" +
+		"<b><id>3</id><total>?</total></b>" +
+		"</a>"
+	for _, workers := range []int{0, 4} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			var mtx sync.Mutex
+			var warnings []string
+			tfm, err := s.NewTransform("test-input", strings.NewReader(input), &transformctx.Ctx{
+				Workers: workers,
+				WarningSink: func(warning error) {
+					mtx.Lock()
+					defer mtx.Unlock()
+					warnings = append(warnings, warning.Error())
+				},
+			})
+			assert.NoError(t, err)
+			var records []string
+			var recordWarnings [][]string
+			for {
+				b, err := tfm.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					records = append(records, err.Error())
+					recordWarnings = append(recordWarnings, nil)
+					continue
+				}
+				records = append(records, string(b))
+				rawRecord, rerr := tfm.RawRecord()
+				assert.NoError(t, rerr)
+				var rws []string
+				for _, warning := range rawRecord.(schemahandler.Warner).Warnings() {
+					rws = append(rws, warning.Error())
+				}
+				recordWarnings = append(recordWarnings, rws)
+			}
+			assert.Equal(t, []string{
+				`{"id":"1","qty":2,"prices":[1.5],"code":"c"}`,
+				`{"id":"2","qty":0,"prices":[2.5],"code":null}`,
+				`input 'test-input' near line 1: fail to transform. err: unable to convert value '?' to type ` +
+					`'float' on 'FINAL_OUTPUT.total', err: strconv.ParseFloat: parsing "?": invalid syntax`,
+			}, records)
+			assert.Equal(t, [][]string{
+				nil,
+				{
+					`error on 'FINAL_OUTPUT.qty' suppressed by 'on_error' 'default': unable to convert value 'x' ` +
+						`to type 'int' on 'FINAL_OUTPUT.qty', err: strconv.ParseInt: parsing "x": invalid syntax`,
+					`error on 'FINAL_OUTPUT.prices.elem[1]' suppressed by 'on_error' 'skip': unable to convert ` +
+						`value '?' to type 'float' on 'FINAL_OUTPUT.prices.elem[1]', err: strconv.ParseFloat: ` +
+						`parsing "?": invalid syntax`,
+					`error on 'FINAL_OUTPUT.code' suppressed by 'on_error' 'null': xpath query 'code' on ` +
+						`'FINAL_OUTPUT.code' yielded more than one result`,
+				},
+				nil,
+			}, recordWarnings)
+			assert.ElementsMatch(t, recordWarnings[1], warnings)
+			stats := tfm.Stats()
+			assert.Equal(t, int64(2), stats.RecordsEmitted)
+			assert.Equal(t, int64(3), stats.Warnings)
+		})
+	}
+}
+
+func TestSchema_NewTransform_Coercion(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"coercion": { "thousands_separator": ".", "decimal_separator": ",", "trailing_sign": true },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"qty": { "xpath": "qty", "type": "int" },
+				"amount": { "xpath": "amount", "type": "decimal", "coercion": { "parenthesized_negative": true } },
+				"active": { "xpath": "active", "type": "boolean", "coercion": { "true_values": [ "Y" ], "false_values": [ "N" ] } },
+				"code": { "xpath": "code" }
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	tfm, err := s.NewTransform("test-input", strings.NewReader(
+		"<a>"+
+			"<b><qty>+0012</qty><amount>1.234,50</amount><active>Y</active><code>1.000-</code></b>"+
+			"<b><qty>1.000-</qty><amount>(45,00)</amount><active>n</active></b>"+
+			"</a>"), &transformctx.Ctx{})
+	assert.NoError(t, err)
+	var records []string
+	for {
+		b, err := tfm.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		records = append(records, string(b))
+	}
+	assert.Equal(t, []string{
+		`{"qty":12,"amount":1234.50,"active":true,"code":"1.000-"}`,
+		`{"qty":-1000,"amount":-45.00,"active":false}`,
+	}, records)
+
+	_, err = NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "a", "type": "int", "coercion": { "thousands_separator": "1" } }
+		}
+	}`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "thousands_separator: Does not match pattern")
+}
+
+func TestSchema_NewTransform_Explain(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"id": { "xpath": "id", "type": "int" },
+				"name": { "xpath": "name" }
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	explain := func(rawRecord schemahandler.RawRecord) []string {
+		var traces []string
+		for _, trace := range rawRecord.(schemahandler.Explainer).Explain() {
+			traces = append(traces, trace.DeclFQDN+": "+trace.Omitted+trace.Err)
+		}
+		return traces
+	}
+	for _, workers := range []int{0, 4} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			var failed []string
+			tfm, err := s.NewTransform("test-input",
+				strings.NewReader("<a><b><id>1</id><name> </name></b><b><id>x</id></b></a>"),
+				&transformctx.Ctx{
+					Workers:     workers,
+					Explain:     true,
+					ErrorPolicy: transformctx.ErrorPolicySkip,
+					ErrorSink: func(rawRecord transformctx.RawRecord, _ error) {
+						failed = explain(rawRecord)
+					},
+				})
+			assert.NoError(t, err)
+			b, err := tfm.Read()
+			assert.NoError(t, err)
+			assert.Equal(t, `{"id":1}`, string(b))
+			rawRecord, err := tfm.RawRecord()
+			assert.NoError(t, err)
+			assert.Equal(t, []string{
+				"FINAL_OUTPUT: ",
+				"FINAL_OUTPUT.id: ",
+				"FINAL_OUTPUT.name: value is empty after trimming",
+			}, explain(rawRecord))
+			_, err = tfm.Read()
+			assert.Equal(t, io.EOF, err)
+			assert.Equal(t, []string{
+				"FINAL_OUTPUT: unable to convert value 'x' to type 'int' on 'FINAL_OUTPUT.id', err: " +
+					"strconv.ParseInt: parsing \"x\": invalid syntax",
+				"FINAL_OUTPUT.id: unable to convert value 'x' to type 'int' on 'FINAL_OUTPUT.id', err: " +
+					"strconv.ParseInt: parsing \"x\": invalid syntax",
+			}, failed)
+		})
+	}
+}
+
+func TestSchema_NewTransform_Pivot(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"attrs": { "pivot": {
+					"xpath": "attr", "key": { "xpath": "@name" }, "value": { "xpath": "@value" }
+				}},
+				"dims": { "xpath": "dims", "unpivot": { "key_name": "dim" } }
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	tfm, err := s.NewTransform("test-input", strings.NewReader(
+		`<a><b><attr name="color" value="red"/><attr name="size" value="10"/><dims><w>3</w><h>4</h></dims></b></a>`),
+		&transformctx.Ctx{})
+	assert.NoError(t, err)
+	b, err := tfm.Read()
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`{"attrs":{"color":"red","size":"10"},"dims":[{"dim":"w","value":"3"},{"dim":"h","value":"4"}]}`,
+		string(b))
+	_, err = tfm.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSchema_NewTransform_Filter(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FILTER": { "xpath": ".[status != 'X' and amount != '0']" },
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"id": { "xpath": "id", "type": "int" }
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	input := "<a>" +
+		"<b><id>1</id><status>A</status><amount>1</amount></b>" +
+		"<b><id>2</id><status>X</status><amount>1</amount></b>" +
+		"<b><id>3</id><status>A</status><amount>0</amount></b>" +
+		"<b><id>x</id><status>A</status><amount>1</amount></b>" +
+		"<b><id>5</id><status>A</status><amount>1</amount></b>" +
+		"<b><id>6</id><status>X</status><amount>1</amount></b>" +
+		"</a>"
+	for _, workers := range []int{0, 4} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			tfm, err := s.NewTransform("test-input", strings.NewReader(input), &transformctx.Ctx{Workers: workers})
+			assert.NoError(t, err)
+			var records []string
+			for {
+				b, err := tfm.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					records = append(records, "error")
+					continue
+				}
+				records = append(records, string(b))
+			}
+			assert.Equal(t, []string{`{"id":1}`, "error", `{"id":5}`}, records)
+			stats := tfm.Stats()
+			assert.Equal(t, int64(2), stats.RecordsEmitted)
+			assert.Equal(t, int64(3), stats.RecordsFiltered)
+			assert.Equal(t, map[string]int64{transformctx.FailureClassTransform: 1}, stats.RecordsFailed)
+		})
+	}
+}
+
+func TestSchema_NewTransform_Explode(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FILTER": { "xpath": ".[. != 'skip']" },
+			"FINAL_OUTPUT": { "xpath": "/a/shipment", "explode": "status", "object": {
+				"shipment": { "xpath": "../id" },
+				"status": { "xpath": "." }
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	var input strings.Builder
+	var expected []string
+	input.WriteString("<a>")
+	for i := 0; i < 20; i++ {
+		input.WriteString(fmt.Sprintf("<shipment><id>%d</id>", i))
+		for j := 0; j < i%3; j++ {
+			input.WriteString(fmt.Sprintf("<status>s%d</status>", j))
+			expected = append(expected, fmt.Sprintf(`{"shipment":"%d","status":"s%d"}`, i, j))
+		}
+		input.WriteString("<status>skip</status></shipment>")
+	}
+	input.WriteString("</a>")
+	for _, workers := range []int{0, 4} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			tfm, err := s.NewTransform(
+				"test-input", strings.NewReader(input.String()), &transformctx.Ctx{Workers: workers})
+			assert.NoError(t, err)
+			var records []string
+			for {
+				b, err := tfm.Read()
+				if err == io.EOF {
+					break
+				}
+				assert.NoError(t, err)
+				records = append(records, string(b))
+			}
+			assert.Equal(t, expected, records)
+			assert.Equal(t, int64(20), tfm.Stats().RecordsFiltered)
+		})
+	}
+}
+
+func TestSchema_NewTransform_Vars(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"variables": {
+			"seq": { "initial": "0", "update": { "custom_func": { "name": "decimalAdd", "args": [ { "var": "seq" }, { "const": "1" } ] } } },
+			"balance": { "initial": "100", "update": { "custom_func": {
+				"name": "decimalAdd", "args": [ { "var": "balance" }, { "xpath": "amount" } ] } } },
+			"customer": { "update": { "xpath": "customer" } }
+		},
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"seq": { "var": "seq", "type": "int" },
+				"balance": { "var": "balance", "type": "decimal" },
+				"customer": { "var": "customer" }
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	// Variables persist across input streams, and records are transformed in order even if workers are
+	// requested.
+	tfm, err := s.NewMultiInputTransform([]NamedInput{
+		{Name: "input-1", Input: strings.NewReader(
+			"<a><b><customer>c1</customer><amount>10.5</amount></b><b><amount>-20</amount></b></a>")},
+		{Name: "input-2", Input: strings.NewReader(
+			"<a><b><customer>c2</customer><amount>1</amount></b></a>")},
+	}, &transformctx.Ctx{Workers: 4, Vars: transformctx.NewVars(map[string]interface{}{"seq": "10"})})
+	assert.NoError(t, err)
+	for _, expected := range []string{
+		`{"seq":11,"balance":110.5,"customer":"c1"}`,
+		`{"seq":12,"balance":90.5,"customer":"c1"}`,
+		`{"seq":13,"balance":91.5,"customer":"c2"}`,
+	} {
+		b, err := tfm.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(b))
+	}
+	_, err = tfm.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSchema_NewMultiInputTransform(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"id": { "xpath": "id", "type": "int" }
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+
+	tfm, err := s.NewMultiInputTransform(nil, &transformctx.Ctx{})
+	assert.Error(t, err)
+	assert.Equal(t, "no input", err.Error())
+	assert.Nil(t, tfm)
+
+	for _, workers := range []int{0, 4} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			ctx := &transformctx.Ctx{Workers: workers}
+			tfm, err := s.NewMultiInputTransform([]NamedInput{
+				{Name: "input-1", Input: strings.NewReader("<a>\n<b><id>1</id></b>\n<b><id>x</id></b>\n</a>")},
+				{Name: "input-2", Input: strings.NewReader("<a></a>")},
+				{Name: "input-3", Input: strings.NewReader("<a>\n<b><id>y</id></b>\n<b><id>4</id></b>\n</a>")},
+			}, ctx)
+			assert.NoError(t, err)
+			var results []string
+			for {
+				b, err := tfm.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					var transformErr *errs.TransformError
+					assert.True(t, errors.As(err, &transformErr))
+					assert.Equal(t, ctx.InputName, transformErr.Input)
+					results = append(results, fmt.Sprintf("%s: error at line %d", ctx.InputName, transformErr.Line))
+					continue
+				}
+				results = append(results, ctx.InputName+": "+string(b))
+			}
+			assert.Equal(t, []string{
+				`input-1: {"id":1}`,
+				`input-1: error at line 3`,
+				`input-3: error at line 2`,
+				`input-3: {"id":4}`,
+			}, results)
+			assert.Equal(t, map[string]int64{transformctx.FailureClassTransform: 2}, tfm.Stats().RecordsFailed)
+		})
+	}
+}
+
+func TestSchema_NewMultiInputTransform_Failures(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+		"transform_declarations": { "FINAL_OUTPUT": { "xpath": "/a" } }
+	}`))
+	assert.NoError(t, err)
+
+	tfm, err := s.NewMultiInputTransform([]NamedInput{
+		{Name: "input-1", Input: testlib.NewMockReadCloser("read failure", nil)},
+	}, &transformctx.Ctx{})
+	assert.Error(t, err)
+	assert.Equal(t, "read failure", err.Error())
+	assert.Nil(t, tfm)
+
+	ctx := &transformctx.Ctx{}
+	tfm, err = s.NewMultiInputTransform([]NamedInput{
+		{Name: "input-1", Input: strings.NewReader(`{"a":"1"}`)},
+		{Name: "input-2", Input: testlib.NewMockReadCloser("read failure", nil)},
+		{Name: "input-3", Input: strings.NewReader(`{"a":"3"}`)},
+	}, ctx)
+	assert.NoError(t, err)
+	b, err := tfm.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `"1"`, string(b))
+	// failure to start ingesting the next input is fatal.
+	b, err = tfm.Read()
+	assert.Error(t, err)
+	assert.Equal(t, "read failure", err.Error())
+	assert.Equal(t, "input-2", ctx.InputName)
+	assert.Nil(t, b)
+	b, err = tfm.Read()
+	assert.Equal(t, "read failure", err.Error())
+	assert.Nil(t, b)
+}
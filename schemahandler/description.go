@@ -0,0 +1,104 @@
+package schemahandler
+
+// Description is a read-only view of a loaded schema, for tooling such as mapping documentation
+// generators, schema editors and data lineage, to introspect what a schema does.
+type Description struct {
+	// FinalOutput describes the transform declaration tree of the output records.
+	FinalOutput *DeclDescription `json:"final_output,omitempty"`
+	// FileDeclaration is the parsed, format specific file declaration of the schema, if the file format
+	// has one, e.g. *csv.FileDecl for 'csv2' format. nil if the file format has no file declaration.
+	FileDeclaration interface{} `json:"file_declaration,omitempty"`
+}
+
+// DeclDescription is a read-only view of a transform declaration, with all the template references
+// already resolved.
+type DeclDescription struct {
+	// Name is the name of the declaration within its parent, e.g. the field name in an object, or
+	// "elem[2]" for an array element.
+	Name string `json:"name,omitempty"`
+	// FQDN is the fully qualified name of the declaration, e.g. "FINAL_OUTPUT.items.price".
+	FQDN string `json:"fqdn,omitempty"`
+	// Kind is the kind of the declaration, such as "const", "external", "field", "object", "array",
+	// "pivot", "unpivot" or "custom_func".
+	Kind string `json:"kind,omitempty"`
+	// XPath is the xpath of the declaration, if specified.
+	XPath string `json:"xpath,omitempty"`
+	// XPathDynamic describes the declaration that computes the xpath dynamically, if specified.
+	XPathDynamic *DeclDescription `json:"xpath_dynamic,omitempty"`
+	// Const is the constant value of a "const" declaration.
+	Const string `json:"const,omitempty"`
+	// External is the external property name of an "external" declaration.
+	External string `json:"external,omitempty"`
+	// Var is the variable name of a "var" declaration.
+	Var string `json:"var,omitempty"`
+	// CustomFunc describes the custom function invocation of a "custom_func" declaration.
+	CustomFunc *CustomFuncDescription `json:"custom_func,omitempty"`
+	// CustomParse is the custom parse function name of a "custom_parse" declaration. Deprecated.
+	CustomParse string `json:"custom_parse,omitempty"`
+	// ResultType is the result type cast of the declaration, if specified.
+	ResultType string `json:"type,omitempty"`
+	// NoTrim and KeepEmptyOrNull are the corresponding settings of the declaration.
+	NoTrim          bool `json:"no_trim,omitempty"`
+	KeepEmptyOrNull bool `json:"keep_empty_or_null,omitempty"`
+	// Children are the fields of an "object" declaration or the elements of an "array" declaration, in
+	// their declared order.
+	Children []*DeclDescription `json:"children,omitempty"`
+	// Cases are the cases of a "switch" declaration, in their declared order.
+	Cases []*SwitchCaseDescription `json:"cases,omitempty"`
+	// Pivot describes the name/value pairs of a "pivot" declaration.
+	Pivot *PivotDescription `json:"pivot,omitempty"`
+	// Unpivot describes the name/value pairs of an "unpivot" declaration.
+	Unpivot *UnpivotDescription `json:"unpivot,omitempty"`
+	// Default describes the declaration whose value is used when the declaration resolves to nothing, if
+	// specified.
+	Default *DeclDescription `json:"default,omitempty"`
+	// OnError is what to do when the declaration fails to be computed, if specified: "fail", "null",
+	// "default" or "skip".
+	OnError string `json:"on_error,omitempty"`
+}
+
+// CustomFuncDescription is a read-only view of a custom function invocation.
+type CustomFuncDescription struct {
+	Name        string             `json:"name,omitempty"`
+	Args        []*DeclDescription `json:"args,omitempty"`
+	IgnoreError bool               `json:"ignore_error,omitempty"`
+}
+
+// SwitchCaseDescription is a read-only view of a case of a "switch" declaration.
+type SwitchCaseDescription struct {
+	// When is the case's predicate. nil for the default case.
+	When *DeclDescription `json:"when,omitempty"`
+	Then *DeclDescription `json:"then,omitempty"`
+}
+
+// PivotDescription is a read-only view of a "pivot" declaration, which builds an object out of name/value
+// pairs.
+type PivotDescription struct {
+	// XPath selects the nodes, one for each name/value pair.
+	XPath string           `json:"xpath,omitempty"`
+	Key   *DeclDescription `json:"key,omitempty"`
+	Value *DeclDescription `json:"value,omitempty"`
+}
+
+// UnpivotDescription is a read-only view of an "unpivot" declaration, which builds an array of name/value
+// pair objects.
+type UnpivotDescription struct {
+	// XPath selects the nodes, one for each name/value pair.
+	XPath     string           `json:"xpath,omitempty"`
+	KeyName   string           `json:"key_name,omitempty"`
+	ValueName string           `json:"value_name,omitempty"`
+	Value     *DeclDescription `json:"value,omitempty"`
+}
+
+// Describer is an optional interface a SchemaHandler can implement to support schema introspection.
+type Describer interface {
+	// Describe returns a read-only view of the schema.
+	Describe() *Description
+}
+
+// OutputJSONSchemaGenerator is an optional interface a SchemaHandler can implement to support generating
+// the JSON Schema of the output records.
+type OutputJSONSchemaGenerator interface {
+	// OutputJSONSchema returns a JSON Schema describing the output records.
+	OutputJSONSchema() ([]byte, error)
+}
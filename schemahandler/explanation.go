@@ -0,0 +1,37 @@
+package schemahandler
+
+// DeclTrace records how a transform declaration is evaluated for a record in explain mode (see
+// transformctx.Ctx.Explain), for debugging why a field comes out missing or unexpected.
+type DeclTrace struct {
+	// DeclFQDN is the fully qualified name of the declaration evaluated, e.g. "FINAL_OUTPUT.items.price".
+	DeclFQDN string `json:"decl_fqdn"`
+	// XPath is the xpath query evaluated for the declaration, if any. For a declaration with
+	// "xpath_dynamic", it is the computed xpath.
+	XPath string `json:"xpath,omitempty"`
+	// XPathDynamic tells whether XPath is computed by "xpath_dynamic".
+	XPathDynamic bool `json:"xpath_dynamic,omitempty"`
+	// MatchedNodes are the nodes matched by the xpath query, in their JSON form.
+	MatchedNodes []string `json:"matched_nodes,omitempty"`
+	// RawValue is the value before being trimmed and type converted. It is not recorded for "object"
+	// and "array" declarations, whose children have their own traces.
+	RawValue interface{} `json:"raw_value,omitempty"`
+	// Value is the resulting value of the declaration.
+	Value interface{} `json:"value,omitempty"`
+	// CacheHit tells whether Value comes from the transform cache, i.e. the declaration has been
+	// evaluated on the same node before, in which case the trace has nothing else recorded.
+	CacheHit bool `json:"cache_hit,omitempty"`
+	// IgnoredError is the custom function error ignored due to "ignore_error", or the error suppressed
+	// due to the declaration's "on_error".
+	IgnoredError string `json:"ignored_error,omitempty"`
+	// Omitted tells why the declaration yields nothing and is thus omitted from the output, if so.
+	Omitted string `json:"omitted,omitempty"`
+	// Err is the error the evaluation failed with, if any.
+	Err string `json:"error,omitempty"`
+}
+
+// Explainer is an optional interface a RawRecord can implement to provide, in explain mode, the traces
+// of all the transform declaration evaluations done for the record.
+type Explainer interface {
+	// Explain returns the traces of the record's declaration evaluations, in the evaluation order.
+	Explain() []*DeclTrace
+}
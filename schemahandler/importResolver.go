@@ -0,0 +1,16 @@
+package schemahandler
+
+// ImportResolver resolves a schema import reference (as specified in a schema's "imports") into
+// the content of the imported schema document. What a reference means (a file path, a URL, a key
+// into a registry, etc) is entirely up to the resolver.
+type ImportResolver interface {
+	Resolve(ref string) ([]byte, error)
+}
+
+// ImportResolverFunc is an adapter to allow the use of an ordinary function as an ImportResolver.
+type ImportResolverFunc func(ref string) ([]byte, error)
+
+// Resolve implements ImportResolver interface.
+func (f ImportResolverFunc) Resolve(ref string) ([]byte, error) {
+	return f(ref)
+}
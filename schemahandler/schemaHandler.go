@@ -16,6 +16,8 @@ type CreateCtx struct {
 	Content      []byte
 	CustomFuncs  customfuncs.CustomFuncs
 	CreateParams interface{}
+	// ImportResolver resolves the schema's imports, if any. Can be nil if not needed.
+	ImportResolver ImportResolver
 }
 
 // CreateFunc is a function that checks if a given schema is supported by its associated
@@ -37,13 +39,9 @@ type SchemaHandler interface {
 	NewIngester(ctx *transformctx.Ctx, input io.Reader) (Ingester, error)
 }
 
-// RawRecord represents a raw record ingested from the input.
-type RawRecord interface {
-	// Raw returns the actual raw record that is version specific to each of the schema handlers.
-	Raw() interface{}
-	// Checksum returns a UUIDv3 (MD5) stable hash of the raw record.
-	Checksum() string
-}
+// RawRecord represents a raw record ingested from the input. It is an alias of transformctx.RawRecord
+// so that transformctx.ErrorSink can receive it.
+type RawRecord = transformctx.RawRecord
 
 // Ingester is an interface of ingestion and transformation for a given input stream.
 type Ingester interface {
@@ -55,6 +53,8 @@ type Ingester interface {
 	// However, the overall design principle of omniparser is to have streaming processing capability
 	// so memory won't be a constraint when dealing with large input file. All built-in ingesters are
 	// implemented this way.
+	// If a record is ingested but fails to transform, Read should, if possible, return the raw record
+	// along with the (continuable) error, so the raw record can be passed to transformctx.ErrorSink.
 	Read() (RawRecord, []byte, error)
 
 	// IsContinuableError is called to determine if an error returned by Read is fatal or not. After
@@ -0,0 +1,9 @@
+package schemahandler
+
+// Warner is an optional interface a RawRecord can implement to provide the warnings reported while
+// transforming the record, such as the errors suppressed by transform declarations' "on_error" policies.
+// The same warnings are also passed to transformctx.Ctx.WarningSink, if set.
+type Warner interface {
+	// Warnings returns the warnings reported while transforming the record, in the reported order.
+	Warnings() []error
+}
@@ -2,9 +2,11 @@ package omniparser
 
 import (
 	"errors"
+	"io"
 
 	"github.com/jf-tech/omniparser/errs"
 	"github.com/jf-tech/omniparser/schemahandler"
+	"github.com/jf-tech/omniparser/transformctx"
 )
 
 // Transform is an interface that represents one input stream ingestion and transform
@@ -14,48 +16,106 @@ type Transform interface {
 	// Read returns a JSON byte slice representing one ingested and transformed record.
 	// io.EOF should be returned when input stream is completely consumed and future calls
 	// to Read should always return io.EOF.
-	// errs.ErrTransformFailed should be returned when a record ingestion and transformation
-	// failed and such failure isn't considered fatal. Future calls to Read will attempt
-	// new record ingestion and transformations.
+	// errs.ErrTransformFailed or *errs.TransformError (use errs.IsErrTransformFailed to check)
+	// should be returned when a record ingestion and transformation failed and such failure
+	// isn't considered fatal. Future calls to Read will attempt new record ingestion and
+	// transformations. *errs.TransformError, if the schema handler supports it, additionally
+	// carries the error's input position, offending transform decl and underlying cause.
 	// Any other error returned is considered fatal and future calls to Read will always
-	// return the same error.
+	// return the same error. If the transform is created with a context and the context is
+	// canceled or its deadline exceeded, the context's error is returned and considered fatal.
 	// Note if returned error isn't nil, then returned []byte will be nil.
+	// How record transform failures are dealt with can be changed by the error policy settings
+	// in transformctx.Ctx (ErrorPolicy, MaxErrors and ErrorSink).
 	Read() ([]byte, error)
 	// RawRecord returns the current raw record ingested from the input stream. If the last
 	// Read call failed, or Read hasn't been called yet, it will return an error.
 	RawRecord() (schemahandler.RawRecord, error)
+	// Stats returns a snapshot of the statistics of the transform operation so far, such as the
+	// number of records emitted and failed, the bytes consumed and the time spent in each phase.
+	Stats() transformctx.Stats
 }
 
 type transform struct {
 	ingester      schemahandler.Ingester
+	ctx           *transformctx.Ctx
 	lastRawRecord schemahandler.RawRecord
 	lastErr       error
+	failures      int // number of record transform failures so far.
+	// multi-input transform related. see schema.NewMultiInputTransform for details.
+	nextInputs  []NamedInput
+	newIngester func(input NamedInput) (schemahandler.Ingester, error)
 }
 
 // Read returns a JSON byte slice representing one ingested and transformed record.
 // io.EOF should be returned when input stream is completely consumed and future calls
 // to Read should always return io.EOF.
-// errs.ErrTransformFailed should be returned when a record ingestion and transformation
-// failed and such failure isn't considered fatal. Future calls to Read will attempt
-// new record ingestion and transformations.
+// errs.ErrTransformFailed or *errs.TransformError (use errs.IsErrTransformFailed to check)
+// should be returned when a record ingestion and transformation failed and such failure
+// isn't considered fatal. Future calls to Read will attempt new record ingestion and
+// transformations. *errs.TransformError, if the schema handler supports it, additionally
+// carries the error's input position, offending transform decl and underlying cause.
 // Any other error returned is considered fatal and future calls to Read will always
-// return the same error.
+// return the same error. If the transform is created with a context and the context is
+// canceled or its deadline exceeded, the context's error is returned and considered fatal.
 // Note if returned error isn't nil, then returned []byte will be nil.
+// How record transform failures are dealt with can be changed by the error policy settings
+// in transformctx.Ctx (ErrorPolicy, MaxErrors and ErrorSink).
 func (o *transform) Read() ([]byte, error) {
+	for {
+		transformed, failedRawRecord, err := o.read()
+		if err == nil {
+			o.ctx.Stats().AddRecordEmitted()
+			return transformed, nil
+		}
+		if !errs.IsErrTransformFailed(err) {
+			return nil, err
+		}
+		if err = o.handleFailure(failedRawRecord, err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// read does one ingester Read. In case of a record transform failure, it also returns the failed
+// raw record, if the ingester provides it.
+func (o *transform) read() ([]byte, schemahandler.RawRecord, error) {
 	// errs.ErrTransformFailed is a generic wrapping error around all handlers' ingesters'
 	// **continuable** errors (so client side doesn't have to deal with myriad of different
 	// types of benign continuable errors). All other errors: non-continuable errors or io.EOF
 	// should cause the operation to cease.
 	if o.lastErr != nil && !errs.IsErrTransformFailed(o.lastErr) {
-		return nil, o.lastErr
+		return nil, nil, o.lastErr
+	}
+	if err := o.ctx.Err(); err != nil {
+		o.lastRawRecord = nil
+		o.lastErr = err
+		return nil, nil, err
 	}
 	rawRecord, transformed, err := o.ingester.Read()
+	for err == io.EOF && len(o.nextInputs) > 0 {
+		// The current input stream is completely consumed, move on to the next one.
+		if err = o.nextIngester(); err != nil {
+			o.lastRawRecord = nil
+			o.lastErr = err
+			return nil, nil, err
+		}
+		rawRecord, transformed, err = o.ingester.Read()
+	}
+	var failedRawRecord schemahandler.RawRecord
 	if err != nil {
 		if o.ingester.IsContinuableError(err) {
 			// If ingester error is continuable, wrap it into a standard generic ErrTransformFailed
-			// so caller has an easier time to deal with it. If fatal error, then leave it raw to the
+			// (or pass along the structured *errs.TransformError if the ingester provides one) so
+			// caller has an easier time to deal with it. If fatal error, then leave it raw to the
 			// caller, so they can decide what it is and how to proceed.
-			err = errs.ErrTransformFailed(err.Error())
+			var transformErr *errs.TransformError
+			if errors.As(err, &transformErr) {
+				err = transformErr
+			} else {
+				err = errs.ErrTransformFailed(err.Error())
+			}
+			failedRawRecord = rawRecord
 		}
 		transformed = nil
 	}
@@ -65,7 +125,63 @@ func (o *transform) Read() ([]byte, error) {
 		o.lastRawRecord = nil
 	}
 	o.lastErr = err
-	return transformed, err
+	return transformed, failedRawRecord, err
+}
+
+func (o *transform) nextIngester() error {
+	if closer, ok := o.ingester.(io.Closer); ok {
+		_ = closer.Close()
+	}
+	input := o.nextInputs[0]
+	o.nextInputs = o.nextInputs[1:]
+	ingester, err := o.newIngester(input)
+	if err != nil {
+		return err
+	}
+	o.ingester = ingester
+	return nil
+}
+
+// handleFailure applies the error policy to a record transform failure. It returns nil if the failure
+// is to be skipped, otherwise the error to be returned to the caller.
+func (o *transform) handleFailure(rawRecord schemahandler.RawRecord, err error) error {
+	o.failures++
+	o.ctx.Stats().AddRecordFailed(failureClass(rawRecord, err))
+	if o.ctx == nil {
+		return err
+	}
+	if o.ctx.ErrorSink != nil {
+		o.ctx.ErrorSink(rawRecord, err)
+	}
+	if o.ctx.ErrorPolicy == transformctx.ErrorPolicyFailFast ||
+		(o.ctx.MaxErrors > 0 && o.failures > o.ctx.MaxErrors) {
+		o.lastErr = &errs.ErrTransformAborted{Failures: o.failures, Err: err}
+		// The transform operation ceases early, let the ingester release its resources, if any.
+		if closer, ok := o.ingester.(io.Closer); ok {
+			_ = closer.Close()
+		}
+		return o.lastErr
+	}
+	if o.ctx.ErrorPolicy == transformctx.ErrorPolicySkip {
+		return nil
+	}
+	return err
+}
+
+// failureClass tells the failure class of a record transform failure. A failure caused by a violated
+// 'validate' rule, or by a particular transform decl, is classified by its error, if the schema handler
+// reports structured errors. Otherwise, a failure that comes with the raw record is a transform failure,
+// because the record was read, and one without is a read failure.
+func failureClass(rawRecord schemahandler.RawRecord, err error) string {
+	var validationErr *errs.ValidationError
+	if errors.As(err, &validationErr) {
+		return transformctx.FailureClassValidation
+	}
+	var transformErr *errs.TransformError
+	if (errors.As(err, &transformErr) && transformErr.DeclFQDN != "") || rawRecord != nil {
+		return transformctx.FailureClassTransform
+	}
+	return transformctx.FailureClassRead
 }
 
 // RawRecord returns the current raw record ingested from the input stream. If the last
@@ -79,3 +195,8 @@ func (o *transform) RawRecord() (schemahandler.RawRecord, error) {
 	}
 	return o.lastRawRecord, nil
 }
+
+// Stats returns a snapshot of the statistics of the transform operation so far.
+func (o *transform) Stats() transformctx.Stats {
+	return o.ctx.Stats().Stats()
+}
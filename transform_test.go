@@ -1,6 +1,7 @@
 package omniparser
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/jf-tech/omniparser/errs"
 	"github.com/jf-tech/omniparser/schemahandler"
+	"github.com/jf-tech/omniparser/transformctx"
 )
 
 type testReadCall struct {
@@ -35,6 +37,12 @@ type testIngester struct {
 	readCalled      int
 	readCalls       []testReadCall
 	continuableErrs map[error]bool
+	closeCalled     int
+}
+
+func (g *testIngester) Close() error {
+	g.closeCalled++
+	return nil
 }
 
 func (g *testIngester) Read() (schemahandler.RawRecord, []byte, error) {
@@ -153,6 +161,230 @@ func TestTransform_Read_EndWithNonContinuableError(t *testing.T) {
 	assert.Nil(t, raw)
 }
 
+func TestTransform_Read_StructuredTransformError(t *testing.T) {
+	transformErr := &errs.TransformError{
+		Position: errs.Position{Input: "test-input", Line: 3},
+		DeclFQDN: "FINAL_OUTPUT.a",
+		Err:      errors.New("cause"),
+		Msg:      "input 'test-input' line 3: cause",
+	}
+	wrappedErr := fmt.Errorf("wrapped: %w", transformErr)
+	tfm := &transform{
+		ingester: &testIngester{
+			readCalls:       []testReadCall{{err: wrappedErr}},
+			continuableErrs: map[error]bool{wrappedErr: true},
+		},
+	}
+	record, err := tfm.Read()
+	assert.True(t, errs.IsErrTransformFailed(err))
+	assert.True(t, err == transformErr)
+	assert.Nil(t, record)
+}
+
+func TestTransform_Read_ErrorPolicy(t *testing.T) {
+	continuableErr1 := errors.New("continuable error 1")
+	continuableErr2 := errors.New("continuable error 2")
+	readCalls := []testReadCall{
+		{result: []byte("1st good read")},
+		{err: continuableErr1},
+		{result: []byte("2nd good read")},
+		{err: continuableErr2},
+		{err: io.EOF},
+	}
+	for _, test := range []struct {
+		name        string
+		policy      transformctx.ErrorPolicy
+		maxErrors   int
+		expected    []string
+		sinkCalls   int
+		closeCalled int
+	}{
+		{
+			name:      "return errors",
+			policy:    transformctx.ErrorPolicyReturn,
+			expected:  []string{"1st good read", "continuable error 1", "2nd good read", "continuable error 2", "EOF"},
+			sinkCalls: 2,
+		},
+		{
+			name:        "return errors with max errors",
+			policy:      transformctx.ErrorPolicyReturn,
+			maxErrors:   1,
+			expected:    []string{"1st good read", "continuable error 1", "2nd good read", "aborted after 2"},
+			closeCalled: 1,
+			sinkCalls:   2,
+		},
+		{
+			name:        "fail fast",
+			policy:      transformctx.ErrorPolicyFailFast,
+			expected:    []string{"1st good read", "aborted after 1"},
+			closeCalled: 1,
+			sinkCalls:   1,
+		},
+		{
+			name:      "skip",
+			policy:    transformctx.ErrorPolicySkip,
+			expected:  []string{"1st good read", "2nd good read", "EOF"},
+			sinkCalls: 2,
+		},
+		{
+			name:        "skip with max errors",
+			policy:      transformctx.ErrorPolicySkip,
+			maxErrors:   1,
+			expected:    []string{"1st good read", "2nd good read", "aborted after 2"},
+			closeCalled: 1,
+			sinkCalls:   2,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ingester := &testIngester{
+				readCalls:       append([]testReadCall(nil), readCalls...),
+				continuableErrs: map[error]bool{continuableErr1: true, continuableErr2: true},
+			}
+			var sunk []schemahandler.RawRecord
+			tfm := &transform{
+				ingester: ingester,
+				ctx: &transformctx.Ctx{
+					ErrorPolicy: test.policy,
+					MaxErrors:   test.maxErrors,
+					ErrorSink: func(rawRecord transformctx.RawRecord, err error) {
+						assert.True(t, errs.IsErrTransformFailed(err))
+						sunk = append(sunk, rawRecord)
+					},
+				},
+			}
+			var results []string
+			for {
+				record, err := tfm.Read()
+				if err == nil {
+					results = append(results, string(record))
+					continue
+				}
+				assert.Nil(t, record)
+				var abortErr *errs.ErrTransformAborted
+				if errors.As(err, &abortErr) {
+					results = append(results, fmt.Sprintf("aborted after %d", abortErr.Failures))
+					assert.True(t, errs.IsErrTransformFailed(abortErr.Err))
+					// abortion is fatal.
+					_, err2 := tfm.Read()
+					assert.True(t, err == err2)
+					break
+				}
+				results = append(results, err.Error())
+				if err == io.EOF {
+					break
+				}
+				assert.True(t, errs.IsErrTransformFailed(err))
+			}
+			assert.Equal(t, test.expected, results)
+			// all the failed raw records are passed to the sink.
+			assert.Equal(t, test.sinkCalls, len(sunk))
+			for i, rawRecord := range sunk {
+				assert.Equal(t, readCalls[i*2+1], rawRecord)
+			}
+			assert.Equal(t, test.closeCalled, ingester.closeCalled)
+		})
+	}
+}
+
+func TestTransform_Read_Canceled(t *testing.T) {
+	c, cancel := context.WithCancel(context.Background())
+	tfm := &transform{
+		ingester: &testIngester{
+			readCalls: []testReadCall{
+				{result: []byte("1st good read")},
+			},
+		},
+		ctx: &transformctx.Ctx{Context: c},
+	}
+	record, err := tfm.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, "1st good read", string(record))
+
+	cancel()
+	record, err = tfm.Read()
+	assert.Error(t, err)
+	assert.False(t, errs.IsErrTransformFailed(err))
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, record)
+	raw, err := tfm.RawRecord()
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, raw)
+}
+
+func TestTransform_Stats(t *testing.T) {
+	continuableErr1 := errors.New("continuable error 1")
+	tfm := &transform{
+		ingester: &testIngester{
+			readCalls: []testReadCall{
+				{result: []byte("1st good read")},
+				{err: continuableErr1},
+				{err: io.EOF},
+			},
+			continuableErrs: map[error]bool{continuableErr1: true},
+		},
+		ctx: &transformctx.Ctx{StatsRecorder: transformctx.NewStatsRecorder()},
+	}
+	for {
+		if _, err := tfm.Read(); err == io.EOF {
+			break
+		}
+	}
+	stats := tfm.Stats()
+	assert.Equal(t, int64(1), stats.RecordsEmitted)
+	// testIngester returns the testReadCall as the raw record of a failure.
+	assert.Equal(t, map[string]int64{transformctx.FailureClassTransform: 1}, stats.RecordsFailed)
+
+	// no ctx.
+	tfm = &transform{}
+	assert.Equal(t, transformctx.Stats{RecordsFailed: map[string]int64{}}, tfm.Stats())
+}
+
+func TestFailureClass(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		rawRecord schemahandler.RawRecord
+		err       error
+		expected  string
+	}{
+		{
+			name: "validation failure",
+			err: &errs.TransformError{
+				Position: errs.Position{Input: "test-input", Line: 3},
+				Err: &errs.TransformError{
+					DeclFQDN: "FINAL_OUTPUT.a",
+					Err:      &errs.ValidationError{DeclFQDN: "FINAL_OUTPUT.a", Rule: "required", Msg: "value is missing"},
+				},
+			},
+			expected: transformctx.FailureClassValidation,
+		},
+		{
+			name:     "decl failure without raw record",
+			err:      &errs.TransformError{DeclFQDN: "FINAL_OUTPUT.a", Err: errors.New("cause")},
+			expected: transformctx.FailureClassTransform,
+		},
+		{
+			name:     "positional failure without raw record",
+			err:      &errs.TransformError{Position: errs.Position{Input: "test-input", Line: 3}},
+			expected: transformctx.FailureClassRead,
+		},
+		{
+			name:      "unstructured failure with raw record",
+			rawRecord: testReadCall{},
+			err:       errs.ErrTransformFailed("failure"),
+			expected:  transformctx.FailureClassTransform,
+		},
+		{
+			name:     "unstructured failure without raw record",
+			err:      errs.ErrTransformFailed("failure"),
+			expected: transformctx.FailureClassRead,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, failureClass(test.rawRecord, test.err))
+		})
+	}
+}
+
 func TestTransform_RawRecord_CalledBeforeRead(t *testing.T) {
 	tfm := &transform{ingester: &testIngester{readCalls: []testReadCall{}}}
 	raw, err := tfm.RawRecord()
@@ -1,6 +1,8 @@
 package transformctx
 
 import (
+	"context"
+
 	"github.com/jf-tech/omniparser/errs"
 )
 
@@ -21,6 +23,44 @@ type Ctx struct {
 	// param will be passed along with the Ctx object throughout all the stages and operations of
 	// a transform, including passing to all the `custom_func` and `custom_parse`.
 	CustomParam interface{}
+	// Context carries the cancellation signal and deadline of a transform operation. Most of the
+	// time there is no need for caller to set it directly, it will be auto-set by omniparser when
+	// NewTransformWithContext is used. If nil, the transform operation can never be canceled.
+	Context context.Context
+	// Workers, if greater than 1, opts the transform operation into parallel record transformation:
+	// records are still read from the input stream sequentially, but transformed by this many worker
+	// goroutines concurrently, while Transform.Read continues to return records in input order. When
+	// enabled, all the custom funcs used by the schema, as well as CustomParam, must be safe for
+	// concurrent use. Caller must either read the Transform to its end (io.EOF or a fatal error) or
+	// cancel its Context, so that all the worker goroutines can exit. Schema handlers that don't
+	// support parallel transformation ignore this setting.
+	Workers int
+	// ErrorPolicy decides how the transform operation deals with record transform failures. See
+	// ErrorPolicy for details.
+	ErrorPolicy ErrorPolicy
+	// MaxErrors, if greater than 0, caps the number of record transform failures tolerated by the
+	// transform operation: once it is exceeded, the transform operation is aborted with a fatal
+	// *errs.ErrTransformAborted, regardless of the ErrorPolicy.
+	MaxErrors int
+	// ErrorSink, if not nil, is called with every record transform failure. See ErrorSink for details.
+	ErrorSink ErrorSink
+	// WarningSink, if not nil, is called with every warning, such as a violation of a schema
+	// validation rule that is configured to only warn, or an error suppressed by a schema's "on_error"
+	// policy. See WarningSink for details.
+	WarningSink WarningSink
+	// Explain, if true, turns on explain mode for schema debugging: schema handlers that support it
+	// record how each transform declaration is evaluated for every record, retrievable from the record's
+	// raw record (both Transform.RawRecord and the one passed to ErrorSink) via the
+	// schemahandler.Explainer interface. Explain mode slows down the transform operation considerably.
+	Explain bool
+	// StatsRecorder collects the statistics of the transform operation, which are available from
+	// Transform.Stats. Most of the time there is no need for caller of NewTransform to set it, it
+	// will be auto-set by omniparser.
+	StatsRecorder *StatsRecorder
+	// Vars contains the transform-scoped variables declared in the schema. See Vars for details. Most
+	// of the time there is no need for caller of NewTransform to set it, it will be auto-set by
+	// omniparser.
+	Vars *Vars
 }
 
 // External looks up, and returns an external property value, if exists.
@@ -28,3 +68,43 @@ func (ctx *Ctx) External(name string) (string, bool) {
 	v, found := ctx.ExternalProperties[name]
 	return v, found
 }
+
+// Stats returns the StatsRecorder of the transform operation. Stats returns nil (whose methods are
+// all no-op) if ctx is nil.
+func (ctx *Ctx) Stats() *StatsRecorder {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.StatsRecorder
+}
+
+// Warn reports a warning: it is counted in the stats, and passed to WarningSink, if set. Warn is a
+// no-op if ctx is nil.
+func (ctx *Ctx) Warn(warning error) {
+	if ctx == nil {
+		return
+	}
+	ctx.Stats().AddWarning()
+	if ctx.WarningSink != nil {
+		ctx.WarningSink(warning)
+	}
+}
+
+// Done returns a channel that is closed when the transform operation is canceled or its deadline
+// is exceeded. Done returns nil (a channel that never closes) if ctx or its Context is nil.
+func (ctx *Ctx) Done() <-chan struct{} {
+	if ctx == nil || ctx.Context == nil {
+		return nil
+	}
+	return ctx.Context.Done()
+}
+
+// Err returns context.Canceled or context.DeadlineExceeded if the transform operation has been
+// canceled or has run past its deadline, respectively. Otherwise, it returns nil. Err returns nil
+// if ctx or its Context is nil.
+func (ctx *Ctx) Err() error {
+	if ctx == nil || ctx.Context == nil {
+		return nil
+	}
+	return ctx.Context.Err()
+}
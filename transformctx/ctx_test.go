@@ -1,6 +1,8 @@
 package transformctx
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -50,3 +52,37 @@ func TestCtx_External(t *testing.T) {
 		})
 	}
 }
+
+func TestCtx_DoneAndErr(t *testing.T) {
+	var nilCtx *Ctx
+	assert.Nil(t, nilCtx.Done())
+	assert.NoError(t, nilCtx.Err())
+
+	ctx := &Ctx{}
+	assert.Nil(t, ctx.Done())
+	assert.NoError(t, ctx.Err())
+
+	c, cancel := context.WithCancel(context.Background())
+	ctx = &Ctx{Context: c}
+	assert.NotNil(t, ctx.Done())
+	assert.NoError(t, ctx.Err())
+	cancel()
+	<-ctx.Done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
+
+func TestCtx_Warn(t *testing.T) {
+	(*Ctx)(nil).Warn(errors.New("ignored"))
+	(&Ctx{}).Warn(errors.New("no sink, no stats"))
+
+	var warnings []error
+	ctx := &Ctx{
+		StatsRecorder: NewStatsRecorder(),
+		WarningSink:   func(warning error) { warnings = append(warnings, warning) },
+	}
+	w1, w2 := errors.New("w1"), errors.New("w2")
+	ctx.Warn(w1)
+	ctx.Warn(w2)
+	assert.Equal(t, []error{w1, w2}, warnings)
+	assert.Equal(t, int64(2), ctx.Stats().Stats().Warnings)
+}
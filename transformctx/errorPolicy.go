@@ -0,0 +1,41 @@
+package transformctx
+
+// ErrorPolicy decides how a Transform deals with record transform failures, i.e. the continuable
+// errors (errs.ErrTransformFailed or *errs.TransformError) that would otherwise be returned by
+// Transform.Read.
+type ErrorPolicy int
+
+const (
+	// ErrorPolicyReturn returns every record transform failure to the caller of Transform.Read,
+	// which can then continue with the next Read call. This is the default.
+	ErrorPolicyReturn ErrorPolicy = iota
+	// ErrorPolicyFailFast aborts the transform on the first record transform failure: Transform.Read
+	// returns a fatal *errs.ErrTransformAborted, and so do all the subsequent Read calls.
+	ErrorPolicyFailFast
+	// ErrorPolicySkip skips record transform failures: Transform.Read silently moves on to the next
+	// record, so the caller only ever sees successfully transformed records, io.EOF or a fatal error.
+	// Use Ctx.ErrorSink to collect the skipped records.
+	ErrorPolicySkip
+)
+
+// RawRecord represents a raw record ingested from the input.
+type RawRecord interface {
+	// Raw returns the actual raw record that is version specific to each of the schema handlers.
+	Raw() interface{}
+	// Checksum returns a UUIDv3 (MD5) stable hash of the raw record.
+	Checksum() string
+}
+
+// ErrorSink receives every record transform failure, regardless of the ErrorPolicy, e.g. for writing
+// the rejected records to a dead-letter file. rawRecord is the raw record that failed to transform, or
+// nil if the schema handler can't provide it; rawRecord is only valid during the ErrorSink call. err
+// is the record transform failure, either errs.ErrTransformFailed or *errs.TransformError.
+type ErrorSink func(rawRecord RawRecord, err error)
+
+// WarningSink receives every warning of a transform operation, which, unlike a record transform failure,
+// doesn't stop the record from being transformed and returned. warning is usually an *errs.TransformError
+// identifying the offending decl. Note in parallel record transformation mode, WarningSink is called from
+// the worker goroutines concurrently, thus must be safe for concurrent use. Schema handlers that support it
+// also attach the warnings of a record to its raw record (both Transform.RawRecord and the one passed to
+// ErrorSink), retrievable via the schemahandler.Warner interface.
+type WarningSink func(warning error)
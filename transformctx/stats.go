@@ -0,0 +1,146 @@
+package transformctx
+
+import (
+	"sync"
+	"time"
+)
+
+// Phase identifies a phase of the record processing in a transform operation.
+type Phase int
+
+const (
+	// PhaseRead is the phase of reading a record from the input stream and building its IDR tree.
+	PhaseRead Phase = iota
+	// PhaseTransform is the phase of evaluating the transform decls against a record's IDR tree.
+	PhaseTransform
+	// PhaseMarshal is the phase of marshaling a transformed record into JSON.
+	PhaseMarshal
+)
+
+// Record failure classes used as the keys of Stats.RecordsFailed.
+const (
+	// FailureClassRead indicates a record failed to be read/ingested from the input stream, or the
+	// schema handler provides neither a structured error nor the raw record of a failed record.
+	FailureClassRead = "read"
+	// FailureClassTransform indicates a record was read but failed to transform.
+	FailureClassTransform = "transform"
+	// FailureClassValidation indicates a record was read but one of its values violated the 'validate'
+	// rules of a transform decl.
+	FailureClassValidation = "validation"
+)
+
+// Stats contains the statistics of a transform operation.
+type Stats struct {
+	// RecordsEmitted is the number of records successfully transformed and returned.
+	RecordsEmitted int64
+	// RecordsFiltered is the number of records skipped by the schema's record filter.
+	RecordsFiltered int64
+	// Warnings is the number of warnings reported, see Ctx.Warn.
+	Warnings int64
+	// RecordsFailed is the number of record failures, keyed by failure class (FailureClassRead,
+	// FailureClassTransform or FailureClassValidation).
+	RecordsFailed map[string]int64
+	// BytesConsumed is the number of bytes consumed from the input stream.
+	BytesConsumed int64
+	// ReadDuration, TransformDuration and MarshalDuration are the time spent in PhaseRead,
+	// PhaseTransform and PhaseMarshal, respectively. Note in parallel record transformation mode,
+	// TransformDuration and MarshalDuration are summed across all the workers, thus can be longer
+	// than the wall-clock time of the transform operation.
+	ReadDuration      time.Duration
+	TransformDuration time.Duration
+	MarshalDuration   time.Duration
+}
+
+// StatsRecorder collects the Stats of a transform operation. It is safe for concurrent use. All its
+// methods are no-op on a nil StatsRecorder.
+type StatsRecorder struct {
+	mtx   sync.Mutex
+	stats Stats
+}
+
+// NewStatsRecorder creates a new StatsRecorder.
+func NewStatsRecorder() *StatsRecorder {
+	return &StatsRecorder{stats: Stats{RecordsFailed: map[string]int64{}}}
+}
+
+// AddPhaseDuration adds d to the time spent in phase p.
+func (r *StatsRecorder) AddPhaseDuration(p Phase, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	switch p {
+	case PhaseRead:
+		r.stats.ReadDuration += d
+	case PhaseTransform:
+		r.stats.TransformDuration += d
+	case PhaseMarshal:
+		r.stats.MarshalDuration += d
+	}
+}
+
+// AddBytesConsumed adds n to the number of bytes consumed from the input stream.
+func (r *StatsRecorder) AddBytesConsumed(n int) {
+	if r == nil {
+		return
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.stats.BytesConsumed += int64(n)
+}
+
+// AddRecordEmitted increments the number of records emitted.
+func (r *StatsRecorder) AddRecordEmitted() {
+	if r == nil {
+		return
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.stats.RecordsEmitted++
+}
+
+// AddRecordFiltered increments the number of records skipped by the schema's record filter.
+func (r *StatsRecorder) AddRecordFiltered() {
+	if r == nil {
+		return
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.stats.RecordsFiltered++
+}
+
+// AddWarning increments the number of warnings.
+func (r *StatsRecorder) AddWarning() {
+	if r == nil {
+		return
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.stats.Warnings++
+}
+
+// AddRecordFailed increments the number of record failures of the failure class.
+func (r *StatsRecorder) AddRecordFailed(class string) {
+	if r == nil {
+		return
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.stats.RecordsFailed[class]++
+}
+
+// Stats returns a snapshot of the Stats collected so far.
+func (r *StatsRecorder) Stats() Stats {
+	if r == nil {
+		return Stats{RecordsFailed: map[string]int64{}}
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	stats := r.stats
+	stats.RecordsFailed = make(map[string]int64, len(r.stats.RecordsFailed))
+	for class, n := range r.stats.RecordsFailed {
+		stats.RecordsFailed[class] = n
+	}
+	return stats
+}
@@ -0,0 +1,53 @@
+package transformctx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsRecorder(t *testing.T) {
+	r := NewStatsRecorder()
+	r.AddPhaseDuration(PhaseRead, time.Second)
+	r.AddPhaseDuration(PhaseRead, time.Second)
+	r.AddPhaseDuration(PhaseTransform, 3*time.Second)
+	r.AddPhaseDuration(PhaseMarshal, 4*time.Second)
+	r.AddPhaseDuration(Phase(99), 5*time.Second)
+	r.AddBytesConsumed(10)
+	r.AddBytesConsumed(20)
+	r.AddRecordEmitted()
+	r.AddRecordFiltered()
+	r.AddWarning()
+	r.AddRecordFailed(FailureClassRead)
+	r.AddRecordFailed(FailureClassTransform)
+	r.AddRecordFailed(FailureClassTransform)
+	stats := r.Stats()
+	assert.Equal(t, Stats{
+		RecordsEmitted:    1,
+		RecordsFiltered:   1,
+		Warnings:          1,
+		RecordsFailed:     map[string]int64{FailureClassRead: 1, FailureClassTransform: 2},
+		BytesConsumed:     30,
+		ReadDuration:      2 * time.Second,
+		TransformDuration: 3 * time.Second,
+		MarshalDuration:   4 * time.Second,
+	}, stats)
+	// Stats returns a snapshot.
+	r.AddRecordFailed(FailureClassRead)
+	assert.Equal(t, int64(1), stats.RecordsFailed[FailureClassRead])
+	assert.Equal(t, int64(2), r.Stats().RecordsFailed[FailureClassRead])
+}
+
+func TestStatsRecorder_Nil(t *testing.T) {
+	var r *StatsRecorder
+	r.AddPhaseDuration(PhaseRead, time.Second)
+	r.AddBytesConsumed(10)
+	r.AddRecordEmitted()
+	r.AddRecordFiltered()
+	r.AddWarning()
+	r.AddRecordFailed(FailureClassRead)
+	assert.Equal(t, Stats{RecordsFailed: map[string]int64{}}, r.Stats())
+	assert.Nil(t, (*Ctx)(nil).Stats())
+	assert.Nil(t, (&Ctx{}).Stats())
+}
@@ -0,0 +1,47 @@
+package transformctx
+
+// Vars contains the transform-scoped variables declared in a schema, whose values persist across
+// records (and across the input streams of a multi-input transform) of a transform operation. Caller
+// of NewTransform can seed the variables (e.g. to continue a record sequence number from a previous
+// transform operation) by setting Ctx.Vars with the values; the schema declared initial values only
+// apply to the variables not set yet. Vars is not safe for concurrent use. Get is nil safe.
+type Vars struct {
+	values map[string]interface{}
+}
+
+// NewVars creates a new Vars with the given values, if any.
+func NewVars(values ...map[string]interface{}) *Vars {
+	vars := &Vars{values: map[string]interface{}{}}
+	for _, vs := range values {
+		for name, v := range vs {
+			vars.values[name] = v
+		}
+	}
+	return vars
+}
+
+// Get returns the value of a variable and whether it is set or not.
+func (v *Vars) Get(name string) (interface{}, bool) {
+	if v == nil {
+		return nil, false
+	}
+	value, found := v.values[name]
+	return value, found
+}
+
+// Set sets the value of a variable.
+func (v *Vars) Set(name string, value interface{}) {
+	v.values[name] = value
+}
+
+// Values returns a copy of all the variables and their values.
+func (v *Vars) Values() map[string]interface{} {
+	values := map[string]interface{}{}
+	if v == nil {
+		return values
+	}
+	for name, value := range v.values {
+		values[name] = value
+	}
+	return values
+}
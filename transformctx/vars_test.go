@@ -0,0 +1,31 @@
+package transformctx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVars(t *testing.T) {
+	var nilVars *Vars
+	v, found := nilVars.Get("a")
+	assert.False(t, found)
+	assert.Nil(t, v)
+	assert.Equal(t, map[string]interface{}{}, nilVars.Values())
+
+	vars := NewVars(map[string]interface{}{"a": 1}, map[string]interface{}{"a": 2, "b": "x"})
+	v, found = vars.Get("a")
+	assert.True(t, found)
+	assert.Equal(t, 2, v)
+	_, found = vars.Get("c")
+	assert.False(t, found)
+	vars.Set("c", nil)
+	v, found = vars.Get("c")
+	assert.True(t, found)
+	assert.Nil(t, v)
+	values := vars.Values()
+	assert.Equal(t, map[string]interface{}{"a": 2, "b": "x", "c": nil}, values)
+	values["a"] = 3
+	v, _ = vars.Get("a")
+	assert.Equal(t, 2, v)
+}